@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/urfave/cli/v2"
+
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/leveldb"
+)
+
+var (
+	MainDbFlag = cli.StringFlag{
+		Name:     "main",
+		Usage:    "leveldb path of the consensus main DB",
+		Required: true,
+	}
+	EpochDbFlag = cli.StringFlag{
+		Name:     "epoch",
+		Usage:    "leveldb path of the DB of the current epoch",
+		Required: true,
+	}
+	SamplesFlag = cli.IntFlag{
+		Name:  "forkless-cause.samples",
+		Usage: "Number of roots, whose frames are verified by recalculating ForklessCause",
+		Value: 100,
+	}
+	SeedFlag = cli.Uint64Flag{
+		Name:  "seed",
+		Usage: "Seed of the roots sampling",
+	}
+	IndexPersistedFlag = cli.BoolFlag{
+		Name:  "index.persisted",
+		Usage: "Set if the DAG index is written through to the epoch DB, otherwise the records missing the vectors are reported as unverifiable",
+	}
+)
+
+// exitViolations is the exit code if the DBs are inconsistent.
+const exitViolations = 2
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	return &cli.App{
+		Name:        "Consensus DB checker",
+		Description: "Offline consistency checker of the consensus databases, prints the report of violations as JSON",
+		Copyright:   "(c) 2025 Sonic Labs",
+		Flags:       []cli.Flag{&MainDbFlag, &EpochDbFlag, &SamplesFlag, &SeedFlag, &IndexPersistedFlag},
+		Action:      run,
+	}
+}
+
+func run(ctx *cli.Context) error {
+	mainDB, err := openDB(ctx.String(MainDbFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer closeDB(mainDB)
+	epochDB, err := openDB(ctx.String(EpochDbFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer closeDB(epochDB)
+
+	report, err := consensusstore.Fsck(mainDB, epochDB, consensusstore.FsckConfig{
+		ForklessCauseSamples: ctx.Int(SamplesFlag.Name),
+		Seed:                 ctx.Uint64(SeedFlag.Name),
+		IndexPersisted:       ctx.Bool(IndexPersistedFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(ctx.App.Writer, string(out))
+	if !report.OK() {
+		return cli.Exit(fmt.Sprintf("%d violations found", len(report.Violations)), exitViolations)
+	}
+	return nil
+}
+
+// openDB opens an existing leveldb, as opening a missing one would create it.
+func openDB(path string) (kvdb.Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return leveldb.New(path, 16*opt.MiB, 64, nil, nil)
+}
+
+func closeDB(db kvdb.Store) {
+	if err := db.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error closing database: %v\n", err)
+	}
+}
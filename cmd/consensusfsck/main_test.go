@@ -0,0 +1,142 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/urfave/cli/v2"
+
+	"github.com/0xsoniclabs/cacheutils/cachescale"
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusengine"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/leveldb"
+)
+
+// writeDefaultDBs processes the events of the first epoch by an engine with the default DAG index config,
+// and returns the paths of the closed main DB and epoch DB.
+func writeDefaultDBs(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	mainPath, epochPath := filepath.Join(dir, "main"), filepath.Join(dir, "epoch")
+	open := func(path string) kvdb.Store {
+		db, err := leveldb.New(path, 16*opt.MiB, 64, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	var epochDB kvdb.Store
+	store := consensusstore.NewStore(open(mainPath), func(epoch consensus.Epoch) kvdb.Store {
+		if epoch != consensus.FirstEpoch {
+			t.Fatalf("unexpected epoch %d", epoch)
+		}
+		if epochDB == nil {
+			epochDB = open(epochPath)
+		}
+		return epochDB
+	}, nil, consensusstore.DefaultStoreConfig(cachescale.Identity))
+
+	nodes := consensustest.GenNodes(5)
+	validators := consensus.ValidatorsBuilder{}
+	for _, v := range nodes {
+		validators[v] = 1
+	}
+	if err := store.ApplyGenesis(&consensusstore.Genesis{
+		Validators: validators.Build(),
+		Epoch:      consensus.FirstEpoch,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	input := consensustest.NewTestEventSource()
+	crit := func(err error) {
+		t.Fatal(err)
+	}
+	index := dagindexer.NewIndex(crit, dagindexer.DefaultConfig(cachescale.Identity))
+	lch := consensusengine.NewIndexedLachesis(store, input, index, crit, consensusengine.DefaultConfig())
+	if err := lch.Bootstrap(consensus.ConsensusCallbacks{}); err != nil {
+		t.Fatal(err)
+	}
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 100, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			if err := lch.Process(e); err != nil {
+				t.Fatal(err)
+			}
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lch.Build(e)
+		},
+	})
+	if store.GetLastDecidedFrame() == 0 {
+		t.Fatal("no frame is decided")
+	}
+	// the vectors held by the cache of the index aren't written to the epoch DB
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return mainPath, epochPath
+}
+
+func TestFsckCLI_DefaultIndexConfig(t *testing.T) {
+	mainPath, epochPath := writeDefaultDBs(t)
+
+	exitCode := 0
+	osExiter := cli.OsExiter
+	cli.OsExiter = func(code int) {
+		exitCode = code
+	}
+	defer func() {
+		cli.OsExiter = osExiter
+	}()
+	runFsck := func(args ...string) *consensusstore.FsckReport {
+		out := &bytes.Buffer{}
+		app := newApp()
+		app.Writer = out
+		app.ErrWriter = &bytes.Buffer{}
+		exitCode = 0
+		_ = app.Run(append([]string{"consensusfsck", "--main", mainPath, "--epoch", epochPath}, args...))
+		report := &consensusstore.FsckReport{}
+		if err := json.Unmarshal(out.Bytes(), report); err != nil {
+			t.Fatalf("unexpected report %q: %v", out.String(), err)
+		}
+		return report
+	}
+
+	// the roots, whose vectors aren't written by the index, are unverifiable
+	report := runFsck()
+	if !report.OK() || exitCode != 0 {
+		t.Fatalf("unexpected violations of a healthy DB, exit code %d: %v", exitCode, report.Violations)
+	}
+	if report.Unverifiable[consensusstore.CheckRoots] == 0 {
+		t.Fatalf("expected unverifiable roots, got %v", report.Unverifiable)
+	}
+
+	// they're violations only if the index is known to be written through
+	report = runFsck("--index.persisted")
+	if report.OK() || exitCode != exitViolations {
+		t.Fatalf("expected violations, exit code %d: %v", exitCode, report.Violations)
+	}
+	for _, v := range report.Violations {
+		if v.Check != consensusstore.CheckRoots {
+			t.Fatalf("unexpected violation: %v", v)
+		}
+	}
+}
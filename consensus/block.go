@@ -14,4 +14,14 @@ package consensus
 type Block struct {
 	Atropos  EventHash
 	Cheaters Cheaters
+	// Events are the IDs of the confirmed events, in the order they're applied, see Config.EventsOrdering.
+	Events EventHashes
+}
+
+// AppliedBlock describes a block, which is already applied, without its events.
+type AppliedBlock struct {
+	Atropos  EventHash
+	Cheaters Cheaters
+	// Events is the metric of the events confirmed by the block
+	Events Metric
 }
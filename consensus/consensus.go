@@ -25,7 +25,7 @@ type EndBlockFn func() (sealEpoch *Validators)
 type BlockCallbacks struct {
 	// ApplyEvent is called on confirmation of each event during block processing.
 	// Cannot be called twice for the same event.
-	// The order in which ApplyBlock is called for events is deterministic but undefined. It's application's responsibility to sort events according to its needs.
+	// The order in which ApplyEvent is called for events is the order of Block.Events, which is selected by the consensus configuration.
 	// It's application's responsibility to interpret this data (e.g. events may be related to batches of transactions or other ordered data).
 	ApplyEvent ApplyEventFn
 	// EndBlock indicates that ApplyEvent was called for all the events
@@ -35,9 +35,14 @@ type BlockCallbacks struct {
 }
 
 type BeginBlockFn func(block *Block) BlockCallbacks
+type RestoreEpochFn func(validators *Validators, blocks []AppliedBlock)
 
 // ConsensusCallbacks contains callbacks called during block processing by consensus engine
 type ConsensusCallbacks struct {
-	// BeginBlock returns further callbacks for processing of this block
+	// BeginBlock returns further callbacks for processing of this block. Optional:
+	// without it, the events are still confirmed and the block is recorded, e.g. by consensusstore.Store.AddBlock
 	BeginBlock BeginBlockFn
+	// RestoreEpoch is called on Bootstrap and Rewind with the blocks of the current epoch, which stay applied,
+	// before the following blocks are delivered. It lets the application rebuild the state of the epoch, e.g. Sealer. Optional.
+	RestoreEpoch RestoreEpochFn
 }
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/cacheutils/cachescale"
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestOrderer_ArchivedEpochs(t *testing.T) {
+	t.Run("lite", func(t *testing.T) {
+		testOrdererArchivedEpochs(t, dagindexer.LiteConfig())
+	})
+	// the vectors are held by the cache of the DAG index, until it's written through on archiving
+	t.Run("default", func(t *testing.T) {
+		testOrdererArchivedEpochs(t, dagindexer.DefaultConfig(cachescale.Identity))
+	})
+}
+
+func testOrdererArchivedEpochs(t *testing.T, indexConfig dagindexer.IndexConfig) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	cfg := consensusstore.LiteStoreConfig()
+	cfg.Archive = consensusstore.KeepAllEpochs()
+	store := consensusstore.NewStore(memorydb.New(), func(consensus.Epoch) kvdb.Store {
+		return memorydb.New()
+	}, nil, cfg)
+	engine, _, input, _ := newCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5}, store, indexConfig)
+	lch := &CoreLachesis{
+		IndexedLachesis: engine,
+		blocks:          map[BlockKey]*BlockResult{},
+		epochBlocks:     map[consensus.Epoch]consensus.Frame{},
+	}
+	assertar.NoError(lch.Bootstrap(lch.consensusCallbacks()))
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		if lch.store.GetLastDecidedFrame()+1 == 10 {
+			return lch.store.GetValidators()
+		}
+		return nil
+	}
+
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				input.SetEvent(e)
+				assertar.NoError(lch.Process(e))
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lch.store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lch.Build(e)
+			},
+		})
+	}
+	assertar.Equal(consensus.Epoch(epochs+1), lch.store.GetEpoch())
+
+	// the decisions of the sealed epochs are available
+	assertar.NotEmpty(lch.blocks)
+	for key, block := range lch.blocks {
+		view, err := store.OpenEpochView(key.Epoch)
+		if !assertar.NoError(err) {
+			return
+		}
+		frame, err := view.GetEventConfirmedOn(block.Atropos)
+		assertar.NoError(err)
+		assertar.Equal(key.Frame, frame)
+
+		roots, err := view.GetFrameRoots(key.Frame)
+		assertar.NoError(err)
+		assertar.True(slices.ContainsFunc(roots, func(root consensusstore.RootDescriptor) bool {
+			return root.RootHash == block.Atropos
+		}))
+
+		vec, err := view.Vectors().GetHighestBefore(block.Atropos)
+		assertar.NoError(err)
+		assertar.NotNil(vec)
+		info, err := view.Vectors().GetBranchesInfo()
+		assertar.NoError(err)
+		assertar.NotNil(info)
+	}
+
+	_, err := store.OpenEpochView(epochs + 1)
+	assertar.ErrorIs(err, consensusstore.ErrEpochNotArchived)
+}
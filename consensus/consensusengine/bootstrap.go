@@ -17,7 +17,10 @@ import (
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
 )
 
-var ErrAlreadyBootstrapped = errors.New("already bootstrapped")
+var (
+	ErrAlreadyBootstrapped = errors.New("already bootstrapped")
+	ErrNotBootstrapped     = errors.New("not bootstrapped")
+)
 
 // Bootstrap restores abft's state from store.
 func (p *Orderer) Bootstrap(callback OrdererCallbacks) error {
@@ -27,15 +30,28 @@ func (p *Orderer) Bootstrap(callback OrdererCallbacks) error {
 	// block handler must be set before p.handleElection
 	p.callback = callback
 
+	if err := p.loadStates(); err != nil {
+		return err
+	}
 	// restore current epoch DB
 	err := p.loadEpochDB()
 	if err != nil {
 		return err
 	}
+	// complete the frame decision interrupted by a crash
+	if _, err := p.store.RecoverTransition(); err != nil {
+		return err
+	}
 	if p.callback.EpochDBLoaded != nil {
 		p.callback.EpochDBLoaded(p.store.GetEpoch())
 	}
-	p.election = NewElection(p.store.GetLastDecidedFrame()+1, p.store.GetValidators(), p.dagIndex.ForklessCause, p.store.GetFrameRoots)
+	p.election = NewElection(p.store.GetLastDecidedFrame()+1, p.store.GetValidators(), p.dagIndex.ForklessCauseRoots, p.store.GetFrameRoots)
+	p.election.initMetrics(p.config.Metrics)
+	if p.callback.EpochRestored != nil {
+		if err := p.callback.EpochRestored(); err != nil {
+			return err
+		}
+	}
 
 	// events reprocessing
 	err = p.bootstrapElection()
@@ -59,6 +75,16 @@ func (p *Orderer) Reset(epoch consensus.Epoch, validators *consensus.Validators)
 	return nil
 }
 
+// loadStates reads the epoch and last decided states, so the storage errors are returned by the caller.
+// The states are cached once read, so the store accessors, e.g. GetEpoch, don't fail afterwards.
+func (p *Orderer) loadStates() error {
+	if _, err := p.store.GetEpochState(); err != nil {
+		return err
+	}
+	_, err := p.store.GetLastDecidedState()
+	return err
+}
+
 func (p *Orderer) loadEpochDB() error {
 	return p.store.OpenEpochDB(p.store.GetEpoch())
 }
@@ -1,11 +1,15 @@
 package consensusengine
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/0xsoniclabs/consensus/consensus"
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
 	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
 )
 
 func TestBootstrap_AlreadyBootstrapped(t *testing.T) {
@@ -20,6 +24,53 @@ func TestBootstrap_AlreadyBootstrapped(t *testing.T) {
 	}
 }
 
+func TestBootstrap_NoGenesis(t *testing.T) {
+	store := consensusstore.NewMemStore()
+	lachesis := NewIndexedLachesis(store, consensustest.NewTestEventSource(), dagindexer.NewIndex(nil, dagindexer.LiteConfig()), func(error) {}, DefaultConfig())
+	if err := lachesis.Bootstrap(consensus.ConsensusCallbacks{}); !errors.Is(err, consensusstore.ErrNoGenesis) {
+		t.Fatalf("expected a `genesis not applied` error but recieved: %v", err)
+	}
+}
+
+var errReadFailure = errors.New("read failure")
+
+// unreadableDB is a kvdb.Store, which fails every read
+type unreadableDB struct {
+	kvdb.Store
+}
+
+func (unreadableDB) Get([]byte) ([]byte, error) { return nil, errReadFailure }
+
+func TestBootstrap_StatesReadFailure(t *testing.T) {
+	nodes := consensustest.GenNodes(3)
+	mainDB := memorydb.New()
+	genesisStore := consensusstore.NewStore(mainDB, func(consensus.Epoch) kvdb.Store { return memorydb.New() }, nil, consensusstore.LiteStoreConfig())
+	if err := genesisStore.ApplyGenesis(&consensusstore.Genesis{Epoch: consensus.FirstEpoch, Validators: consensus.EqualWeightValidators(nodes, 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the states aren't cached, so they're read from the failing DB
+	store := consensusstore.NewStore(unreadableDB{mainDB}, func(consensus.Epoch) kvdb.Store { return memorydb.New() }, nil, consensusstore.LiteStoreConfig())
+	lachesis := NewIndexedLachesis(store, consensustest.NewTestEventSource(), dagindexer.NewIndex(nil, dagindexer.LiteConfig()), func(error) {}, DefaultConfig())
+	e := &consensustest.TestEvent{}
+	e.SetEpoch(consensus.FirstEpoch)
+	e.SetCreator(nodes[0])
+	e.SetSeq(1)
+	e.SetFrame(1)
+	if err := lachesis.Build(e); !errors.Is(err, consensus.ErrStorageIO) || !errors.Is(err, errReadFailure) {
+		t.Fatalf("expected a storage IO error from Build but recieved: %v", err)
+	}
+	if err := lachesis.Process(e); !errors.Is(err, consensus.ErrStorageIO) {
+		t.Fatalf("expected a storage IO error from Process but recieved: %v", err)
+	}
+	if _, _, err := lachesis.ProcessBatch(consensus.Events{e}); !errors.Is(err, consensus.ErrStorageIO) {
+		t.Fatalf("expected a storage IO error from ProcessBatch but recieved: %v", err)
+	}
+	if err := lachesis.Bootstrap(consensus.ConsensusCallbacks{}); !errors.Is(err, consensus.ErrStorageIO) {
+		t.Fatalf("expected a storage IO error from Bootstrap but recieved: %v", err)
+	}
+}
+
 func TestBootstrap_NoNewRoots(t *testing.T) {
 	testBootstrap_ReprocessRoots(t, 10, 0, 10)
 }
@@ -43,7 +94,9 @@ func TestBootstrap_DecidedFramesSealing(t *testing.T) {
 func testBootstrap_ReprocessRoots(t *testing.T, lastDecidedFrame, sealingFrame, numFrames consensus.Frame) {
 	nodes := consensustest.GenNodes(1)
 	engine, _, eventSource, _ := NewCoreConsensus(nodes, []consensus.Weight{1})
-	engine.store.SetLastDecidedState(&consensusstore.LastDecidedState{LastDecidedFrame: lastDecidedFrame})
+	if err := engine.store.SetLastDecidedState(&consensusstore.LastDecidedState{LastDecidedFrame: lastDecidedFrame}); err != nil {
+		t.Fatal(err)
+	}
 	numAtropoiDelivered := consensus.Frame(0)
 	if err := engine.Bootstrap(consensus.ConsensusCallbacks{
 		BeginBlock: func(block *consensus.Block) consensus.BlockCallbacks {
@@ -107,6 +160,8 @@ func prepareTestRoot(
 	if err := lachesis.DagIndexer.Add(root); err != nil {
 		t.Fatal(err)
 	}
-	lachesis.store.AddRoot(root)
+	if err := lachesis.store.AddRoot(root); err != nil {
+		t.Fatal(err)
+	}
 	return root
 }
@@ -0,0 +1,181 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/finality"
+)
+
+// ErrFrameNotDecided is returned when the stored roots don't decide the frame.
+var ErrFrameNotDecided = errors.New("frame isn't decided")
+
+// Certificate exports the finality certificate of a decided frame of the current epoch, see finality.Verify.
+// The votes are recalculated from the stored roots, so it may be called from ApplyAtropos,
+// including the one of the frame which seals the epoch.
+// The certificate isn't signed, the application attaches the signatures of the validators over its SigningHash.
+func (p *Orderer) Certificate(frame consensus.Frame) (*finality.Certificate, error) {
+	pruned, err := p.store.GetPrunedFrame()
+	if err != nil {
+		return nil, err
+	}
+	if frame <= pruned {
+		return nil, fmt.Errorf("%w: roots of frame %d", consensus.ErrPruned, frame)
+	}
+	validators := p.store.GetValidators()
+	idxs := validators.Idxs()
+
+	prevRoots, err := p.sortedFrameRoots(frame)
+	if err != nil {
+		return nil, err
+	}
+	frames := make(map[consensus.EventHash]consensus.Frame)
+	for _, root := range prevRoots {
+		frames[root.RootHash] = frame
+	}
+	votes := make(map[consensus.EventHash][]int32)
+	observes := make(map[consensus.EventHash][]consensusstore.RootDescriptor)
+	for f := frame + 1; len(prevRoots) != 0; f++ {
+		roots, err := p.sortedFrameRoots(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, root := range roots {
+			frames[root.RootHash] = f
+			aggregation := make([]int32, validators.Len())
+			if f == frame+1 {
+				aggregation = initInt32WithConst(-1, len(aggregation))
+			}
+			observedWeight := int32(0)
+			for _, observed := range prevRoots {
+				ok, err := p.dagIndex.ForklessCause(root.RootHash, observed.RootHash)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+				observes[root.RootHash] = append(observes[root.RootHash], observed)
+				idx := idxs[observed.ValidatorID]
+				observedWeight += int32(validators.GetWeightByIdx(idx))
+				if f == frame+1 {
+					aggregation[idx] = 1
+				} else {
+					addInt32Vecs(aggregation, aggregation, votes[observed.RootHash])
+				}
+			}
+
+			if f >= frame+2 {
+				if candidate, q, ok := certificateDecision(validators, aggregation, observedWeight); ok {
+					cert := &finality.Certificate{
+						Epoch:     p.store.GetEpoch(),
+						Frame:     frame,
+						Decider:   root.RootHash,
+						YesWeight: consensus.Weight(aggregation[idxs[candidate]]),
+						Quorum:    consensus.Weight(q),
+					}
+					cert.Roots = certificateRoots(root, frames, observes)
+					for _, r := range cert.Roots {
+						if r.Frame == frame && r.Validator == candidate {
+							cert.Atropos = r.Hash
+							return cert, nil
+						}
+					}
+					return nil, p.critical(fmt.Errorf("%w: elected validator %d has no observed roots in frame %d", consensus.ErrInconsistentDB, candidate, frame))
+				}
+			}
+
+			normalizeInt32Vec(aggregation, aggregation)
+			mulInt32VecWithConst(aggregation, aggregation, int32(validators.GetWeightByIdx(idxs[root.ValidatorID])))
+			votes[root.RootHash] = aggregation
+		}
+		prevRoots = roots
+	}
+	return nil, fmt.Errorf("%w: frame %d", ErrFrameNotDecided, frame)
+}
+
+// sortedFrameRoots returns the frame roots ordered by validator and hash.
+func (p *Orderer) sortedFrameRoots(frame consensus.Frame) ([]consensusstore.RootDescriptor, error) {
+	roots, err := p.store.GetFrameRoots(frame)
+	if err != nil {
+		return nil, err
+	}
+	roots = slices.Clone(roots)
+	slices.SortFunc(roots, func(a, b consensusstore.RootDescriptor) int {
+		if a.ValidatorID != b.ValidatorID {
+			return cmp.Compare(a.ValidatorID, b.ValidatorID)
+		}
+		return bytes.Compare(a.RootHash[:], b.RootHash[:])
+	})
+	return roots, nil
+}
+
+// certificateDecision returns the elected validator and the quorum, the same way as election.decide does.
+func certificateDecision(validators *consensus.Validators, aggregation []int32, observedWeight int32) (consensus.ValidatorID, int32, bool) {
+	Q_0 := 4*int64(validators.TotalWeight()) - 3*int64(observedWeight)
+	Q := int32((Q_0 + 3 - 1) / 3)
+	idxs := validators.Idxs()
+	for _, candidate := range validators.SortedIDs() {
+		vote := aggregation[idxs[candidate]]
+		if vote >= Q {
+			return candidate, Q, true
+		}
+		if vote > -Q {
+			break
+		}
+	}
+	return 0, 0, false
+}
+
+// certificateRoots collects the roots which the decider observes, directly or indirectly.
+func certificateRoots(
+	decider consensusstore.RootDescriptor,
+	frames map[consensus.EventHash]consensus.Frame,
+	observes map[consensus.EventHash][]consensusstore.RootDescriptor,
+) []finality.Root {
+	included := consensus.EventHashSet{decider.RootHash: {}}
+	roots := make([]finality.Root, 0, len(observes))
+	queue := []consensusstore.RootDescriptor{decider}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		root := finality.Root{
+			Hash:      current.RootHash,
+			Frame:     frames[current.RootHash],
+			Validator: current.ValidatorID,
+		}
+		for _, observed := range observes[current.RootHash] {
+			root.Observes = append(root.Observes, observed.RootHash)
+			if _, ok := included[observed.RootHash]; !ok {
+				included[observed.RootHash] = struct{}{}
+				queue = append(queue, observed)
+			}
+		}
+		roots = append(roots, root)
+	}
+	slices.SortFunc(roots, func(a, b finality.Root) int {
+		if a.Frame != b.Frame {
+			return cmp.Compare(a.Frame, b.Frame)
+		}
+		if a.Validator != b.Validator {
+			return cmp.Compare(a.Validator, b.Validator)
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:])
+	})
+	return roots
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/finality"
+)
+
+func TestOrderer_Certificate(t *testing.T) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+
+	certified := 0
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		frame := lch.store.GetLastDecidedFrame() + 1
+		cert, err := lch.Certificate(frame)
+		if !assertar.NoError(err) {
+			return nil
+		}
+		assertar.Equal(lch.store.GetEpoch(), cert.Epoch)
+		assertar.Equal(frame, cert.Frame)
+		assertar.Equal(block.Atropos, cert.Atropos)
+		assertar.ErrorIs(finality.Verify(cert, lch.store.GetValidators(), testCertificateVerifier), finality.ErrInvalidCertificate, "not signed")
+		for _, validator := range lch.store.GetValidators().SortedIDs() {
+			cert.Signatures = append(cert.Signatures, finality.Signature{
+				Validator: validator,
+				Signature: testCertificateSign(validator, cert.SigningHash()),
+			})
+		}
+		assertar.NoError(finality.Verify(cert, lch.store.GetValidators(), testCertificateVerifier))
+
+		// the certificate survives encoding
+		encoded, err := rlp.EncodeToBytes(cert)
+		assertar.NoError(err)
+		var decoded finality.Certificate
+		assertar.NoError(rlp.DecodeBytes(encoded, &decoded))
+		assertar.NoError(finality.Verify(&decoded, lch.store.GetValidators(), testCertificateVerifier))
+		certified++
+
+		if frame == 10 {
+			return lch.store.GetValidators()
+		}
+		return nil
+	}
+
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				input.SetEvent(e)
+				assertar.NoError(lch.Process(e))
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lch.store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lch.Build(e)
+			},
+		})
+	}
+	assertar.Equal(consensus.Epoch(epochs+1), lch.store.GetEpoch())
+	assertar.Equal(len(lch.blocks), certified)
+
+	_, err := lch.Certificate(lch.store.GetLastDecidedFrame() + 100)
+	assertar.ErrorIs(err, ErrFrameNotDecided)
+}
+
+// testCertificateSign is a fake signature, which is checked by testCertificateVerifier.
+func testCertificateSign(validator consensus.ValidatorID, hash consensus.Hash) []byte {
+	return append(validator.Bytes(), hash.Bytes()...)
+}
+
+func testCertificateVerifier(validator consensus.ValidatorID, hash consensus.Hash, signature []byte) bool {
+	return bytes.Equal(signature, testCertificateSign(validator, hash))
+}
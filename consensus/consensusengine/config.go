@@ -10,14 +10,84 @@
 
 package consensusengine
 
+import (
+	"time"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
+)
+
 type Config struct {
 	// Suppresses the frame missmatch panic - used only for importing older historical event files, disabled by default
 	SuppressFramePanic bool
+	// Metrics receives the metrics of Orderer and election, optional
+	Metrics metrics.Registry
+	// EventsOrdering is the order in which Lachesis applies the confirmed events of a block
+	EventsOrdering EventsOrdering
+	// Pruning limits what IndexedLachesis.Prune removes from the epoch DB
+	Pruning PruningConfig
+	// ElectionSnapshotFrames is the number of decided frames between the election snapshots.
+	// If 0, the election is snapshotted only on Bootstrap and SaveElectionState.
+	// A stale snapshot is ignored on Bootstrap, and the undecided roots are replayed instead.
+	ElectionSnapshotFrames consensus.Frame
+}
+
+// PruningConfig is the safety margins of the epoch DB pruning, see IndexedLachesis.Prune.
+type PruningConfig struct {
+	// KeepFrames is the number of frames below the last decided frame, which aren't pruned.
+	// A pruned node accepts the events whose parents are in frames above lastDecided-KeepFrames,
+	// and rejects the events with a parent in a pruned frame with consensus.ErrPruned, while a node without pruning accepts them.
+	// So pruning is safe only if KeepFrames is at or above maxLag+1, where the lag of an event is the number of frames
+	// between its lowest parent frame and the last decided frame once the event is processed,
+	// e.g. the lag of the slowest honest validator. Otherwise the pruned nodes may diverge from the others.
+	KeepFrames consensus.Frame
+	// MinFrames is the minimal number of frames to prune at once, so a pass isn't run for every decided frame
+	MinFrames consensus.Frame
 }
 
+// EventsOrdering is the order of confirmed events inside a block.
+type EventsOrdering uint8
+
+const (
+	// OrderingTraversal is the order of the DFS traversal from the atropos. It's deterministic, but not canonical.
+	OrderingTraversal EventsOrdering = iota
+	// OrderingLamport orders events by Lamport time, then by hash.
+	OrderingLamport
+	// OrderingTopological orders events after their parents, by creator, then by hash.
+	OrderingTopological
+)
+
 // DefaultConfig for livenet.
 func DefaultConfig() Config {
 	return Config{
 		SuppressFramePanic: false,
+		Pruning: PruningConfig{
+			KeepFrames: 100,
+			MinFrames:  100,
+		},
+		ElectionSnapshotFrames: 10,
+	}
+}
+
+// EventBufferConfig limits the memory held by EventBuffer.
+// The zero MaxEvents, MaxSize and MaxDropped are replaced by the DefaultEventBufferConfig values.
+type EventBufferConfig struct {
+	// MaxEvents is the maximum number of incomplete events held at once
+	MaxEvents int
+	// MaxSize is the maximum total size (see consensus.Event.Size) of incomplete events
+	MaxSize uint64
+	// MaxAge is the duration after which an incomplete event is dropped, 0 disables the limit
+	MaxAge time.Duration
+	// MaxDropped is the number of dropped event IDs remembered, so their late children are dropped at once
+	MaxDropped int
+}
+
+// DefaultEventBufferConfig for livenet.
+func DefaultEventBufferConfig() EventBufferConfig {
+	return EventBufferConfig{
+		MaxEvents:  10000,
+		MaxSize:    10 * 1024 * 1024,
+		MaxAge:     10 * time.Minute,
+		MaxDropped: 10000,
 	}
 }
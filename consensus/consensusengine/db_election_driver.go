@@ -146,9 +146,14 @@ func processLocalEvent(testLachesis *CoreLachesis, event *consensustest.TestEven
 	if targetFrame != event.Frame() {
 		return fmt.Errorf("incorrect frame recalculated for event: [validator: %d, seq: %d], expected: %d, got: %d", event.Creator(), event.Seq(), targetFrame, event.Frame())
 	}
-	selfParentFrame := testLachesis.getSelfParentFrame(event)
+	selfParentFrame, err := testLachesis.getSelfParentFrame(event)
+	if err != nil {
+		return fmt.Errorf("error wihile reading self-parent of event: [validator: %d, seq: %d], err: %v", event.Creator(), event.Seq(), err)
+	}
 	if selfParentFrame != event.Frame() {
-		testLachesis.store.AddRoot(event)
+		if err := testLachesis.store.AddRoot(event); err != nil {
+			return fmt.Errorf("error wihile saving root: [validator: %d, seq: %d], err: %v", event.Creator(), event.Seq(), err)
+		}
 		if _, err := testLachesis.runElectionOnRoot(event.Frame(), event.Creator(), event.ID()); err != nil {
 			return fmt.Errorf("error wihile processing event: [validator: %d, seq: %d], err: %v", event.Creator(), event.Seq(), err)
 		}
@@ -15,11 +15,14 @@ import (
 
 	"github.com/0xsoniclabs/consensus/consensus"
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 )
 
 type (
-	ForklessCauseFn func(a consensus.EventHash, b consensus.EventHash) bool
-	GetFrameRootsFn func(f consensus.Frame) []consensusstore.RootDescriptor
+	// ForklessCauseRootsFn evaluates ForklessCause of the event by every root, see dagindexer.Index.ForklessCauseRoots
+	ForklessCauseRootsFn func(a consensus.EventHash, roots consensus.EventHashes) (*dagindexer.ObservedRoots, error)
+	GetFrameRootsFn      func(f consensus.Frame) ([]consensusstore.RootDescriptor, error)
 )
 
 type atroposDecision struct {
@@ -35,8 +38,10 @@ type rootVoteContext struct {
 type election struct {
 	validators *consensus.Validators
 
-	forklessCauses ForklessCauseFn
-	getFrameRoots  GetFrameRootsFn
+	forklessCauseRoots ForklessCauseRootsFn
+	getFrameRoots      GetFrameRootsFn
+	// roots is reused by observedRoots for the hashes of the frame roots
+	roots consensus.EventHashes
 
 	vote           map[consensus.Frame][]map[consensus.EventHash]*rootVoteContext
 	validatorIDMap map[consensus.ValidatorID]consensus.ValidatorIndex
@@ -44,23 +49,37 @@ type election struct {
 
 	atroposDeliveryBuffer *atroposHeap
 	frameToDeliver        consensus.Frame
+
+	metrics struct {
+		voteMatrixSize  metrics.Histogram
+		undecidedFrames metrics.Gauge
+		decidedFrames   metrics.Counter
+	}
 }
 
 func NewElection(
 	frameToDeliver consensus.Frame,
 	validators *consensus.Validators,
-	forklessCauseFn ForklessCauseFn,
+	forklessCauseRootsFn ForklessCauseRootsFn,
 	getFrameRoots GetFrameRootsFn,
 ) *election {
 	election := &election{
-		forklessCauses: forklessCauseFn,
-		getFrameRoots:  getFrameRoots,
-		validators:     validators,
+		forklessCauseRoots: forklessCauseRootsFn,
+		getFrameRoots:      getFrameRoots,
+		validators:         validators,
 	}
 	election.ResetEpoch(frameToDeliver, validators)
+	election.initMetrics(nil)
 	return election
 }
 
+func (el *election) initMetrics(registry metrics.Registry) {
+	registry = metrics.OrNoop(registry)
+	el.metrics.voteMatrixSize = registry.Histogram("election/vote_matrix_size")
+	el.metrics.undecidedFrames = registry.Gauge("election/undecided_frames")
+	el.metrics.decidedFrames = registry.Counter("election/decided_frames")
+}
+
 func (el *election) ResetEpoch(frameToDeliver consensus.Frame, validators *consensus.Validators) {
 	el.atroposDeliveryBuffer = NewAtroposHeap()
 	el.frameToDeliver = frameToDeliver
@@ -84,7 +103,10 @@ func (el *election) VoteAndAggregate(
 	aggregationMatrix := make([]int32, (frame-el.frameToDeliver-1)*el.validatorCount, (frame-el.frameToDeliver)*el.validatorCount)
 	directVoteVector := initInt32WithConst(-1, int(el.validatorCount))
 
-	observedRoots := el.observedRoots(rootHash, frame-1)
+	observedRoots, err := el.observedRoots(rootHash, frame-1)
+	if err != nil {
+		return nil, err
+	}
 	observedRootsWeight := int32(0)
 
 	for _, observedRoot := range observedRoots {
@@ -100,20 +122,24 @@ func (el *election) VoteAndAggregate(
 		}
 	}
 
-	el.decide(frame, aggregationMatrix, observedRootsWeight)
+	if err := el.decide(frame, aggregationMatrix, observedRootsWeight); err != nil {
+		return nil, err
+	}
 
 	normalizeInt32Vec(aggregationMatrix, aggregationMatrix)
 	aggregationMatrix = append(aggregationMatrix, directVoteVector...)
 
 	mulInt32VecWithConst(aggregationMatrix, aggregationMatrix, int32(el.validators.GetWeightByIdx(validatorIdx)))
 	el.vote[frame][validatorIdx][rootHash].voteMatrix = aggregationMatrix
+	el.metrics.voteMatrixSize.Observe(float64(len(aggregationMatrix)))
+	el.metrics.undecidedFrames.Set(int64(len(el.vote)))
 
 	atropoi := el.atroposDeliveryBuffer.getDeliveryReadyAtropoi(el.frameToDeliver)
 	el.frameToDeliver += consensus.Frame(len(atropoi))
 	return atropoi, nil
 }
 
-func (el *election) decide(aggregatingFrame consensus.Frame, aggregationMatr []int32, observedRootsWeight int32) {
+func (el *election) decide(aggregatingFrame consensus.Frame, aggregationMatr []int32, observedRootsWeight int32) error {
 	// Q = ceil((4*TotalValidatorWeight - 3*observedRootsWeight)/3)
 	// numerator (Q_0) can exceed the int32 limits before division
 	Q_0 := 4*int64(el.validators.TotalWeight()) - 3*int64(observedRootsWeight)
@@ -131,8 +157,12 @@ func (el *election) decide(aggregatingFrame consensus.Frame, aggregationMatr []i
 			voteMatrixOffset := (frame-el.frameToDeliver)*el.validatorCount + consensus.Frame(validatorIdx)
 
 			if yesDecisions[voteMatrixOffset] {
-				atroposHash := el.elect(frame, candidateValidator)
+				atroposHash, err := el.elect(frame, candidateValidator)
+				if err != nil {
+					return err
+				}
 				heap.Push(el.atroposDeliveryBuffer, &atroposDecision{frame, atroposHash})
+				el.metrics.decidedFrames.Inc(1)
 				el.cleanupDecidedFrame(frame)
 				break
 			}
@@ -142,13 +172,14 @@ func (el *election) decide(aggregatingFrame consensus.Frame, aggregationMatr []i
 			}
 		}
 	}
+	return nil
 }
 
 // elect picks the final atropos event once its frame and validator number have been finalized
 // by the "upper frame" root votes'. This is trivial in case of non-forking events as such
 // roots are uniquely identified by (frame, validator).
 // In the case of a fork, a tiebreaker algorithm has to be run.
-func (el *election) elect(frame consensus.Frame, validatorCandidate consensus.ValidatorID) consensus.EventHash {
+func (el *election) elect(frame consensus.Frame, validatorCandidate consensus.ValidatorID) (consensus.EventHash, error) {
 	validatorIdx := el.validatorIDMap[validatorCandidate]
 	candidateMap := el.vote[frame][validatorIdx]
 	atroposHash := consensus.EventHash{}
@@ -159,28 +190,50 @@ func (el *election) elect(frame consensus.Frame, validatorCandidate consensus.Va
 	// It is easiest to look for any vote (forkless cause) by frame + 1 roots.
 	// Due to forkless cause semantics, only one forkless-caused root can exist with specified frame and validator number.
 	if len(candidateMap) > 1 {
-		judgeRoots := el.getFrameRoots(frame + 1)
+		judgeRoots, err := el.getFrameRoots(frame + 1)
+		if err != nil {
+			return consensus.EventHash{}, err
+		}
+		candidates := make(consensus.EventHashes, 0, len(candidateMap))
 		for atroposCandidateHash := range candidateMap {
-			for _, judge := range judgeRoots {
-				if el.forklessCauses(judge.RootHash, atroposCandidateHash) {
-					return atroposCandidateHash
+			candidates = append(candidates, atroposCandidateHash)
+		}
+		for _, judge := range judgeRoots {
+			observed, err := el.forklessCauseRoots(judge.RootHash, candidates)
+			if err != nil {
+				return consensus.EventHash{}, err
+			}
+			for i, ok := range observed.Observed {
+				if ok {
+					return candidates[i], nil
 				}
 			}
 		}
 	}
 
-	return atroposHash
+	return atroposHash, nil
 }
 
-func (el *election) observedRoots(root consensus.EventHash, frame consensus.Frame) []consensusstore.RootDescriptor {
+func (el *election) observedRoots(root consensus.EventHash, frame consensus.Frame) ([]consensusstore.RootDescriptor, error) {
 	observedRoots := make([]consensusstore.RootDescriptor, 0, el.validators.Len())
-	frameRoots := el.getFrameRoots(frame)
+	frameRoots, err := el.getFrameRoots(frame)
+	if err != nil {
+		return nil, err
+	}
+	el.roots = el.roots[:0]
 	for _, frameRoot := range frameRoots {
-		if el.forklessCauses(root, frameRoot.RootHash) {
+		el.roots = append(el.roots, frameRoot.RootHash)
+	}
+	observed, err := el.forklessCauseRoots(root, el.roots)
+	if err != nil {
+		return nil, err
+	}
+	for i, frameRoot := range frameRoots {
+		if observed.Observed[i] {
 			observedRoots = append(observedRoots, frameRoot)
 		}
 	}
-	return observedRoots
+	return observedRoots, nil
 }
 
 func (el *election) prepareNewElectorRoot(frame consensus.Frame, validatorIdx consensus.ValidatorIndex, root consensus.EventHash) {
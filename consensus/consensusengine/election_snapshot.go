@@ -0,0 +1,106 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+)
+
+// snapshot serializes the election state, which covers the specified roots.
+// The output is deterministic.
+func (el *election) snapshot(roots []consensus.EventHash) *consensusstore.ElectionState {
+	state := &consensusstore.ElectionState{
+		FrameToDeliver: el.frameToDeliver,
+		Roots:          roots,
+		Votes:          []consensusstore.ElectionVote{},
+		Atropoi:        make([]consensusstore.ElectionAtropos, 0, el.atroposDeliveryBuffer.Len()),
+	}
+
+	frames := make([]consensus.Frame, 0, len(el.vote))
+	for frame := range el.vote {
+		frames = append(frames, frame)
+	}
+	slices.Sort(frames)
+	for _, frame := range frames {
+		for validatorIdx, rootContexts := range el.vote[frame] {
+			hashes := make(consensus.EventHashes, 0, len(rootContexts))
+			for hash := range rootContexts {
+				hashes = append(hashes, hash)
+			}
+			slices.SortFunc(hashes, func(a, b consensus.EventHash) int {
+				return bytes.Compare(a[:], b[:])
+			})
+			for _, hash := range hashes {
+				rootContext := rootContexts[hash]
+				voteMatrix := make([]uint32, len(rootContext.voteMatrix))
+				for i, v := range rootContext.voteMatrix {
+					voteMatrix[i] = uint32(v)
+				}
+				state.Votes = append(state.Votes, consensusstore.ElectionVote{
+					Frame:                frame,
+					ValidatorIdx:         consensus.ValidatorIndex(validatorIdx),
+					Root:                 hash,
+					FrameToDeliverOffset: rootContext.frameToDeliverOffset,
+					VoteMatrix:           voteMatrix,
+				})
+			}
+		}
+	}
+
+	for _, decision := range el.atroposDeliveryBuffer.container {
+		state.Atropoi = append(state.Atropoi, consensusstore.ElectionAtropos{
+			Frame:   decision.Frame,
+			Atropos: decision.AtroposHash,
+		})
+	}
+	slices.SortFunc(state.Atropoi, func(a, b consensusstore.ElectionAtropos) int {
+		return cmp.Compare(a.Frame, b.Frame)
+	})
+	return state
+}
+
+// restore replaces the election state with the snapshot.
+// Returns false, leaving the election reset, if the snapshot doesn't match the election's validators.
+func (el *election) restore(state *consensusstore.ElectionState) bool {
+	el.ResetEpoch(state.FrameToDeliver, el.validators)
+
+	for _, vote := range state.Votes {
+		if consensus.Frame(vote.ValidatorIdx) >= el.validatorCount || len(vote.VoteMatrix) != 0 &&
+			(vote.Frame < vote.FrameToDeliverOffset || consensus.Frame(len(vote.VoteMatrix)) != (vote.Frame-vote.FrameToDeliverOffset)*el.validatorCount) {
+			el.ResetEpoch(state.FrameToDeliver, el.validators)
+			return false
+		}
+		el.prepareNewElectorRoot(vote.Frame, vote.ValidatorIdx, vote.Root)
+		rootContext := el.vote[vote.Frame][vote.ValidatorIdx][vote.Root]
+		rootContext.frameToDeliverOffset = vote.FrameToDeliverOffset
+		if len(vote.VoteMatrix) != 0 {
+			rootContext.voteMatrix = make([]int32, len(vote.VoteMatrix))
+			for i, v := range vote.VoteMatrix {
+				rootContext.voteMatrix[i] = int32(v)
+			}
+		}
+	}
+
+	for _, atropos := range state.Atropoi {
+		if atropos.Frame < state.FrameToDeliver {
+			el.ResetEpoch(state.FrameToDeliver, el.validators)
+			return false
+		}
+		heap.Push(el.atroposDeliveryBuffer, &atroposDecision{atropos.Frame, atropos.Atropos})
+	}
+	return true
+}
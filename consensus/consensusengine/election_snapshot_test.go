@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+// testSnapshotLachesis processes random events until a few frames are decided.
+// Returns the instance and the events which aren't processed yet.
+func testSnapshotLachesis(t *testing.T) (*CoreLachesis, consensus.Events) {
+	nodes := consensustest.GenNodes(5)
+	generator, _, generatorInput, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch.config.ElectionSnapshotFrames = 1
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assert.NoError(t, generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+
+	for i, e := range events {
+		decided := lch.store.GetLastDecidedFrame()
+		input.SetEvent(e)
+		assert.NoError(t, lch.Process(e))
+		if decided != lch.store.GetLastDecidedFrame() && decided >= 3 {
+			return lch, events[i+1:]
+		}
+	}
+	t.Fatal("not enough frames are decided")
+	return nil, nil
+}
+
+// testSnapshotRestart restarts lch from a copy of its databases.
+func testSnapshotRestart(t *testing.T, lch *CoreLachesis) *CoreLachesis {
+	restored := &CoreLachesis{
+		IndexedLachesis: restartLachesis(assert.New(t), lch),
+		blocks:          maps.Clone(lch.blocks),
+		lastBlock:       lch.lastBlock,
+		epochBlocks:     maps.Clone(lch.epochBlocks),
+	}
+	assert.NoError(t, restored.Bootstrap(restored.consensusCallbacks()))
+	return restored
+}
+
+func TestElectionSnapshot_RoundTrip(t *testing.T) {
+	lch, _ := testSnapshotLachesis(t)
+
+	state, err := lch.store.GetElectionState()
+	assert.NoError(t, err)
+	assert.NotNil(t, state)
+	assert.Equal(t, lch.election.frameToDeliver, state.FrameToDeliver)
+	assert.NotEmpty(t, state.Roots)
+	assert.NotEmpty(t, state.Votes)
+
+	restored := NewElection(consensus.FirstFrame, lch.store.GetValidators(), nil, nil)
+	assert.True(t, restored.restore(state))
+	assert.Equal(t, state, restored.snapshot(state.Roots))
+	assert.Equal(t, lch.election.vote, restored.vote)
+	assert.Equal(t, lch.election.atroposDeliveryBuffer.Len(), restored.atroposDeliveryBuffer.Len())
+
+	invalid := *state
+	invalid.Votes = append([]consensusstore.ElectionVote{}, state.Votes...)
+	invalid.Votes[0].ValidatorIdx = consensus.ValidatorIndex(lch.store.GetValidators().Len())
+	assert.False(t, restored.restore(&invalid))
+	assert.Empty(t, restored.vote)
+}
+
+func TestElectionSnapshot_BootstrapResumesFromSnapshot(t *testing.T) {
+	lch, rest := testSnapshotLachesis(t)
+
+	// mark the snapshot, to check it's used instead of replaying the roots
+	state, err := lch.store.GetElectionState()
+	assert.NoError(t, err)
+	var marked *consensusstore.ElectionVote
+	for i := range state.Votes {
+		if len(state.Votes[i].VoteMatrix) != 0 {
+			marked = &state.Votes[i]
+			break
+		}
+	}
+	if !assert.NotNil(t, marked) {
+		return
+	}
+	marked.VoteMatrix[0]++
+	assert.NoError(t, lch.store.SetElectionState(state))
+
+	restored := testSnapshotRestart(t, lch)
+	assert.Equal(t, state, restored.election.snapshot(state.Roots))
+
+	// restore the original snapshot and check the processing continues as without restart
+	marked.VoteMatrix[0]--
+	assert.NoError(t, lch.store.SetElectionState(state))
+	restored = testSnapshotRestart(t, lch)
+	testSnapshotContinue(t, lch, restored, rest)
+}
+
+func TestElectionSnapshot_BootstrapReplaysUncoveredRoots(t *testing.T) {
+	lch, rest := testSnapshotLachesis(t)
+
+	// process events until new roots are added, without a decision
+	decided := lch.store.GetLastDecidedFrame()
+	for len(rest) != 0 {
+		e := rest[0]
+		rest = rest[1:]
+		lch.Input.(*consensustest.TestEventSource).SetEvent(e)
+		assert.NoError(t, lch.Process(e))
+		if decided != lch.store.GetLastDecidedFrame() {
+			t.Fatal("frame is decided before a new root")
+		}
+		roots, err := lch.store.GetFrameRoots(e.Frame())
+		assert.NoError(t, err)
+		if slices.ContainsFunc(roots, func(root consensusstore.RootDescriptor) bool { return root.RootHash == e.ID() }) {
+			break
+		}
+	}
+	state, err := lch.store.GetElectionState()
+	assert.NoError(t, err)
+	assert.Equal(t, decided+1, state.FrameToDeliver)
+
+	restored := testSnapshotRestart(t, lch)
+	testSnapshotContinue(t, lch, restored, rest)
+}
+
+func TestElectionSnapshot_BootstrapReplaysIfStale(t *testing.T) {
+	lch, rest := testSnapshotLachesis(t)
+
+	state, err := lch.store.GetElectionState()
+	assert.NoError(t, err)
+	state.FrameToDeliver--
+	assert.NoError(t, lch.store.SetElectionState(state))
+
+	restored := testSnapshotRestart(t, lch)
+	testSnapshotContinue(t, lch, restored, rest)
+
+	// the replayed state is saved
+	state, err = restored.store.GetElectionState()
+	assert.NoError(t, err)
+	assert.Equal(t, restored.store.GetLastDecidedFrame()+1, state.FrameToDeliver)
+}
+
+func TestElectionSnapshot_Interval(t *testing.T) {
+	assertar := assert.New(t)
+
+	const interval = 3
+	nodes := consensustest.GenNodes(5)
+	generator, _, generatorInput, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch.config.ElectionSnapshotFrames = interval
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, TestMaxEpochEvents, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assertar.NoError(generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+
+	// the snapshot is written only once the interval of frames is decided
+	snapshots := 0
+	snapshotFrame := consensus.Frame(0)
+	half := len(events) / 2
+	for _, e := range events[:half] {
+		input.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+		state, err := lch.store.GetElectionState()
+		assertar.NoError(err)
+		if state == nil || state.FrameToDeliver == snapshotFrame {
+			continue
+		}
+		assertar.GreaterOrEqual(state.FrameToDeliver, snapshotFrame+interval)
+		snapshotFrame = state.FrameToDeliver
+		snapshots++
+	}
+	assertar.Greater(lch.store.GetLastDecidedFrame(), consensus.Frame(2*interval))
+	assertar.NotZero(snapshots)
+
+	// the snapshot is brought up to date explicitly, e.g. before a shutdown
+	assertar.NoError(lch.SaveElectionState())
+	state, err := lch.store.GetElectionState()
+	assertar.NoError(err)
+	assertar.Equal(lch.store.GetLastDecidedFrame()+1, state.FrameToDeliver)
+
+	restored := testSnapshotRestart(t, lch)
+	testSnapshotContinue(t, lch, restored, events[half:])
+}
+
+// testSnapshotContinue processes the rest of events and compares decisions with the instance which wasn't restarted.
+func testSnapshotContinue(t *testing.T, lch, restored *CoreLachesis, rest consensus.Events) {
+	assertar := assert.New(t)
+	lastBlock := lch.lastBlock
+	for _, e := range rest {
+		lch.Input.(*consensustest.TestEventSource).SetEvent(e)
+		assertar.NoError(lch.Process(e))
+		assertar.NoError(restored.Process(e))
+		compareStates(assertar, lch, restored)
+	}
+	assertar.NotEqual(lastBlock, lch.lastBlock)
+	compareBlocks(assertar, lch, restored)
+}
@@ -20,9 +20,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/0xsoniclabs/cacheutils/cachescale"
 	"github.com/0xsoniclabs/consensus/consensus"
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
 	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
 )
 
 type fakeEdge struct {
@@ -258,15 +260,25 @@ func testVoteAndAggregate(
 	}
 	validators := validatorsBuilder.Build()
 
-	forklessCauseFn := func(a consensus.EventHash, b consensus.EventHash) bool {
-		edge := fakeEdge{
-			from: a,
-			to:   b,
+	forklessCauseRootsFn := func(a consensus.EventHash, roots consensus.EventHashes) (*dagindexer.ObservedRoots, error) {
+		observed := &dagindexer.ObservedRoots{
+			Observed: make([]bool, len(roots)),
+			Weight:   validators.NewCounter(),
 		}
-		return state.edges[edge]
+		for i, b := range roots {
+			edge := fakeEdge{
+				from: a,
+				to:   b,
+			}
+			if state.edges[edge] {
+				observed.Observed[i] = true
+				observed.Weight.CountVoteByID(state.vertices[b].validatorID)
+			}
+		}
+		return observed, nil
 	}
-	getFrameRootsFn := func(f consensus.Frame) []consensusstore.RootDescriptor {
-		return state.frameRoots[f]
+	getFrameRootsFn := func(f consensus.Frame) ([]consensusstore.RootDescriptor, error) {
+		return state.frameRoots[f], nil
 	}
 
 	// re-order events randomly, preserving parents order
@@ -276,7 +288,7 @@ func testVoteAndAggregate(
 	}
 	state.ordered = unordered.ByParents()
 
-	election := NewElection(consensus.FirstFrame, validators, forklessCauseFn, getFrameRootsFn)
+	election := NewElection(consensus.FirstFrame, validators, forklessCauseRootsFn, getFrameRootsFn)
 
 	// processing:
 	for _, root := range state.ordered {
@@ -354,3 +366,75 @@ func indexTestEvent(state *testState, root *consensustest.TestEvent, isFork bool
 		}
 	}
 }
+
+func BenchmarkElection_ObservedRoots(b *testing.B) {
+	benchElectionObservedRoots(b, func(vi *dagindexer.Index) ForklessCauseRootsFn {
+		return vi.ForklessCauseRoots
+	})
+}
+
+// BenchmarkElection_ObservedRoots_PerRoot evaluates ForklessCause per root, as the election did before ForklessCauseRoots
+func BenchmarkElection_ObservedRoots_PerRoot(b *testing.B) {
+	benchElectionObservedRoots(b, func(vi *dagindexer.Index) ForklessCauseRootsFn {
+		return func(a consensus.EventHash, roots consensus.EventHashes) (*dagindexer.ObservedRoots, error) {
+			observed := &dagindexer.ObservedRoots{Observed: make([]bool, len(roots))}
+			for i, root := range roots {
+				ok, err := vi.ForklessCause(a, root)
+				if err != nil {
+					return nil, err
+				}
+				observed.Observed[i] = ok
+			}
+			return observed, nil
+		}
+	})
+}
+
+// benchElectionObservedRoots evaluates the roots of the previous frame by every root of the epoch, as VoteAndAggregate does
+func benchElectionObservedRoots(b *testing.B, forklessCauseRoots func(vi *dagindexer.Index) ForklessCauseRootsFn) {
+	b.Helper()
+	nodes := consensustest.GenNodes(50)
+	cfg := dagindexer.DefaultConfig(cachescale.Identity)
+	// the pairs aren't cached, as after a restart
+	cfg.Caches.ForklessCausePairs = 1
+	engine, store, input, vi := newCoreConsensus(nodes, nil, consensusstore.NewMemStore(), cfg)
+	lch := bootstrapCoreConsensus(engine)
+	consensustest.ForEachRandEvent(nodes, 20, 5, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			if err := lch.Process(e); err != nil {
+				b.Fatal(err)
+			}
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lch.Build(e)
+		},
+	})
+	if store.GetEpoch() != consensus.FirstEpoch {
+		b.Fatal("epoch is sealed")
+	}
+	var frames [][]consensusstore.RootDescriptor
+	for f := consensus.FirstFrame; ; f++ {
+		roots, err := store.GetFrameRoots(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(roots) == 0 {
+			break
+		}
+		frames = append(frames, roots)
+	}
+	el := NewElection(consensus.FirstFrame, store.GetValidators(), forklessCauseRoots(vi), store.GetFrameRoots)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for f := 1; f < len(frames); f++ {
+			for _, root := range frames[f] {
+				if _, err := el.observedRoots(root.RootHash, consensus.FirstFrame+consensus.Frame(f-1)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
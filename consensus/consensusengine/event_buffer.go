@@ -0,0 +1,372 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+var (
+	// ErrEventEvicted is reported for an incomplete event dropped to respect the memory limits.
+	ErrEventEvicted = errors.New("event evicted from buffer")
+	// ErrEventExpired is reported for an incomplete event which has been waiting for its parents for too long.
+	ErrEventExpired = errors.New("event expired in buffer")
+	// ErrOrphanEvent is reported for an event which can never be connected, because one of its ancestors was rejected or dropped.
+	// A child pushed after its parent was dropped is reported at once, as long as the parent is remembered (see EventBufferConfig.MaxDropped).
+	ErrOrphanEvent = errors.New("orphan event")
+)
+
+// EventBufferCallbacks contains the callbacks of EventBuffer.
+type EventBufferCallbacks struct {
+	// Process is called for every event whose parents are all known, parents first.
+	// A successfully processed event must be reported by EventSource.HasEvent afterwards.
+	Process func(e consensus.Event) error
+	// Dropped is called for every event which leaves the buffer without being processed successfully.
+	// The error is either the processing error or wraps one of ErrEventEvicted, ErrEventExpired, ErrOrphanEvent. Optional.
+	Dropped func(e consensus.Event, err error)
+}
+
+type bufferedEvent struct {
+	event   consensus.Event
+	missing int // number of parents which aren't processed yet
+	added   time.Time
+}
+
+// EventBuffer accepts events in any order and passes them to EventBufferCallbacks.Process
+// in a valid topological order, once all of their parents are known by the EventSource.
+// Not safe for concurrent use.
+type EventBuffer struct {
+	input     EventSource
+	callbacks EventBufferCallbacks
+	config    EventBufferConfig
+
+	incomplete map[consensus.EventHash]*bufferedEvent
+	// children maps a missing parent to the incomplete events waiting for it
+	children map[consensus.EventHash]consensus.EventHashes
+	// queue holds incomplete events in order of addition, dropped entries are skipped lazily
+	queue consensus.EventHashes
+	size  uint64
+
+	// dropped remembers the recently dropped events, mapped to their position in droppedQueue
+	dropped      map[consensus.EventHash]uint64
+	droppedQueue []droppedEvent
+	droppedSeq   uint64
+
+	now func() time.Time
+}
+
+type droppedEvent struct {
+	id  consensus.EventHash
+	seq uint64
+}
+
+// NewEventBuffer creates an EventBuffer in front of callbacks.Process.
+// The zero limits of config are replaced by the DefaultEventBufferConfig values.
+func NewEventBuffer(input EventSource, callbacks EventBufferCallbacks, config EventBufferConfig) *EventBuffer {
+	def := DefaultEventBufferConfig()
+	if config.MaxEvents == 0 {
+		config.MaxEvents = def.MaxEvents
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = def.MaxSize
+	}
+	if config.MaxDropped == 0 {
+		config.MaxDropped = def.MaxDropped
+	}
+	return &EventBuffer{
+		input:      input,
+		callbacks:  callbacks,
+		config:     config,
+		incomplete: make(map[consensus.EventHash]*bufferedEvent),
+		children:   make(map[consensus.EventHash]consensus.EventHashes),
+		dropped:    make(map[consensus.EventHash]uint64),
+		now:        time.Now,
+	}
+}
+
+// Push adds an event to the buffer.
+// The event, and every buffered event which was waiting for it, is processed as soon as its parents are known.
+func (b *EventBuffer) Push(e consensus.Event) {
+	b.expire()
+	if b.input.HasEvent(e.ID()) || b.IsBuffered(e.ID()) {
+		return
+	}
+	// a dropped event may be pushed again
+	delete(b.dropped, e.ID())
+
+	parents := uniqueHashes(e.Parents())
+	for _, p := range parents {
+		if _, ok := b.dropped[p]; ok && !b.input.HasEvent(p) {
+			b.report(e, fmt.Errorf("%w: parent %s was dropped", ErrOrphanEvent, p))
+			return
+		}
+	}
+
+	buffered := &bufferedEvent{
+		event: e,
+		added: b.now(),
+	}
+	for _, p := range parents {
+		if !b.input.HasEvent(p) {
+			buffered.missing++
+			b.children[p] = append(b.children[p], e.ID())
+		}
+	}
+	if buffered.missing == 0 {
+		b.process(e)
+		return
+	}
+
+	b.incomplete[e.ID()] = buffered
+	b.queue = append(b.queue, e.ID())
+	b.size += uint64(e.Size())
+	b.evict()
+}
+
+// IsBuffered returns true if the event is held by the buffer, waiting for its parents.
+func (b *EventBuffer) IsBuffered(id consensus.EventHash) bool {
+	_, ok := b.incomplete[id]
+	return ok
+}
+
+// Len returns the number of buffered events.
+func (b *EventBuffer) Len() int {
+	return len(b.incomplete)
+}
+
+// Size returns the total size of buffered events.
+func (b *EventBuffer) Size() uint64 {
+	return b.size
+}
+
+// MissingParents returns the events which are awaited by the buffered events,
+// but are neither known nor buffered. These have to be requested from peers.
+func (b *EventBuffer) MissingParents() consensus.EventHashes {
+	missing := make(consensus.EventHashes, 0, len(b.children))
+	for p := range b.children {
+		if !b.IsBuffered(p) && !b.input.HasEvent(p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// Known releases the buffered events waiting for the event, which became known by the EventSource
+// without passing through the buffer, e.g. if it was processed directly.
+func (b *EventBuffer) Known(id consensus.EventHash) {
+	delete(b.dropped, id)
+	if buffered, ok := b.incomplete[id]; ok {
+		// the event isn't dropped, as it's processed already
+		b.unlink(buffered)
+	}
+	waiting := b.children[id]
+	delete(b.children, id)
+	for _, e := range b.release(waiting) {
+		b.process(e)
+	}
+	b.compact()
+}
+
+// Clear drops all buffered events, reporting them as evicted, and forgets the dropped events.
+func (b *EventBuffer) Clear() {
+	for _, id := range b.queue {
+		if buffered, ok := b.incomplete[id]; ok {
+			b.drop(buffered, fmt.Errorf("%w: buffer cleared", ErrEventEvicted))
+		}
+	}
+	b.queue = nil
+	b.dropped = make(map[consensus.EventHash]uint64)
+	b.droppedQueue = nil
+}
+
+// process passes the event to the callback and releases its descendants which became complete.
+func (b *EventBuffer) process(e consensus.Event) {
+	stack := consensus.Events{e}
+	for len(stack) != 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		waiting := b.children[e.ID()]
+		delete(b.children, e.ID())
+
+		if err := b.callbacks.Process(e); err != nil {
+			b.report(e, err)
+			b.dropDescendants(waiting, e.ID(), fmt.Errorf("rejected: %w", err))
+			continue
+		}
+
+		stack = append(stack, b.release(waiting)...)
+	}
+	b.compact()
+}
+
+// release counts the parent as known for the waiting events, and returns the events which became complete.
+func (b *EventBuffer) release(waiting consensus.EventHashes) consensus.Events {
+	var complete consensus.Events
+	for _, id := range waiting {
+		child, ok := b.incomplete[id]
+		if !ok {
+			continue
+		}
+		child.missing--
+		if child.missing == 0 {
+			b.remove(child)
+			complete = append(complete, child.event)
+		}
+	}
+	return complete
+}
+
+// dropDescendants drops the buffered events which can never be connected because of a rejected or dropped ancestor.
+func (b *EventBuffer) dropDescendants(waiting consensus.EventHashes, ancestor consensus.EventHash, cause error) {
+	for len(waiting) != 0 {
+		id := waiting[len(waiting)-1]
+		waiting = waiting[:len(waiting)-1]
+		child, ok := b.incomplete[id]
+		if !ok {
+			continue
+		}
+		waiting = append(waiting, b.children[id]...)
+		delete(b.children, id)
+		b.unlink(child)
+		b.report(child.event, fmt.Errorf("%w: ancestor %s %w", ErrOrphanEvent, ancestor, cause))
+	}
+}
+
+// evict drops the oldest events while the memory limits are exceeded.
+func (b *EventBuffer) evict() {
+	for len(b.incomplete) > b.config.MaxEvents || b.size > b.config.MaxSize {
+		buffered := b.oldest()
+		if buffered == nil {
+			break
+		}
+		b.drop(buffered, fmt.Errorf("%w: limits of %d events and %d bytes exceeded", ErrEventEvicted, b.config.MaxEvents, b.config.MaxSize))
+	}
+	b.compact()
+}
+
+// expire drops the events which have been waiting for longer than MaxAge.
+func (b *EventBuffer) expire() {
+	if b.config.MaxAge == 0 {
+		return
+	}
+	deadline := b.now().Add(-b.config.MaxAge)
+	for {
+		buffered := b.oldest()
+		if buffered == nil || !buffered.added.Before(deadline) {
+			break
+		}
+		b.drop(buffered, fmt.Errorf("%w: waited for parents since %s", ErrEventExpired, buffered.added))
+	}
+	b.compact()
+}
+
+// oldest returns the earliest added event which is still buffered.
+func (b *EventBuffer) oldest() *bufferedEvent {
+	for len(b.queue) != 0 {
+		if buffered, ok := b.incomplete[b.queue[0]]; ok {
+			return buffered
+		}
+		b.queue = b.queue[1:]
+	}
+	return nil
+}
+
+// compact releases the queue entries of events which have left the buffer.
+func (b *EventBuffer) compact() {
+	if len(b.queue) <= 2*len(b.incomplete)+16 {
+		return
+	}
+	queue := make(consensus.EventHashes, 0, len(b.incomplete))
+	for _, id := range b.queue {
+		if b.IsBuffered(id) {
+			queue = append(queue, id)
+		}
+	}
+	b.queue = queue
+}
+
+// drop removes the event and the events waiting for it, reporting them.
+func (b *EventBuffer) drop(buffered *bufferedEvent, err error) {
+	b.unlink(buffered)
+	b.report(buffered.event, err)
+	id := buffered.event.ID()
+	waiting := b.children[id]
+	delete(b.children, id)
+	b.dropDescendants(waiting, id, fmt.Errorf("dropped: %w", err))
+}
+
+// unlink removes the event from the buffer and from the parents it is still waiting for.
+func (b *EventBuffer) unlink(buffered *bufferedEvent) {
+	b.remove(buffered)
+	id := buffered.event.ID()
+	for _, p := range uniqueHashes(buffered.event.Parents()) {
+		waiting, ok := b.children[p]
+		if !ok {
+			continue
+		}
+		for i, child := range waiting {
+			if child == id {
+				waiting = append(waiting[:i], waiting[i+1:]...)
+				break
+			}
+		}
+		if len(waiting) == 0 {
+			delete(b.children, p)
+		} else {
+			b.children[p] = waiting
+		}
+	}
+}
+
+func (b *EventBuffer) remove(buffered *bufferedEvent) {
+	delete(b.incomplete, buffered.event.ID())
+	b.size -= uint64(buffered.event.Size())
+}
+
+func (b *EventBuffer) report(e consensus.Event, err error) {
+	b.remember(e.ID())
+	if b.callbacks.Dropped != nil {
+		b.callbacks.Dropped(e, err)
+	}
+}
+
+// remember adds the dropped event to the bounded set of dropped events, forgetting the earliest ones.
+func (b *EventBuffer) remember(id consensus.EventHash) {
+	b.droppedSeq++
+	b.dropped[id] = b.droppedSeq
+	b.droppedQueue = append(b.droppedQueue, droppedEvent{id, b.droppedSeq})
+	for len(b.dropped) > b.config.MaxDropped || len(b.droppedQueue) > 2*b.config.MaxDropped {
+		oldest := b.droppedQueue[0]
+		b.droppedQueue = b.droppedQueue[1:]
+		// the entry is stale if the event was forgotten or dropped again since
+		if seq, ok := b.dropped[oldest.id]; ok && seq == oldest.seq {
+			delete(b.dropped, oldest.id)
+		}
+	}
+}
+
+func uniqueHashes(hashes consensus.EventHashes) consensus.EventHashes {
+	unique := make(consensus.EventHashes, 0, len(hashes))
+	seen := make(map[consensus.EventHash]struct{}, len(hashes))
+	for _, h := range hashes {
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+		unique = append(unique, h)
+	}
+	return unique
+}
@@ -0,0 +1,336 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+func TestEventBuffer_AnyOrderReachesSameConsensus(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	ordered, _, orderedInput, _ := NewBootstrappedCoreConsensus(nodes, nil)
+	buffered, _, input, _ := NewBootstrappedCoreConsensus(nodes, nil)
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 100, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			orderedInput.SetEvent(e)
+			assertar.NoError(ordered.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return ordered.Build(e)
+		},
+	})
+
+	var dropped consensus.Events
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			for _, p := range e.Parents() {
+				assertar.True(input.HasEvent(p), "parent isn't processed before its child")
+			}
+			input.SetEvent(e)
+			return buffered.Process(e)
+		},
+		Dropped: func(e consensus.Event, err error) {
+			dropped = append(dropped, e)
+		},
+	}, DefaultEventBufferConfig())
+
+	for _, i := range rand.Perm(len(events)) {
+		buffer.Push(events[i])
+		buffer.Push(events[i]) // duplicates are ignored
+	}
+
+	assertar.Empty(dropped)
+	assertar.Zero(buffer.Len())
+	assertar.Zero(buffer.Size())
+	for _, e := range events {
+		assertar.True(input.HasEvent(e.ID()))
+	}
+	compareResults(t, []*CoreLachesis{ordered, buffered})
+}
+
+// testBufferChain returns a chain of events by a single validator, self-parents first.
+func testBufferChain(length int) consensus.Events {
+	chain := make(consensus.Events, length)
+	for i := range chain {
+		e := &consensustest.TestEvent{}
+		e.SetSeq(consensus.Seq(i + 1))
+		e.SetLamport(consensus.Lamport(i + 1))
+		if i > 0 {
+			e.SetParents(consensus.EventHashes{chain[i-1].ID()})
+		}
+		e.SetID([24]byte{byte(i + 1)})
+		chain[i] = e
+	}
+	return chain
+}
+
+func TestEventBuffer_OrphansOfRejectedEvent(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(4)
+	errRejected := errors.New("rejected")
+
+	dropped := map[consensus.EventHash]error{}
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			if e.ID() == chain[1].ID() {
+				return errRejected
+			}
+			input.SetEvent(e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			dropped[e.ID()] = err
+		},
+	}, DefaultEventBufferConfig())
+
+	buffer.Push(chain[3])
+	buffer.Push(chain[2])
+	buffer.Push(chain[1])
+	assertar.Equal(3, buffer.Len())
+	assertar.Equal(consensus.EventHashes{chain[0].ID()}, buffer.MissingParents())
+
+	buffer.Push(chain[0])
+	assertar.True(input.HasEvent(chain[0].ID()))
+	assertar.Zero(buffer.Len())
+	assertar.Len(dropped, 3)
+	assertar.ErrorIs(dropped[chain[1].ID()], errRejected)
+	assertar.ErrorIs(dropped[chain[2].ID()], ErrOrphanEvent)
+	assertar.ErrorIs(dropped[chain[3].ID()], ErrOrphanEvent)
+	assertar.ErrorIs(dropped[chain[3].ID()], errRejected)
+}
+
+func TestEventBuffer_Limits(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(5)
+
+	dropped := map[consensus.EventHash]error{}
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			input.SetEvent(e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			dropped[e.ID()] = err
+		},
+	}, EventBufferConfig{
+		MaxEvents: 2,
+		MaxSize:   uint64(chain[1].Size() * 3),
+		MaxAge:    time.Minute,
+	})
+	now := time.Unix(0, 0)
+	buffer.now = func() time.Time { return now }
+
+	// the oldest event is evicted once the count limit is exceeded
+	buffer.Push(chain[4])
+	buffer.Push(chain[3])
+	buffer.Push(chain[2])
+	assertar.Equal(2, buffer.Len())
+	assertar.ErrorIs(dropped[chain[4].ID()], ErrEventEvicted)
+	assertar.Equal(uint64(chain[2].Size()+chain[3].Size()), buffer.Size())
+
+	// events waiting for too long expire
+	now = now.Add(2 * time.Minute)
+	buffer.Push(chain[1])
+	assertar.Equal(1, buffer.Len())
+	assertar.True(buffer.IsBuffered(chain[1].ID()))
+	assertar.ErrorIs(dropped[chain[2].ID()], ErrEventExpired)
+	assertar.ErrorIs(dropped[chain[3].ID()], ErrEventExpired)
+
+	// an evicted event may be pushed again
+	buffer.Push(chain[0])
+	buffer.Push(chain[2])
+	assertar.Zero(buffer.Len())
+	for _, e := range chain[:3] {
+		assertar.True(input.HasEvent(e.ID()))
+	}
+
+	buffer.Push(chain[4])
+	buffer.Clear()
+	assertar.Zero(buffer.Len())
+	assertar.Zero(buffer.Size())
+	assertar.Empty(buffer.MissingParents())
+}
+
+func TestEventBuffer_DropsDependents(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(5)
+
+	dropped := map[consensus.EventHash]error{}
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			input.SetEvent(e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			dropped[e.ID()] = err
+		},
+	}, EventBufferConfig{
+		MaxEvents: 2,
+		MaxSize:   uint64(chain[1].Size() * 3),
+		MaxAge:    time.Minute,
+	})
+	now := time.Unix(0, 0)
+	buffer.now = func() time.Time { return now }
+
+	// the events waiting for an evicted event are dropped with it
+	buffer.Push(chain[1])
+	buffer.Push(chain[2])
+	buffer.Push(chain[4])
+	assertar.Equal(1, buffer.Len())
+	assertar.ErrorIs(dropped[chain[1].ID()], ErrEventEvicted)
+	assertar.ErrorIs(dropped[chain[2].ID()], ErrOrphanEvent)
+	assertar.ErrorIs(dropped[chain[2].ID()], ErrEventEvicted)
+	assertar.Equal(consensus.EventHashes{chain[3].ID()}, buffer.MissingParents())
+
+	// the events waiting for an expired event are dropped with it
+	buffer.Clear()
+	buffer.Push(chain[3])
+	buffer.Push(chain[4])
+	now = now.Add(2 * time.Minute)
+	buffer.Push(chain[1])
+	assertar.Equal(1, buffer.Len())
+	assertar.ErrorIs(dropped[chain[3].ID()], ErrEventExpired)
+	assertar.ErrorIs(dropped[chain[4].ID()], ErrOrphanEvent)
+	assertar.ErrorIs(dropped[chain[4].ID()], ErrEventExpired)
+	assertar.Equal(consensus.EventHashes{chain[0].ID()}, buffer.MissingParents())
+}
+
+func TestEventBuffer_Known(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(4)
+
+	var processed consensus.Events
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			input.SetEvent(e)
+			processed = append(processed, e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			t.Errorf("unexpected drop of %s: %v", e.ID(), err)
+		},
+	}, DefaultEventBufferConfig())
+
+	buffer.Push(chain[3])
+	buffer.Push(chain[1])
+	assertar.Equal(2, buffer.Len())
+
+	// chain[1] is processed bypassing the buffer, so it's unbuffered without being processed again
+	input.SetEvent(chain[0])
+	input.SetEvent(chain[1])
+	buffer.Known(chain[1].ID())
+	assertar.Equal(1, buffer.Len())
+	assertar.False(buffer.IsBuffered(chain[1].ID()))
+	assertar.Empty(processed)
+
+	// the children waiting for the known parent are released
+	input.SetEvent(chain[2])
+	buffer.Known(chain[2].ID())
+	assertar.Zero(buffer.Len())
+	assertar.Zero(buffer.Size())
+	assertar.Equal(consensus.Events{chain[3]}, processed)
+}
+
+func TestEventBuffer_LateOrphans(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(7)
+	errRejected := errors.New("rejected")
+
+	dropped := map[consensus.EventHash]error{}
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			if e.ID() == chain[1].ID() {
+				return errRejected
+			}
+			input.SetEvent(e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			dropped[e.ID()] = err
+		},
+	}, EventBufferConfig{
+		MaxEvents:  1,
+		MaxDropped: 2,
+	})
+
+	// the children arriving after the parent was rejected are dropped at once
+	buffer.Push(chain[0])
+	buffer.Push(chain[1])
+	assertar.ErrorIs(dropped[chain[1].ID()], errRejected)
+	buffer.Push(chain[2])
+	buffer.Push(chain[3])
+	assertar.Zero(buffer.Len())
+	assertar.ErrorIs(dropped[chain[2].ID()], ErrOrphanEvent)
+	assertar.ErrorIs(dropped[chain[3].ID()], ErrOrphanEvent)
+
+	// the children arriving after the parent was evicted are dropped at once
+	buffer.Push(chain[5])
+	buffer.Push(chain[6])
+	assertar.ErrorIs(dropped[chain[5].ID()], ErrEventEvicted)
+	delete(dropped, chain[6].ID())
+	buffer.Push(chain[6])
+	assertar.ErrorIs(dropped[chain[6].ID()], ErrOrphanEvent)
+	assertar.Zero(buffer.Len())
+
+	// only MaxDropped events are remembered, the earliest ones are forgotten
+	buffer.Push(chain[4])
+	assertar.True(buffer.IsBuffered(chain[4].ID()))
+	assertar.NotContains(dropped, chain[4].ID())
+}
+
+func TestEventBuffer_ZeroConfig(t *testing.T) {
+	assertar := assert.New(t)
+
+	input := consensustest.NewTestEventSource()
+	chain := testBufferChain(3)
+
+	buffer := NewEventBuffer(input, EventBufferCallbacks{
+		Process: func(e consensus.Event) error {
+			input.SetEvent(e)
+			return nil
+		},
+		Dropped: func(e consensus.Event, err error) {
+			t.Errorf("unexpected drop of %s: %v", e.ID(), err)
+		},
+	}, EventBufferConfig{})
+
+	// the zero limits are replaced by the defaults, so nothing is evicted
+	buffer.Push(chain[2])
+	buffer.Push(chain[1])
+	assertar.Equal(2, buffer.Len())
+	buffer.Push(chain[0])
+	assertar.Zero(buffer.Len())
+}
@@ -11,27 +11,38 @@
 package consensusengine
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/0xsoniclabs/consensus/consensus"
 )
 
 var (
-	ErrWrongFrame = errors.New("claimed frame mismatched with calculated")
+	ErrWrongFrame       = errors.New("claimed frame mismatched with calculated")
+	ErrWrongEpoch       = errors.New("event has wrong epoch")
+	ErrUnknownValidator = errors.New("event wasn't created by an existing validator")
 )
 
 // Build fills consensus-related fields: Frame, IsRoot
 // returns error if event should be dropped
 func (p *Orderer) Build(e consensus.MutableEvent) error {
+	if err := p.loadStates(); err != nil {
+		return err
+	}
 	// sanity check
 	if e.Epoch() != p.store.GetEpoch() {
-		p.crit(errors.New("event has wrong epoch"))
+		return ErrWrongEpoch
 	}
 	if !p.store.GetValidators().Exists(e.Creator()) {
-		p.crit(errors.New("event wasn't created by an existing validator"))
+		return ErrUnknownValidator
 	}
 
-	_, frame := p.calcFrameIdx(e)
+	_, frame, err := p.calcFrameIdx(e, p.store.GetFrameRoots)
+	if err != nil {
+		return err
+	}
 	e.SetFrame(frame)
 
 	return nil
@@ -42,36 +53,85 @@ func (p *Orderer) Build(e consensus.MutableEvent) error {
 // All the event checkers must be launched.
 // Process is not safe for concurrent use.
 func (p *Orderer) Process(e consensus.Event) (err error) {
+	if err := p.loadStates(); err != nil {
+		return err
+	}
+	if err := p.replayRewound(); err != nil {
+		return err
+	}
 	selfParentFrame, err := p.checkAndSaveEvent(e)
 	if err != nil {
 		return err
 	}
 
-	if selfParentFrame == e.Frame() {
-		return nil
+	_, err = p.processRoot(e, selfParentFrame)
+	return err
+}
+
+// checkEvent checks consensus-related fields: Frame, IsRoot
+func (p *Orderer) checkEvent(e consensus.Event, getFrameRoots GetFrameRootsFn) (selfParentFrame, frame consensus.Frame, err error) {
+	selfParentFrame, frame, err = p.calcFrameIdx(e, getFrameRoots)
+	if err != nil {
+		return 0, 0, err
 	}
-	if _, err := p.runElectionOnRoot(e.Frame(), e.Creator(), e.ID()); err != nil {
-		// election doesn't fail under normal circumstances
-		// storage is in an inconsistent state
-		p.crit(err)
+	if !p.config.SuppressFramePanic && e.Frame() != frame {
+		return 0, 0, ErrWrongFrame
 	}
-	return err
+	return selfParentFrame, frame, nil
 }
 
 // checkAndSaveEvent checks consensus-related fields: Frame, IsRoot
 func (p *Orderer) checkAndSaveEvent(e consensus.Event) (consensus.Frame, error) {
-	// check frame & isRoot
-	selfParentFrame, frameIdx := p.calcFrameIdx(e)
-	if !p.config.SuppressFramePanic && e.Frame() != frameIdx {
-		return 0, ErrWrongFrame
+	selfParentFrame, frameIdx, err := p.checkEvent(e, p.store.GetFrameRoots)
+	if err != nil {
+		return 0, err
 	}
 
 	if selfParentFrame != frameIdx {
-		p.store.AddRoot(e)
+		if err := p.saveRoot(e); err != nil {
+			return 0, err
+		}
 	}
 	return selfParentFrame, nil
 }
 
+// saveRoot saves the root and notifies the subscribers
+func (p *Orderer) saveRoot(e consensus.Event) error {
+	if err := p.store.AddRoot(e); err != nil {
+		return err
+	}
+	p.feed.Send(RootAdded{
+		Epoch:     p.store.GetEpoch(),
+		Frame:     e.Frame(),
+		Validator: e.Creator(),
+		Root:      e.ID(),
+	})
+	return nil
+}
+
+// processRoot runs Atropos election if the event is a root, returns true if epoch was sealed
+func (p *Orderer) processRoot(e consensus.Event, selfParentFrame consensus.Frame) (bool, error) {
+	p.metrics.events.Inc(1)
+	if selfParentFrame == e.Frame() {
+		return false, nil
+	}
+	p.metrics.roots.Inc(1)
+	frameToDeliver := p.election.frameToDeliver
+	sealed, err := p.runElectionOnRoot(e.Frame(), e.Creator(), e.ID())
+	if err != nil {
+		// election doesn't fail under normal circumstances
+		// storage is in an inconsistent state
+		return false, p.critical(err)
+	}
+	if !sealed && p.election.frameToDeliver != frameToDeliver && p.snapshotDue() {
+		// decided frame is a safe point to snapshot the election
+		if err := p.saveElectionState(); err != nil {
+			return false, err
+		}
+	}
+	return sealed, nil
+}
+
 // runElectionOnRoot runs Atropos election for the root and triggers block closure callbacks if election was decided
 func (p *Orderer) runElectionOnRoot(frame consensus.Frame, validatorID consensus.ValidatorID, rootHash consensus.EventHash) (bool, error) {
 	decisions, err := p.election.VoteAndAggregate(frame, validatorID, rootHash)
@@ -79,6 +139,7 @@ func (p *Orderer) runElectionOnRoot(frame consensus.Frame, validatorID consensus
 		return false, err
 	}
 	for _, atroposDecision := range decisions {
+		p.metrics.decisionLag.Observe(float64(frame - atroposDecision.Frame))
 		sealed, err := p.onFrameDecided(atroposDecision.Frame, atroposDecision.AtroposHash)
 		if err != nil {
 			return false, err
@@ -90,13 +151,27 @@ func (p *Orderer) runElectionOnRoot(frame consensus.Frame, validatorID consensus
 	return false, nil
 }
 
+// bootstrapElection restores the election state from the snapshot, and replays the roots which aren't covered by it.
+// If the snapshot is missing or stale, all the undecided roots are replayed.
 func (p *Orderer) bootstrapElection() error {
+	covered, err := p.restoreElectionState()
+	if err != nil {
+		return err
+	}
+	replayed := false
 	for frame := p.store.GetLastDecidedFrame() + 1; ; frame++ {
-		frameRoots := p.store.GetFrameRoots(frame)
+		frameRoots, err := p.store.GetFrameRoots(frame)
+		if err != nil {
+			return err
+		}
 		if len(frameRoots) == 0 {
 			break
 		}
 		for _, root := range frameRoots {
+			if covered.Contains(root.RootHash) {
+				continue
+			}
+			replayed = true
 			sealed, err := p.runElectionOnRoot(frame, root.ValidatorID, root.RootHash)
 			if err != nil {
 				return err
@@ -106,44 +181,161 @@ func (p *Orderer) bootstrapElection() error {
 			}
 		}
 	}
+	if replayed {
+		return p.saveElectionState()
+	}
 	return nil
 }
 
-// forklessCausedByQuorumOn returns true if event is forkless caused by 2/3W roots on specified frame
-func (p *Orderer) forklessCausedByQuorumOn(e consensus.Event, f consensus.Frame) bool {
-	observedCounter := p.store.GetValidators().NewCounter()
-	// check "observing" prev roots only if called by creator, or if creator has marked that event as root
-	for _, it := range p.store.GetFrameRoots(f) {
-		if p.dagIndex.ForklessCause(e.ID(), it.RootHash) {
-			observedCounter.CountVoteByID(it.ValidatorID)
+// restoreElectionState loads the election snapshot, returns the roots covered by it.
+// Returns an empty set if the snapshot is missing or stale.
+func (p *Orderer) restoreElectionState() (consensus.EventHashSet, error) {
+	state, err := p.store.GetElectionState()
+	if err != nil || state == nil {
+		return consensus.EventHashSet{}, err
+	}
+	if state.FrameToDeliver != p.store.GetLastDecidedFrame()+1 {
+		return consensus.EventHashSet{}, nil
+	}
+	covered := consensus.EventHashes(state.Roots).Set()
+	// every covered root must be still stored
+	stored := 0
+	for frame := state.FrameToDeliver; ; frame++ {
+		frameRoots, err := p.store.GetFrameRoots(frame)
+		if err != nil {
+			return nil, err
+		}
+		if len(frameRoots) == 0 {
+			break
 		}
-		if observedCounter.HasQuorum() {
+		for _, root := range frameRoots {
+			if covered.Contains(root.RootHash) {
+				stored++
+			}
+		}
+	}
+	if stored != len(covered) || !p.election.restore(state) {
+		return consensus.EventHashSet{}, nil
+	}
+	p.snapshotFrame = state.FrameToDeliver
+	return covered, nil
+}
+
+// snapshotDue returns true if ElectionSnapshotFrames frames are decided since the last election snapshot.
+func (p *Orderer) snapshotDue() bool {
+	interval := p.config.ElectionSnapshotFrames
+	// the frames start over in a new epoch
+	return interval != 0 && (p.election.frameToDeliver < p.snapshotFrame || p.election.frameToDeliver-p.snapshotFrame >= interval)
+}
+
+// SaveElectionState snapshots the election, so it's resumed without replaying the undecided roots on Bootstrap,
+// e.g. before a shutdown. See Config.ElectionSnapshotFrames.
+// SaveElectionState is not safe for concurrent use.
+func (p *Orderer) SaveElectionState() error {
+	if p.election == nil {
+		return ErrNotBootstrapped
+	}
+	if err := p.replayRewound(); err != nil {
+		return err
+	}
+	return p.saveElectionState()
+}
+
+// saveElectionState snapshots the election state, which covers all the stored roots since the frame to deliver.
+func (p *Orderer) saveElectionState() error {
+	roots := consensus.EventHashes{}
+	for frame := p.election.frameToDeliver; ; frame++ {
+		frameRoots, err := p.store.GetFrameRoots(frame)
+		if err != nil {
+			return err
+		}
+		if len(frameRoots) == 0 {
 			break
 		}
+		for _, root := range frameRoots {
+			roots = append(roots, root.RootHash)
+		}
+	}
+	if err := p.store.SetElectionState(p.election.snapshot(roots)); err != nil {
+		return err
 	}
-	return observedCounter.HasQuorum()
+	p.snapshotFrame = p.election.frameToDeliver
+	return nil
+}
+
+// forklessCausedByQuorumOn returns true if event is forkless caused by 2/3W roots on specified frame
+func (p *Orderer) forklessCausedByQuorumOn(e consensus.Event, f consensus.Frame, getFrameRoots GetFrameRootsFn) (bool, error) {
+	frameRoots, err := getFrameRoots(f)
+	if err != nil {
+		return false, err
+	}
+	roots := make(consensus.EventHashes, len(frameRoots))
+	for i, it := range frameRoots {
+		roots[i] = it.RootHash
+	}
+	// check "observing" prev roots only if called by creator, or if creator has marked that event as root
+	observed, err := p.dagIndex.ForklessCauseRoots(e.ID(), roots)
+	if err != nil {
+		return false, err
+	}
+	return observed.Weight.HasQuorum(), nil
 }
 
 // calcFrameIdx is not safe for concurrent use.
-func (p *Orderer) calcFrameIdx(e consensus.Event) (selfParentFrame, frame consensus.Frame) {
+func (p *Orderer) calcFrameIdx(e consensus.Event, getFrameRoots GetFrameRootsFn) (selfParentFrame, frame consensus.Frame, err error) {
+	defer func(start time.Time) {
+		p.metrics.calcFrameIdx.Observe(time.Since(start).Seconds())
+	}(time.Now())
 	if e.SelfParent() == nil {
-		return 0, 1
+		return 0, 1, nil
+	}
+	selfParentFrame, err = p.getSelfParentFrame(e)
+	if err != nil {
+		return 0, 0, err
 	}
-	selfParentFrame = p.Input.GetEvent(*e.SelfParent()).Frame()
 	frame = selfParentFrame
 	for _, parent := range e.Parents() {
-		frame = max(frame, p.Input.GetEvent(parent).Frame())
+		parentEvent, err := p.getEvent(parent)
+		if err != nil {
+			return 0, 0, err
+		}
+		frame = max(frame, parentEvent.Frame())
+	}
+	pruned, err := p.store.GetPrunedFrame()
+	if err != nil {
+		return 0, 0, err
+	}
+	// a node without pruning accepts the event, so the lag of events must be bounded, see PruningConfig.KeepFrames
+	if frame <= pruned {
+		return 0, 0, fmt.Errorf("%w: roots of frame %d", consensus.ErrPruned, frame)
 	}
 
-	if p.forklessCausedByQuorumOn(e, frame) {
+	quorum, err := p.forklessCausedByQuorumOn(e, frame, getFrameRoots)
+	if err != nil {
+		return 0, 0, err
+	}
+	if quorum {
 		frame++
 	}
-	return selfParentFrame, frame
+	return selfParentFrame, frame, nil
 }
 
-func (p *Orderer) getSelfParentFrame(e consensus.Event) consensus.Frame {
+func (p *Orderer) getSelfParentFrame(e consensus.Event) (consensus.Frame, error) {
 	if e.SelfParent() == nil {
-		return 0
+		return 0, nil
+	}
+	selfParent, err := p.getEvent(*e.SelfParent())
+	if err != nil {
+		return 0, err
+	}
+	return selfParent.Frame(), nil
+}
+
+// getEvent reads the event from the input, which is expected to contain it.
+func (p *Orderer) getEvent(id consensus.EventHash) (consensus.Event, error) {
+	event := p.Input.GetEvent(id)
+	if event == nil {
+		return nil, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, id.String())
 	}
-	return p.Input.GetEvent(*e.SelfParent()).Frame()
+	return event, nil
 }
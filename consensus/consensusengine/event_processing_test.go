@@ -127,7 +127,7 @@ func testLachesisRandomAndReset(t *testing.T, weights []consensus.Weight, mutate
 				inputs[0].SetEvent(e)
 				assertar.NoError(
 					lchs[0].Process(e))
-				epochStates[lchs[0].store.GetEpoch()] = lchs[0].store.GetEpochState()
+				epochStates[lchs[0].store.GetEpoch()] = &consensusstore.EpochState{Epoch: lchs[0].store.GetEpoch(), Validators: lchs[0].store.GetValidators()}
 			},
 			Build: func(e consensus.MutableEvent, name string) error {
 				if epoch != lchs[0].store.GetEpoch() {
@@ -192,8 +192,9 @@ func compareResults(t *testing.T, lchs []*CoreLachesis) {
 		for j := i + 1; j < len(lchs); j++ {
 			lch1 := lchs[j]
 
-			assertar.Equal(*(lchs[j].store.GetLastDecidedState()), *(lchs[i].store.GetLastDecidedState()))
-			assertar.Equal(*(lchs[j].store.GetEpochState()), *(lchs[i].store.GetEpochState()))
+			assertar.Equal(lchs[j].store.GetLastDecidedFrame(), lchs[i].store.GetLastDecidedFrame())
+			assertar.Equal(lchs[j].store.GetEpoch(), lchs[i].store.GetEpoch())
+			assertar.Equal(lchs[j].store.GetValidators(), lchs[i].store.GetValidators())
 
 			for e := consensus.Epoch(1); e <= lch0.store.GetEpoch(); e++ {
 				both := lch0.epochBlocks[e]
@@ -213,3 +214,42 @@ func compareResults(t *testing.T, lchs []*CoreLachesis) {
 		}
 	}
 }
+
+func TestLachesis_ErrorsArePropagated(t *testing.T) {
+	nodes := consensustest.GenNodes(2)
+	lch, _, eventSource, _ := NewBootstrappedCoreConsensus(nodes, nil)
+
+	wrongEpoch := &consensustest.TestEvent{}
+	wrongEpoch.SetEpoch(lch.store.GetEpoch() + 1)
+	wrongEpoch.SetCreator(nodes[0])
+	if err := lch.Build(wrongEpoch); !errors.Is(err, ErrWrongEpoch) {
+		t.Fatalf("expected wrong epoch error, got: %v", err)
+	}
+
+	unknownCreator := &consensustest.TestEvent{}
+	unknownCreator.SetEpoch(lch.store.GetEpoch())
+	unknownCreator.SetCreator(consensustest.FakePeer())
+	if err := lch.Build(unknownCreator); !errors.Is(err, ErrUnknownValidator) {
+		t.Fatalf("expected unknown validator error, got: %v", err)
+	}
+
+	// the self-parent is never delivered to the event source
+	selfParent := &consensustest.TestEvent{}
+	selfParent.SetEpoch(lch.store.GetEpoch())
+	selfParent.SetCreator(nodes[0])
+	selfParent.SetSeq(1)
+	selfParent.SetLamport(1)
+	selfParent.SetID([24]byte{1})
+
+	e := &consensustest.TestEvent{}
+	e.SetEpoch(lch.store.GetEpoch())
+	e.SetCreator(nodes[0])
+	e.SetSeq(2)
+	e.SetLamport(2)
+	e.SetParents(consensus.EventHashes{selfParent.ID()})
+	e.SetID([24]byte{2})
+	eventSource.SetEvent(e)
+	if err := lch.Process(e); !errors.Is(err, consensus.ErrEventNotFound) {
+		t.Fatalf("expected event not found error, got: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// orderEvents sorts the confirmed events of a block in place.
+func orderEvents(events consensus.Events, ordering EventsOrdering) {
+	switch ordering {
+	case OrderingLamport:
+		slices.SortFunc(events, func(a, b consensus.Event) int {
+			if a.Lamport() != b.Lamport() {
+				return cmp.Compare(a.Lamport(), b.Lamport())
+			}
+			return compareEventIDs(a, b)
+		})
+	case OrderingTopological:
+		orderTopologically(events)
+	}
+}
+
+// orderTopologically sorts events after their parents, picking the lowest creator, then the lowest hash, among ready events.
+func orderTopologically(events consensus.Events) {
+	pending := make(map[consensus.EventHash]int, len(events))
+	for _, e := range events {
+		pending[e.ID()] = 0
+	}
+	children := make(map[consensus.EventHash]consensus.Events, len(events))
+	ready := &readyEvents{}
+	for _, e := range events {
+		for _, parent := range e.Parents() {
+			if _, ok := pending[parent]; ok {
+				pending[e.ID()]++
+				children[parent] = append(children[parent], e)
+			}
+		}
+		if pending[e.ID()] == 0 {
+			ready.container = append(ready.container, e)
+		}
+	}
+	heap.Init(ready)
+
+	for i := range events {
+		e := heap.Pop(ready).(consensus.Event)
+		events[i] = e
+		for _, child := range children[e.ID()] {
+			pending[child.ID()]--
+			if pending[child.ID()] == 0 {
+				heap.Push(ready, child)
+			}
+		}
+	}
+}
+
+// readyEvents is a min-heap of events ordered by creator, then by hash.
+type readyEvents struct {
+	container consensus.Events
+}
+
+func (h readyEvents) Len() int { return len(h.container) }
+func (h readyEvents) Less(i, j int) bool {
+	a, b := h.container[i], h.container[j]
+	if a.Creator() != b.Creator() {
+		return a.Creator() < b.Creator()
+	}
+	return compareEventIDs(a, b) < 0
+}
+func (h readyEvents) Swap(i, j int) { h.container[i], h.container[j] = h.container[j], h.container[i] }
+
+func (h *readyEvents) Push(x any) {
+	h.container = append(h.container, x.(consensus.Event))
+}
+
+func (h *readyEvents) Pop() any {
+	backIdx := len(h.container) - 1
+	toPop := h.container[backIdx]
+	h.container = h.container[0:backIdx]
+	return toPop
+}
+
+func compareEventIDs(a, b consensus.Event) int {
+	aID, bID := a.ID(), b.ID()
+	return bytes.Compare(aID[:], bID[:])
+}
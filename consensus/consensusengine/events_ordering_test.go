@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+func TestOrderEvents(t *testing.T) {
+	testEvent := func(id byte, creator consensus.ValidatorID, lamport consensus.Lamport, parents ...consensus.Event) consensus.Event {
+		e := &consensustest.TestEvent{}
+		e.SetCreator(creator)
+		e.SetLamport(lamport)
+		// parents outside of the block are ignored
+		e.SetParents(consensus.EventHashes{consensustest.FakeEventHash()})
+		for _, p := range parents {
+			e.AddParent(p.ID())
+		}
+		e.SetID([24]byte{id})
+		return e
+	}
+	c1 := testEvent(1, 3, 1)
+	a1 := testEvent(2, 1, 1)
+	b1 := testEvent(3, 2, 1)
+	a2 := testEvent(4, 1, 2, a1, c1)
+	b2 := testEvent(5, 2, 3, b1, a2)
+	block := consensus.Events{b2, a2, a1, c1, b1}
+
+	for ordering, expected := range map[EventsOrdering]consensus.Events{
+		OrderingTraversal:   block,
+		OrderingLamport:     {c1, a1, b1, a2, b2},
+		OrderingTopological: {a1, b1, c1, a2, b2},
+	} {
+		events := append(consensus.Events{}, block...)
+		orderEvents(events, ordering)
+		assert.Equal(t, expected.IDs(), events.IDs(), "ordering %d", ordering)
+	}
+}
+
+func TestLachesis_EventsOrdering(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	orderings := []EventsOrdering{OrderingTraversal, OrderingLamport, OrderingTopological}
+	lchs := make([]*CoreLachesis, 0, len(orderings))
+	inputs := make([]*consensustest.TestEventSource, 0, len(orderings))
+	blocks := make([][]consensus.Events, len(orderings))
+	for i, ordering := range orderings {
+		lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+		lch.config.EventsOrdering = ordering
+		lch.callback = consensus.ConsensusCallbacks{
+			BeginBlock: func(block *consensus.Block) consensus.BlockCallbacks {
+				var applied consensus.Events
+				return consensus.BlockCallbacks{
+					ApplyEvent: func(e consensus.Event) {
+						applied = append(applied, e)
+					},
+					EndBlock: func() *consensus.Validators {
+						assertar.Equal(block.Events, applied.IDs())
+						if ordering != OrderingTraversal {
+							assertar.Equal(block.Atropos, applied[len(applied)-1].ID(), "atropos must be the last event")
+						}
+						blocks[i] = append(blocks[i], applied)
+						return nil
+					},
+				}
+			},
+		}
+		lchs = append(lchs, lch)
+		inputs = append(inputs, input)
+	}
+
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], 500, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			for i, lch := range lchs {
+				inputs[i].SetEvent(e)
+				assertar.NoError(lch.Process(e))
+			}
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lchs[0].Build(e)
+		},
+	})
+	assertar.NotEmpty(blocks[0])
+
+	for i := range orderings[1:] {
+		assertar.Equal(len(blocks[0]), len(blocks[i+1]))
+	}
+	for b := range blocks[0] {
+		for i := range orderings[1:] {
+			assertar.ElementsMatch(blocks[0][b].IDs(), blocks[i+1][b].IDs())
+		}
+		lamport := blocks[1][b]
+		for j := 1; j < len(lamport); j++ {
+			assertar.LessOrEqual(lamport[j-1].Lamport(), lamport[j].Lamport())
+		}
+		position := map[consensus.EventHash]int{}
+		for j, e := range blocks[2][b] {
+			position[e.ID()] = j
+		}
+		for j, e := range blocks[2][b] {
+			for _, p := range e.Parents() {
+				if pos, ok := position[p]; ok {
+					assertar.Less(pos, j, "parent must precede its child")
+				}
+			}
+		}
+	}
+}
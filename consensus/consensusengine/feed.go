@@ -0,0 +1,141 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// Notification is a consensus progress notification, published by Feed.
+// It's one of RootAdded, AtroposDecided, CheatersDetected, EpochSealed, Rewound.
+type Notification interface {
+	notification()
+}
+
+// RootAdded is published when a root is saved.
+type RootAdded struct {
+	Epoch     consensus.Epoch
+	Frame     consensus.Frame
+	Validator consensus.ValidatorID
+	Root      consensus.EventHash
+}
+
+// AtroposDecided is published when a frame is decided and its block is applied.
+type AtroposDecided struct {
+	Epoch   consensus.Epoch
+	Frame   consensus.Frame
+	Atropos consensus.EventHash
+}
+
+// CheatersDetected is published by IndexedLachesis when the first fork of a validator in the epoch is indexed,
+// i.e. before the cheater is confirmed by a block. Other nodes may detect the fork at another event.
+type CheatersDetected struct {
+	Epoch    consensus.Epoch
+	Cheaters consensus.Cheaters
+}
+
+// EpochSealed is published when the epoch is sealed.
+type EpochSealed struct {
+	// Epoch is the new epoch
+	Epoch      consensus.Epoch
+	Validators *consensus.Validators
+}
+
+// Rewound is published when the decided frames are rewound, see Orderer.Rewind.
+type Rewound struct {
+	Epoch consensus.Epoch
+	// Frame is the new last decided frame
+	Frame consensus.Frame
+}
+
+func (RootAdded) notification()        {}
+func (AtroposDecided) notification()   {}
+func (CheatersDetected) notification() {}
+func (EpochSealed) notification()      {}
+func (Rewound) notification()          {}
+
+// Feed delivers notifications to subscribers without blocking the publisher.
+// Every subscriber has its own buffer, notifications which don't fit into it are dropped.
+// Safe for concurrent use.
+type Feed struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription receives notifications from Feed.
+type Subscription struct {
+	feed    *Feed
+	ch      chan Notification
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// NewFeed creates Feed instance.
+func NewFeed() *Feed {
+	return &Feed{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe creates a subscription, which buffers up to buffer notifications.
+func (f *Feed) Subscribe(buffer int) *Subscription {
+	s := &Subscription{
+		feed: f,
+		ch:   make(chan Notification, buffer),
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs[s] = struct{}{}
+	return s
+}
+
+// Send publishes the notification to all the subscribers.
+func (f *Feed) Send(n Notification) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for s := range f.subs {
+		select {
+		case s.ch <- n:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// HasSubscribers returns true if there is at least one subscriber.
+func (f *Feed) HasSubscribers() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subs) != 0
+}
+
+// C returns the channel of notifications, which is closed on Unsubscribe.
+func (s *Subscription) C() <-chan Notification {
+	return s.ch
+}
+
+// Dropped returns the number of notifications dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the delivery and closes the channel.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.feed.mu.Lock()
+		defer s.feed.mu.Unlock()
+		delete(s.feed.subs, s)
+		close(s.ch)
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+func TestFeed_NonBlockingDelivery(t *testing.T) {
+	assertar := assert.New(t)
+
+	feed := NewFeed()
+	assertar.False(feed.HasSubscribers())
+	slow := feed.Subscribe(1)
+	fast := feed.Subscribe(3)
+	assertar.True(feed.HasSubscribers())
+
+	for frame := consensus.Frame(1); frame <= 3; frame++ {
+		feed.Send(AtroposDecided{Frame: frame})
+	}
+	assertar.Equal(uint64(2), slow.Dropped())
+	assertar.Equal(uint64(0), fast.Dropped())
+	assertar.Equal(AtroposDecided{Frame: 1}, <-slow.C())
+	for frame := consensus.Frame(1); frame <= 3; frame++ {
+		assertar.Equal(AtroposDecided{Frame: frame}, <-fast.C())
+	}
+
+	slow.Unsubscribe()
+	slow.Unsubscribe()
+	_, ok := <-slow.C()
+	assertar.False(ok)
+	feed.Send(EpochSealed{Epoch: 2})
+	assertar.Equal(EpochSealed{Epoch: 2}, <-fast.C())
+
+	fast.Unsubscribe()
+	assertar.False(feed.HasSubscribers())
+}
+
+func TestFeed_ConsensusNotifications(t *testing.T) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		if lch.store.GetLastDecidedFrame()+1 == 10 {
+			return lch.store.GetValidators()
+		}
+		return nil
+	}
+	sub := lch.Subscribe(100000)
+	defer sub.Unsubscribe()
+
+	roots := 0
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				input.SetEvent(e)
+				selfParentFrame, err := lch.getSelfParentFrame(e)
+				assertar.NoError(err)
+				if selfParentFrame != e.Frame() {
+					roots++
+				}
+				assertar.NoError(lch.Process(e))
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lch.store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lch.Build(e)
+			},
+		})
+	}
+	assertar.Equal(consensus.Epoch(epochs+1), lch.store.GetEpoch())
+
+	var (
+		rootsAdded int
+		decided    []AtroposDecided
+		sealed     []EpochSealed
+		cheaters   = map[consensus.Epoch]consensus.Cheaters{}
+	)
+	for len(sub.C()) != 0 {
+		switch n := (<-sub.C()).(type) {
+		case RootAdded:
+			rootsAdded++
+		case AtroposDecided:
+			decided = append(decided, n)
+		case CheatersDetected:
+			cheaters[n.Epoch] = append(cheaters[n.Epoch], n.Cheaters...)
+		case EpochSealed:
+			sealed = append(sealed, n)
+		}
+	}
+	assertar.Zero(sub.Dropped())
+	assertar.Equal(roots, rootsAdded)
+	assertar.Len(decided, len(lch.blocks))
+	for _, n := range decided {
+		assertar.Equal(lch.blocks[BlockKey{n.Epoch, n.Frame}].Atropos, n.Atropos)
+	}
+	assertar.Len(sealed, epochs)
+	for i, n := range sealed {
+		assertar.Equal(consensus.Epoch(i+2), n.Epoch)
+	}
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		// the cheater is reported once per epoch
+		assertar.Equal(consensus.Cheaters{nodes[0]}, cheaters[epoch], "epoch %d", epoch)
+	}
+}
+
+func TestFeed_CheatersDetectedOnFork(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	generator, _, generatorInput, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	sub := lch.Subscribe(100000)
+	defer sub.Unsubscribe()
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assertar.NoError(generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+
+	// the cheater is published once its fork is indexed, before a block confirms it
+	detected := -1
+	for i, e := range events {
+		input.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+		for len(sub.C()) != 0 {
+			if n, ok := (<-sub.C()).(CheatersDetected); ok {
+				assertar.Equal(-1, detected, "the cheater is published twice")
+				assertar.Equal(CheatersDetected{Epoch: consensus.FirstEpoch, Cheaters: consensus.Cheaters{nodes[0]}}, n)
+				detected = i
+				for _, block := range lch.blocks {
+					assertar.Empty(block.Cheaters)
+				}
+			}
+		}
+		if detected != -1 && i > detected+len(events)/4 {
+			events = events[i+1:]
+			break
+		}
+	}
+	if !assertar.NotEqual(-1, detected) {
+		return
+	}
+
+	// the cheater isn't published again after a restart
+	restored := testSnapshotRestart(t, lch)
+	restoredSub := restored.Subscribe(100000)
+	defer restoredSub.Unsubscribe()
+	for _, e := range events {
+		input.SetEvent(e)
+		assertar.NoError(restored.Process(e))
+	}
+	for len(restoredSub.C()) != 0 {
+		_, ok := (<-restoredSub.C()).(CheatersDetected)
+		assertar.False(ok)
+	}
+	confirmed := false
+	for _, block := range restored.blocks {
+		confirmed = confirmed || len(block.Cheaters) != 0
+	}
+	assertar.True(confirmed)
+}
@@ -16,25 +16,44 @@ import (
 
 // onFrameDecided moves LastDecidedFrameN to frame.
 // It includes: moving current decided frame, txs ordering and execution, epoch sealing.
+// The writes are journaled, so the decision interrupted by a crash is rolled back or forward by Bootstrap.
 func (p *Orderer) onFrameDecided(frame consensus.Frame, atropos consensus.EventHash) (bool, error) {
+	epoch := p.store.GetEpoch()
+	if err := p.store.BeginTransition(frame, atropos); err != nil {
+		return false, err
+	}
 	// new checkpoint
 	var newValidators *consensus.Validators
 	if p.callback.ApplyAtropos != nil {
-		newValidators = p.callback.ApplyAtropos(frame, atropos)
+		var err error
+		newValidators, err = p.callback.ApplyAtropos(frame, atropos)
+		if err != nil {
+			return false, err
+		}
 	}
 
-	lastDecidedState := *p.store.GetLastDecidedState()
+	if err := p.store.CommitTransition(newValidators); err != nil {
+		return newValidators != nil, err
+	}
 	if newValidators != nil {
-		lastDecidedState.LastDecidedFrame = consensus.FirstFrame - 1
-		err := p.sealEpoch(newValidators)
-		if err != nil {
-			return true, err
+		if p.callback.EpochDBLoaded != nil {
+			p.callback.EpochDBLoaded(p.store.GetEpoch())
 		}
 		p.election.ResetEpoch(consensus.FirstFrame, newValidators)
-	} else {
-		lastDecidedState.LastDecidedFrame = frame
 	}
-	p.store.SetLastDecidedState(&lastDecidedState)
+
+	p.metrics.lastDecidedFrame.Set(int64(p.store.GetLastDecidedFrame()))
+	p.feed.Send(AtroposDecided{
+		Epoch:   epoch,
+		Frame:   frame,
+		Atropos: atropos,
+	})
+	if newValidators != nil {
+		p.feed.Send(EpochSealed{
+			Epoch:      p.store.GetEpoch(),
+			Validators: newValidators,
+		})
+	}
 	return newValidators != nil, nil
 }
 
@@ -53,13 +72,3 @@ func (p *Orderer) resetEpochStore(newEpoch consensus.Epoch) error {
 	}
 	return nil
 }
-
-func (p *Orderer) sealEpoch(newValidators *consensus.Validators) error {
-	// new PrevEpoch state
-	epochState := *p.store.GetEpochState()
-	epochState.Epoch++
-	epochState.Validators = newValidators
-	p.store.SetEpochState(&epochState)
-
-	return p.resetEpochStore(epochState.Epoch)
-}
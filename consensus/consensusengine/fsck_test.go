@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+func TestFsck(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	lch, store, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, TestMaxEpochEvents, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+			events = append(events, e)
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lch.Build(e)
+		},
+	})
+	if t.Failed() {
+		return
+	}
+	// the lite DAG index is written through to the epoch DB
+	cfg := consensusstore.FsckConfig{ForklessCauseSamples: 1000, IndexPersisted: true}
+
+	// consistent DBs
+	for _, getEvent := range []func(consensus.EventHash) consensus.Event{nil, input.GetEvent} {
+		cfg.GetEvent = getEvent
+		report, err := consensusstore.Fsck(store.MainDB, store.EpochDB, cfg)
+		if !assertar.NoError(err) {
+			return
+		}
+		assertar.True(report.OK(), report.Violations)
+		assertar.Empty(report.Skipped)
+		assertar.Empty(report.Unverifiable)
+		assertar.Equal(consensus.FirstEpoch, report.Epoch)
+		assertar.Equal(store.GetLastDecidedFrame(), report.LastDecidedFrame)
+		assertar.Equal(len(events), report.Checked[consensusstore.CheckEventBranches])
+		assertar.Equal(len(events), report.Checked[consensusstore.CheckVectors])
+		assertar.NotZero(report.Checked[consensusstore.CheckRoots])
+		assertar.NotZero(report.Checked[consensusstore.CheckConfirmedEvents])
+		assertar.NotZero(report.Checked[consensusstore.CheckForklessCause])
+	}
+	cfg.GetEvent = nil
+
+	// inconsistent records
+	lastFrame := store.GetLastDecidedFrame() + 1
+	first := events[0]
+	unknown := consensustest.FakeEventHash()
+	copy(unknown[:4], first.Epoch().Bytes())
+
+	assertar.NoError(store.EpochTable.ConfirmedEvent.Put(first.ID().Bytes(), lastFrame.Bytes()))
+	assertar.NoError(store.EpochTable.Roots.Put([]byte{1, 2, 3}, []byte{}))
+	unknownRootKey := append(append(lastFrame.Bytes(), first.Creator().Bytes()...), unknown.Bytes()...)
+	assertar.NoError(store.EpochTable.Roots.Put(unknownRootKey, []byte{}))
+	// an early event isn't forkless caused by the roots of the previous frame
+	earlyRootKey := append(append(lastFrame.Bytes(), first.Creator().Bytes()...), first.ID().Bytes()...)
+	assertar.NoError(store.EpochTable.Roots.Put(earlyRootKey, []byte{}))
+	assertar.NoError(table.New(store.EpochTable.VectorIndex, []byte("b")).Put(unknown.Bytes(), consensus.ValidatorIndex(100).Bytes()))
+
+	report, err := consensusstore.Fsck(store.MainDB, store.EpochDB, cfg)
+	if !assertar.NoError(err) {
+		return
+	}
+	violated := map[string]int{}
+	for _, v := range report.Violations {
+		violated[v.Check]++
+	}
+	assertar.Equal(map[string]int{
+		consensusstore.CheckConfirmedEvents: 1,
+		consensusstore.CheckRoots:           2,
+		consensusstore.CheckEventBranches:   1,
+		consensusstore.CheckVectors:         1,
+		consensusstore.CheckForklessCause:   1,
+	}, violated, report.Violations)
+
+	// the records missing the vectors are unverifiable if the index may be held by its cache
+	cfg.IndexPersisted = false
+	report, err = consensusstore.Fsck(store.MainDB, store.EpochDB, cfg)
+	if !assertar.NoError(err) {
+		return
+	}
+	violated = map[string]int{}
+	for _, v := range report.Violations {
+		violated[v.Check]++
+	}
+	assertar.Equal(1, violated[consensusstore.CheckRoots], report.Violations)
+	assertar.Zero(violated[consensusstore.CheckVectors], report.Violations)
+	assertar.Equal(map[string]int{
+		consensusstore.CheckRoots:   1,
+		consensusstore.CheckVectors: 1,
+	}, report.Unverifiable)
+	cfg.IndexPersisted = true
+
+	// the confirmations of an interrupted decision are rolled back by bootstrap
+	assertar.NoError(store.BeginTransition(lastFrame, first.ID()))
+	report, err = consensusstore.Fsck(store.MainDB, store.EpochDB, cfg)
+	if !assertar.NoError(err) {
+		return
+	}
+	assertar.Equal(1, report.Checked[consensusstore.CheckTransition])
+	for _, v := range report.Violations {
+		assertar.NotEqual(consensusstore.CheckConfirmedEvents, v.Check)
+	}
+
+	// undecodable state
+	assertar.NoError(table.New(store.MainDB, []byte("c")).Put([]byte("d"), []byte{0xff}))
+	report, err = consensusstore.Fsck(store.MainDB, store.EpochDB, cfg)
+	if !assertar.NoError(err) {
+		return
+	}
+	if assertar.False(report.OK()) {
+		assertar.Equal(consensusstore.CheckLastDecidedState, report.Violations[0].Check)
+	}
+}
@@ -11,6 +11,8 @@
 package consensusengine
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -22,6 +24,9 @@ import (
 
 var _ consensus.Consensus = (*IndexedLachesis)(nil)
 
+// ErrEpochSealed is returned by ProcessBatch if the epoch is sealed before the whole batch is processed.
+var ErrEpochSealed = errors.New("epoch sealed by the batch")
+
 // IndexedLachesis performs events ordering and detects cheaters
 // It's a wrapper around Orderer, which adds features which might potentially be application-specific:
 // confirmed events traversal, DAG index updates and cheaters detection.
@@ -39,6 +44,8 @@ func NewIndexedLachesis(store *consensusstore.Store, input EventSource, dagIndex
 		DagIndexer:    dagIndexer,
 		uniqueDirtyID: uniqueID{new(big.Int)},
 	}
+	// the vectors cached by the index are written to the epoch DB before it's archived or exported
+	store.SetIndexPersister(dagIndexer.Persist)
 
 	return p
 }
@@ -46,6 +53,9 @@ func NewIndexedLachesis(store *consensusstore.Store, input EventSource, dagIndex
 // Build fills consensus-related fields: Frame, IsRoot
 // returns error if event should be dropped
 func (p *IndexedLachesis) Build(e consensus.MutableEvent) error {
+	if err := p.loadStates(); err != nil {
+		return err
+	}
 	e.SetID(p.uniqueDirtyID.sample())
 
 	defer p.DagIndexer.DropNotFlushed()
@@ -62,24 +72,168 @@ func (p *IndexedLachesis) Build(e consensus.MutableEvent) error {
 // All the event checkers must be launched.
 // Process is not safe for concurrent use.
 func (p *IndexedLachesis) Process(e consensus.Event) (err error) {
+	if err := p.loadStates(); err != nil {
+		return err
+	}
+	if err := p.replayRewound(); err != nil {
+		return err
+	}
 	defer p.DagIndexer.DropNotFlushed()
+	branches, err := p.branchesNum()
+	if err != nil {
+		return err
+	}
 	err = p.DagIndexer.Add(e)
 	if err != nil {
 		return err
 	}
 
-	err = p.Lachesis.Process(e)
+	selfParentFrame, frame, err := p.checkEvent(e, p.store.GetFrameRoots)
 	if err != nil {
 		return err
 	}
-	p.DagIndexer.Flush()
-	return nil
+	// the event is indexed before it's saved as a root, so Bootstrap can replay the root after a crash
+	if err := p.DagIndexer.Flush(); err != nil {
+		return err
+	}
+	p.notifyForks(branches)
+	if selfParentFrame != frame {
+		if err := p.saveRoot(e); err != nil {
+			return err
+		}
+	}
+	_, err = p.processRoot(e, selfParentFrame)
+	return err
+}
+
+// ProcessBatch takes a batch of events into processing, flushing the DAG index only once.
+// Event order matter: parents first. All the events must be already available in the EventSource.
+// The batch is validated before the consensus state is changed, so if any event is rejected,
+// none of the events is indexed nor processed.
+// Returns the number of processed events, and the number of events added to the DAG index.
+// The events of the current epoch are indexed together once all of them are validated, before any of them is processed.
+// So if the processing fails afterwards, events[processed:indexed] are indexed but not processed,
+// and mustn't be passed to Process or ProcessBatch again.
+// If an event seals the epoch, the following events aren't processed, and ErrEpochSealed is returned.
+// The events of a later epoch, which may follow the sealing root, aren't validated nor indexed against the current epoch.
+// ProcessBatch is not safe for concurrent use.
+func (p *IndexedLachesis) ProcessBatch(events []consensus.Event) (processed, indexed int, err error) {
+	if err := p.loadStates(); err != nil {
+		return 0, 0, err
+	}
+	if err := p.replayRewound(); err != nil {
+		return 0, 0, err
+	}
+	defer p.DagIndexer.DropNotFlushed()
+	branches, err := p.branchesNum()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// roots of the batch, which aren't saved yet
+	batchRoots := make(map[consensus.Frame][]consensusstore.RootDescriptor)
+	getFrameRoots := func(f consensus.Frame) ([]consensusstore.RootDescriptor, error) {
+		roots, err := p.store.GetFrameRoots(f)
+		if err != nil || len(batchRoots[f]) == 0 {
+			return roots, err
+		}
+		merged := make([]consensusstore.RootDescriptor, 0, len(roots)+len(batchRoots[f]))
+		merged = append(merged, roots...)
+		return append(merged, batchRoots[f]...), nil
+	}
+
+	epoch := p.store.GetEpoch()
+	// the events of a later epoch may be processed only once the epoch is sealed by the batch
+	current := len(events)
+	for i, e := range events {
+		if e.Epoch() > epoch {
+			current = i
+			break
+		}
+	}
+	selfParentFrames := make([]consensus.Frame, current)
+	isRoot := make([]bool, current)
+	for i, e := range events[:current] {
+		if e.Epoch() != epoch {
+			return 0, 0, fmt.Errorf("%w: event %s of epoch %d", ErrWrongEpoch, e.ID(), e.Epoch())
+		}
+		if err := p.DagIndexer.Add(e); err != nil {
+			return 0, 0, fmt.Errorf("event %s: %w", e.ID(), err)
+		}
+		selfParentFrame, frame, err := p.checkEvent(e, getFrameRoots)
+		if err != nil {
+			return 0, 0, fmt.Errorf("event %s: %w", e.ID(), err)
+		}
+		selfParentFrames[i] = selfParentFrame
+		if selfParentFrame != frame {
+			isRoot[i] = true
+			batchRoots[e.Frame()] = append(batchRoots[e.Frame()], consensusstore.RootDescriptor{
+				ValidatorID: e.Creator(),
+				RootHash:    e.ID(),
+			})
+		}
+	}
+
+	// the events are indexed before they're saved as roots, so Bootstrap can replay the roots after a crash
+	if err := p.DagIndexer.Flush(); err != nil {
+		return 0, 0, err
+	}
+	p.notifyForks(branches)
+	for i, e := range events[:current] {
+		if isRoot[i] {
+			if err := p.saveRoot(e); err != nil {
+				return i, current, err
+			}
+		}
+		sealed, err := p.processRoot(e, selfParentFrames[i])
+		if err != nil {
+			return i, current, err
+		}
+		if sealed && i+1 < len(events) {
+			return i + 1, current, fmt.Errorf("%w: by event %s, %d events of the batch aren't processed", ErrEpochSealed, e.ID(), len(events)-i-1)
+		}
+	}
+	if current < len(events) {
+		e := events[current]
+		return current, current, fmt.Errorf("%w: event %s of epoch %d", ErrWrongEpoch, e.ID(), e.Epoch())
+	}
+	return current, current, nil
+}
+
+// branchesNum returns the number of the indexed branches, see notifyForks.
+func (p *IndexedLachesis) branchesNum() (int, error) {
+	if err := p.DagIndexer.InitBranchesInfo(); err != nil {
+		return 0, err
+	}
+	return len(p.DagIndexer.BranchesInfo().BranchIDCreatorIdxs), nil
+}
+
+// notifyForks publishes the validators, whose first fork is indexed since the index had the number of branches.
+// The branches are stored in the DAG index, so the cheaters aren't published again after a restart.
+func (p *IndexedLachesis) notifyForks(branches int) {
+	info := p.DagIndexer.BranchesInfo()
+	validators := p.store.GetValidators()
+	var detected consensus.Cheaters
+	for branchID := branches; branchID < len(info.BranchIDCreatorIdxs); branchID++ {
+		creatorIdx := info.BranchIDCreatorIdxs[branchID]
+		// the second branch of the validator is its first fork
+		if info.BranchIDByCreators[creatorIdx][1] == consensus.ValidatorIndex(branchID) {
+			detected = append(detected, validators.GetID(creatorIdx))
+		}
+	}
+	if len(detected) != 0 {
+		p.feed.Send(CheatersDetected{
+			Epoch:    p.store.GetEpoch(),
+			Cheaters: detected,
+		})
+	}
 }
 
 func (p *IndexedLachesis) Bootstrap(callback consensus.ConsensusCallbacks) error {
 	base := p.Lachesis.OrdererCallbacks()
 	ordererCallbacks := OrdererCallbacks{
-		ApplyAtropos: base.ApplyAtropos,
+		ApplyAtropos:  base.ApplyAtropos,
+		EpochRestored: base.EpochRestored,
 		EpochDBLoaded: func(epoch consensus.Epoch) {
 			if base.EpochDBLoaded != nil {
 				base.EpochDBLoaded(epoch)
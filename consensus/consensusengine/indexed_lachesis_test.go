@@ -0,0 +1,323 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+func TestIndexedLachesis_ProcessBatch(t *testing.T) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	lchs := make([]*CoreLachesis, 0, 2)
+	inputs := make([]*consensustest.TestEventSource, 0, 2)
+	for i := 0; i < 2; i++ {
+		lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, weights)
+		lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+			if lch.store.GetLastDecidedFrame()+1 == 10 {
+				return lch.store.GetValidators()
+			}
+			return nil
+		}
+		lchs = append(lchs, lch)
+		inputs = append(inputs, input)
+	}
+
+	// process events one by one on lch0
+	ordered := map[consensus.Epoch]consensus.Events{}
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				ordered[epoch] = append(ordered[epoch], e)
+				inputs[0].SetEvent(e)
+				assertar.NoError(lchs[0].Process(e))
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lchs[0].store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lchs[0].Build(e)
+			},
+		})
+	}
+
+	// process the same events in batches of random size on lch1, the batches may span the epochs
+	var events consensus.Events
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		for _, e := range reorder(ordered[epoch]) {
+			inputs[1].SetEvent(e)
+			events = append(events, e)
+		}
+	}
+	for len(events) != 0 {
+		epoch := lchs[1].store.GetEpoch()
+		size := min(1+r.IntN(50), len(events))
+		processed, indexed, err := lchs[1].ProcessBatch(events[:size])
+		if errors.Is(err, ErrEpochSealed) || errors.Is(err, ErrWrongEpoch) {
+			assertar.Less(processed, size)
+			assertar.LessOrEqual(processed, indexed)
+		} else {
+			assertar.NoError(err)
+			assertar.Equal(size, processed)
+			assertar.Equal(size, indexed)
+		}
+		events = events[processed:]
+		if lchs[1].store.GetEpoch() != epoch {
+			// the rest of the sealed epoch is skipped
+			for len(events) != 0 && events[0].Epoch() == epoch {
+				events = events[1:]
+			}
+		}
+	}
+	assertar.Equal(consensus.Epoch(epochs+1), lchs[1].store.GetEpoch(), "epoch wasn't sealed")
+
+	compareResults(t, lchs)
+}
+
+func TestIndexedLachesis_ProcessBatch_RejectsWholeBatch(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(4)
+	lchs := make([]*CoreLachesis, 0, 2)
+	inputs := make([]*consensustest.TestEventSource, 0, 2)
+	for i := 0; i < 2; i++ {
+		lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, nil)
+		lchs = append(lchs, lch)
+		inputs = append(inputs, input)
+	}
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 60, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			inputs[0].SetEvent(e)
+			inputs[1].SetEvent(e)
+			assertar.NoError(lchs[0].Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lchs[0].Build(e)
+		},
+	})
+	assertar.NotZero(lchs[0].store.GetLastDecidedFrame())
+
+	// an event in the middle of the batch claims a wrong frame
+	original := events[len(events)/2]
+	forged := *original.(*consensustest.TestEvent)
+	forged.SetFrame(original.Frame() + 1)
+	inputs[1].SetEvent(&forged)
+	batch := append(consensus.Events{}, events...)
+	batch[len(events)/2] = &forged
+
+	processed, indexed, err := lchs[1].ProcessBatch(batch)
+	assertar.ErrorIs(err, ErrWrongFrame)
+	assertar.Zero(processed)
+	assertar.Zero(indexed)
+
+	// nothing is processed
+	roots, err := lchs[1].store.GetFrameRoots(consensus.FirstFrame)
+	assertar.NoError(err)
+	assertar.Empty(roots)
+	assertar.Zero(lchs[1].store.GetLastDecidedFrame())
+	for _, e := range events {
+		hb, err := lchs[1].DagIndexer.GetHighestBefore(e.ID())
+		assertar.NoError(err)
+		assertar.Nil(hb)
+	}
+
+	// the valid batch is processed
+	inputs[1].SetEvent(original)
+	processed, indexed, err = lchs[1].ProcessBatch(events)
+	assertar.NoError(err)
+	assertar.Equal(len(events), processed)
+	assertar.Equal(len(events), indexed)
+	compareResults(t, lchs)
+}
+
+var errRootWrite = errors.New("root write failure")
+
+// rootsFailingAfter is a kvdb.Store, which fails the writes once it has accepted the number of them
+type rootsFailingAfter struct {
+	kvdb.Store
+	accepted *int
+}
+
+func (db rootsFailingAfter) Put(key []byte, value []byte) error {
+	if *db.accepted == 0 {
+		return errRootWrite
+	}
+	*db.accepted--
+	return db.Store.Put(key, value)
+}
+
+func TestIndexedLachesis_ProcessBatch_ReportsIndexed(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(4)
+	lchs := make([]*CoreLachesis, 0, 2)
+	inputs := make([]*consensustest.TestEventSource, 0, 2)
+	for i := 0; i < 2; i++ {
+		lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, nil)
+		lchs = append(lchs, lch)
+		inputs = append(inputs, input)
+	}
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 60, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			inputs[0].SetEvent(e)
+			inputs[1].SetEvent(e)
+			assertar.NoError(lchs[0].Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lchs[0].Build(e)
+		},
+	})
+
+	// the processing fails on saving the second root of the frame 2, once the whole batch is indexed
+	accepted := len(nodes) + 1
+	lchs[1].store.EpochTable.Roots = rootsFailingAfter{lchs[1].store.EpochTable.Roots, &accepted}
+	processed, indexed, err := lchs[1].ProcessBatch(events)
+	assertar.ErrorIs(err, errRootWrite)
+	assertar.Equal(len(events), indexed)
+	assertar.Less(processed, indexed)
+	assertar.Equal(consensus.Frame(2), events[processed].Frame())
+
+	// the unprocessed events are indexed
+	for _, e := range events[processed:indexed] {
+		hb, err := lchs[1].DagIndexer.GetHighestBefore(e.ID())
+		assertar.NoError(err)
+		assertar.NotNil(hb)
+	}
+	roots, err := lchs[1].store.GetFrameRoots(2)
+	assertar.NoError(err)
+	assertar.Len(roots, 1)
+}
+
+func TestIndexedLachesis_MemoryIndexBackend(t *testing.T) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	backends := []dagindexer.Backend{dagindexer.DBBackend, dagindexer.MemoryBackend}
+	lchs := make([]*CoreLachesis, 0, len(backends))
+	inputs := make([]*consensustest.TestEventSource, 0, len(backends))
+	for _, backend := range backends {
+		indexConfig := dagindexer.LiteConfig()
+		indexConfig.Backend = backend
+		engine, _, input, _ := newCoreConsensus(nodes, weights, consensusstore.NewMemStore(), indexConfig)
+		lch := bootstrapCoreConsensus(engine)
+		lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+			if lch.store.GetLastDecidedFrame()+1 == 10 {
+				return lch.store.GetValidators()
+			}
+			return nil
+		}
+		lchs = append(lchs, lch)
+		inputs = append(inputs, input)
+	}
+
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				for i, lch := range lchs {
+					inputs[i].SetEvent(e)
+					assertar.NoError(lch.Process(e))
+				}
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lchs[0].store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lchs[0].Build(e)
+			},
+		})
+		assertar.Equal(epoch+1, lchs[1].store.GetEpoch(), "epoch wasn't sealed")
+	}
+
+	compareResults(t, lchs)
+}
+
+// TestIndexedLachesis_BranchCapOrder checks the nodes with a branch cap agree on the frames and the blocks,
+// whichever forks are merged into the sink branch due to the order of arrival.
+func TestIndexedLachesis_BranchCapOrder(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	// the cheaters have enough weight to change the frames, but less than 1/3W
+	weights := []consensus.Weight{3, 1, 3, 3, 3}
+	engine, _, generatorInput, _ := newCoreConsensus(nodes, weights, consensusstore.NewMemStore(), dagindexer.LiteConfig())
+	generator := bootstrapCoreConsensus(engine)
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:2], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assertar.NoError(generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+	assertar.NotZero(generator.store.GetLastDecidedFrame())
+
+	lchs := []*CoreLachesis{generator}
+	for try := 0; try < 3; try++ {
+		ordered := events
+		if try != 0 {
+			unordered := make(consensus.Events, len(events))
+			for i, j := range r.Perm(len(events)) {
+				unordered[i] = events[j]
+			}
+			ordered = consensustest.ByParents(unordered)
+		}
+		indexConfig := dagindexer.LiteConfig()
+		indexConfig.MaxBranches = 2
+		engine, _, input, index := newCoreConsensus(nodes, weights, consensusstore.NewMemStore(), indexConfig)
+		lch := bootstrapCoreConsensus(engine)
+		// the frames of the events are checked by Process
+		for _, e := range ordered {
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		}
+		assertar.NoError(index.InitBranchesInfo())
+		assertar.NotEmpty(index.BranchesInfo().SinkBranchIDs)
+		lchs = append(lchs, lch)
+	}
+	for _, lch := range lchs[1:] {
+		compareBlocks(assertar, generator, lch)
+	}
+	compareResults(t, lchs)
+}
@@ -11,9 +11,12 @@
 package consensusengine
 
 import (
+	"fmt"
+
 	"github.com/0xsoniclabs/consensus/consensus"
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
 	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 )
 
 var _ consensus.Consensus = (*Lachesis)(nil)
@@ -26,6 +29,10 @@ type Lachesis struct {
 	*Orderer
 	dagIndex *dagindexer.Index
 	callback consensus.ConsensusCallbacks
+
+	metrics struct {
+		blockFrames metrics.Histogram
+	}
 }
 
 // NewLachesis creates Lachesis instance.
@@ -34,28 +41,42 @@ func NewLachesis(store *consensusstore.Store, input EventSource, dagIndex *dagin
 		Orderer:  NewOrderer(store, input, dagIndex, crit, config),
 		dagIndex: dagIndex,
 	}
+	// number of frames spanned by the confirmed events of a block, including the decided frame
+	p.metrics.blockFrames = metrics.OrNoop(config.Metrics).Histogram("lachesis/block_frames")
 
 	return p
 }
 
 func (p *Lachesis) confirmEvents(frame consensus.Frame, atropos consensus.EventHash, onEventConfirmed func(consensus.Event)) error {
-	err := p.dfsSubgraph(atropos, func(e consensus.Event) bool {
-		decidedFrame := p.store.GetEventConfirmedOn(e.ID())
+	err := p.dfsSubgraph(atropos, func(e consensus.Event) (bool, error) {
+		decidedFrame, err := p.store.GetEventConfirmedOn(e.ID())
+		if err != nil {
+			return false, err
+		}
 		if decidedFrame != 0 {
-			return false
+			return false, nil
 		}
 		// mark all the walked events as confirmed
-		p.store.SetEventConfirmedOn(e.ID(), frame)
+		if err := p.store.SetEventConfirmedOn(e.ID(), frame); err != nil {
+			return false, err
+		}
 		if onEventConfirmed != nil {
 			onEventConfirmed(e)
 		}
-		return true
+		return true, nil
 	})
 	return err
 }
 
-func (p *Lachesis) applyAtropos(decidedFrame consensus.Frame, atropos consensus.EventHash) *consensus.Validators {
-	atroposVecClock := p.dagIndex.GetMergedHighestBefore(atropos).VSeq
+func (p *Lachesis) applyAtropos(decidedFrame consensus.Frame, atropos consensus.EventHash) (*consensus.Validators, error) {
+	atroposHighestBefore, err := p.dagIndex.GetMergedHighestBefore(atropos)
+	if err != nil {
+		return nil, err
+	}
+	if atroposHighestBefore == nil {
+		return nil, fmt.Errorf("%w: atropos %s isn't indexed", consensus.ErrEventNotFound, atropos.String())
+	}
+	atroposVecClock := atroposHighestBefore.VSeq
 
 	validators := p.store.GetValidators()
 	// cheaters are ordered deterministically
@@ -66,23 +87,84 @@ func (p *Lachesis) applyAtropos(decidedFrame consensus.Frame, atropos consensus.
 		}
 	}
 
+	// traverse newly confirmed events, which are held to be listed in the block and reordered
+	var (
+		confirmed consensus.Events
+		metric    consensus.Metric
+		lowest    = decidedFrame
+	)
+	err = p.confirmEvents(decidedFrame, atropos, func(e consensus.Event) {
+		metric.Num++
+		metric.Size += uint64(e.Size())
+		lowest = min(lowest, e.Frame())
+		if p.callback.BeginBlock != nil {
+			confirmed = append(confirmed, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metric.Num != 0 {
+		p.metrics.blockFrames.Observe(float64(decidedFrame - lowest + 1))
+	}
+
+	// the block is recorded even without BeginBlock, so the block index is complete regardless of the application
+	_, err = p.store.AddBlock(&consensusstore.BlockRecord{
+		Epoch:    p.store.GetEpoch(),
+		Frame:    decidedFrame,
+		Atropos:  atropos,
+		Cheaters: cheaters,
+		Events:   metric,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	if p.callback.BeginBlock == nil {
-		return nil
+		return nil, nil
 	}
+	orderEvents(confirmed, p.config.EventsOrdering)
 	blockCallback := p.callback.BeginBlock(&consensus.Block{
 		Atropos:  atropos,
 		Cheaters: cheaters,
+		Events:   confirmed.IDs(),
 	})
-
-	// traverse newly confirmed events
-	err := p.confirmEvents(decidedFrame, atropos, blockCallback.ApplyEvent)
-	if err != nil {
-		p.crit(err)
+	if blockCallback.ApplyEvent != nil {
+		for _, e := range confirmed {
+			blockCallback.ApplyEvent(e)
+		}
 	}
 
 	if blockCallback.EndBlock != nil {
-		return blockCallback.EndBlock()
+		return blockCallback.EndBlock(), nil
+	}
+	return nil, nil
+}
+
+// restoreEpoch passes the applied blocks of the current epoch to the application, see consensus.ConsensusCallbacks.RestoreEpoch.
+func (p *Lachesis) restoreEpoch() error {
+	if p.callback.RestoreEpoch == nil {
+		return nil
 	}
+	epoch := p.store.GetEpoch()
+	decided := p.store.GetLastDecidedFrame()
+	var blocks []consensus.AppliedBlock
+	err := p.store.ForEachBlock(epoch, epoch, func(_ consensus.BlockID, block *consensusstore.BlockRecord) bool {
+		if block.Frame > decided {
+			// the block is recorded, but the decision of its frame is interrupted, so it's delivered again
+			return false
+		}
+		blocks = append(blocks, consensus.AppliedBlock{
+			Atropos:  block.Atropos,
+			Cheaters: block.Cheaters,
+			Events:   block.Events,
+		})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	p.callback.RestoreEpoch(p.store.GetValidators(), blocks)
 	return nil
 }
 
@@ -91,16 +173,17 @@ func (p *Lachesis) Bootstrap(callback consensus.ConsensusCallbacks) error {
 }
 
 func (p *Lachesis) BootstrapWithOrderer(callback consensus.ConsensusCallbacks, ordererCallbacks OrdererCallbacks) error {
-	err := p.Orderer.Bootstrap(ordererCallbacks)
-	if err != nil {
-		return err
+	if p.election != nil {
+		return ErrAlreadyBootstrapped
 	}
+	// the replayed roots may decide frames, so the callbacks are set before the bootstrap
 	p.callback = callback
-	return nil
+	return p.Orderer.Bootstrap(ordererCallbacks)
 }
 
 func (p *Lachesis) OrdererCallbacks() OrdererCallbacks {
 	return OrdererCallbacks{
-		ApplyAtropos: p.applyAtropos,
+		ApplyAtropos:  p.applyAtropos,
+		EpochRestored: p.restoreEpoch,
 	}
 }
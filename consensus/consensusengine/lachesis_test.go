@@ -0,0 +1,192 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+func TestLachesis_BlockIndex(t *testing.T) {
+	assertar := assert.New(t)
+
+	const epochs = 3
+	nodes := consensustest.GenNodes(5)
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, []consensus.Weight{1, 2, 3, 4, 5})
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		if lch.store.GetLastDecidedFrame()+1 == 10 {
+			return lch.store.GetValidators()
+		}
+		return nil
+	}
+
+	processed := 0
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				input.SetEvent(e)
+				assertar.NoError(lch.Process(e))
+				processed++
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != lch.store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return lch.Build(e)
+			},
+		})
+	}
+
+	last, err := lch.store.GetLastBlockNumber()
+	assertar.NoError(err)
+	assertar.Equal(consensus.BlockID(len(lch.blocks)), last)
+
+	prev := consensus.BlockID(0)
+	confirmed := uint32(0)
+	err = lch.store.ForEachBlock(1, epochs, func(n consensus.BlockID, block *consensusstore.BlockRecord) bool {
+		assertar.Equal(prev+1, n)
+		prev = n
+		expected := lch.blocks[BlockKey{block.Epoch, block.Frame}]
+		if !assertar.NotNil(expected) {
+			return false
+		}
+		assertar.Equal(expected.Atropos, block.Atropos)
+		assertar.Equal(expected.Cheaters, block.Cheaters)
+		assertar.NotZero(block.Events.Num)
+		confirmed += block.Events.Num
+
+		byAtropos, _, err := lch.store.GetBlockByAtropos(block.Atropos)
+		assertar.NoError(err)
+		assertar.Equal(n, byAtropos)
+		return true
+	})
+	assertar.NoError(err)
+	assertar.Equal(last, prev)
+	assertar.LessOrEqual(int(confirmed), processed)
+}
+
+func TestLachesis_SealerRestore(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	generator, _, generatorInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assertar.NoError(generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+
+	// the epoch is sealed by the last decided frame
+	sealAfter := uint64(generator.store.GetLastDecidedFrame())
+	newSealer := func() *consensus.Sealer {
+		return consensus.NewSealer(consensus.SealAfterBlocks(sealAfter, nil), generator.store.GetValidators(), nil, nil)
+	}
+	lch, _, input, _ := NewCoreConsensus(nodes, weights)
+	sealer := newSealer()
+	assertar.NoError(lch.Bootstrap(sealer.Wrap(consensus.ConsensusCallbacks{})))
+
+	progressAt := map[consensus.Frame]consensus.EpochProgress{}
+	half := len(events) / 2
+	for _, e := range events[:half] {
+		input.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+		progressAt[lch.store.GetLastDecidedFrame()] = sealer.Progress()
+	}
+	decided := lch.store.GetLastDecidedFrame()
+	if !assertar.Greater(decided, consensus.Frame(2)) {
+		return
+	}
+	assertar.NotEmpty(sealer.Progress().Cheaters)
+
+	// the progress is rebuilt on bootstrap
+	restarted := restartLachesis(assertar, &CoreLachesis{IndexedLachesis: lch})
+	restartedSealer := newSealer()
+	assertar.NoError(restarted.Bootstrap(restartedSealer.Wrap(consensus.ConsensusCallbacks{})))
+	assertar.Equal(sealer.Progress(), restartedSealer.Progress())
+
+	// the progress of the rewound blocks is dropped
+	rewindTo := decided - 1
+	for rewindTo > 1 && progressAt[rewindTo].Blocks == 0 {
+		rewindTo--
+	}
+	assertar.NoError(restarted.Rewind(rewindTo))
+	assertar.Equal(progressAt[rewindTo], restartedSealer.Progress())
+
+	// both seal the epoch by the same block
+	for _, e := range events[half:] {
+		input.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+		assertar.NoError(restarted.Process(e))
+		assertar.Equal(sealer.Progress(), restartedSealer.Progress())
+		if lch.store.GetEpoch() != consensus.FirstEpoch {
+			break
+		}
+	}
+	assertar.Equal(consensus.FirstEpoch+1, lch.store.GetEpoch())
+	assertar.Equal(consensus.FirstEpoch+1, restarted.store.GetEpoch())
+	assertar.Equal(storedBlocks(t, lch.store), storedBlocks(t, restarted.store))
+}
+
+func TestLachesis_BlockIndexWithoutBeginBlock(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	expected, _, expectedInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	lch, _, input, _ := NewCoreConsensus(nodes, weights)
+	assertar.NoError(lch.Bootstrap(consensus.ConsensusCallbacks{}))
+
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			expectedInput.SetEvent(e)
+			assertar.NoError(expected.Process(e))
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return expected.Build(e)
+		},
+	})
+	assertar.NotEmpty(expected.blocks)
+
+	// the events are confirmed and the blocks are recorded as with the callbacks
+	assertar.Equal(expected.store.GetLastDecidedFrame(), lch.store.GetLastDecidedFrame())
+	assertar.Len(storedBlocks(t, lch.store), len(expected.blocks))
+	assertar.Equal(storedBlocks(t, expected.store), storedBlocks(t, lch.store))
+	for _, e := range events {
+		expectedFrame, err := expected.store.GetEventConfirmedOn(e.ID())
+		assertar.NoError(err)
+		frame, err := lch.store.GetEventConfirmedOn(e.ID())
+		assertar.NoError(err)
+		assertar.Equal(expectedFrame, frame, "event=%s", e.ID())
+	}
+}
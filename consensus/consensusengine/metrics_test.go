@@ -0,0 +1,86 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestMetrics_Reported(t *testing.T) {
+	assertar := assert.New(t)
+	registry := metrics.NewMemoryRegistry()
+
+	nodes := consensustest.GenNodes(5)
+	storeConfig := consensusstore.LiteStoreConfig()
+	storeConfig.Metrics = registry
+	store := consensusstore.NewStore(memorydb.New(), func(consensus.Epoch) kvdb.Store { return memorydb.New() }, nil, storeConfig)
+	assertar.NoError(store.ApplyGenesis(&consensusstore.Genesis{
+		Validators: consensus.ArrayToValidators(nodes, []consensus.Weight{1, 1, 1, 1, 1}),
+		Epoch:      consensus.FirstEpoch,
+	}))
+	indexConfig := dagindexer.LiteConfig()
+	indexConfig.Metrics = registry
+	config := DefaultConfig()
+	config.Metrics = registry
+	input := consensustest.NewTestEventSource()
+	lch := NewIndexedLachesis(store, input, dagindexer.NewIndex(nil, indexConfig), nil, config)
+	assertar.NoError(lch.Bootstrap(consensus.ConsensusCallbacks{}))
+
+	eventCount := 0
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 20, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			eventCount++
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lch.Build(e)
+		},
+	})
+	assertar.NotZero(store.GetLastDecidedFrame())
+
+	assertar.Equal(int64(eventCount), registry.CounterValue("orderer/events"))
+	assertar.Equal(registry.CounterValue("orderer/roots"), registry.CounterValue("consensusstore/roots_added"))
+	assertar.NotZero(registry.CounterValue("orderer/roots"))
+	assertar.Equal(int64(store.GetLastDecidedFrame()), registry.GaugeValue("orderer/last_decided_frame"))
+	assertar.Equal(uint64(store.GetLastDecidedFrame()), registry.HistogramSummary("orderer/decision_lag_frames").Count)
+	assertar.NotZero(registry.HistogramSummary("orderer/calc_frame_idx_seconds").Count)
+	blockFrames := registry.HistogramSummary("lachesis/block_frames")
+	assertar.Equal(uint64(store.GetLastDecidedFrame()), blockFrames.Count)
+	assertar.GreaterOrEqual(blockFrames.Min, float64(1))
+
+	assertar.GreaterOrEqual(registry.CounterValue("election/decided_frames"), int64(store.GetLastDecidedFrame()))
+	assertar.NotZero(registry.HistogramSummary("election/vote_matrix_size").Max)
+	assertar.NotZero(registry.GaugeValue("election/undecided_frames"))
+
+	// events are added to the index by both Build and Process
+	assertar.Equal(int64(2*eventCount), registry.CounterValue("dagindexer/events_added"))
+	assertar.NotZero(registry.CounterValue("dagindexer/forkless_cause/misses"))
+	assertar.NotZero(registry.CounterValue("dagindexer/forkless_cause/hits"))
+	assertar.Equal(int64(len(nodes)), registry.GaugeValue("dagindexer/branches"))
+	assertar.Equal(int64(eventCount), registry.CounterValue("vecflushable/flushes"))
+
+	assertar.NotZero(registry.CounterValue("consensusstore/frame_roots_cache/hits"))
+	assertar.NotZero(registry.CounterValue("consensusstore/frame_roots_cache/misses"))
+	assertar.Zero(registry.CounterValue("consensusstore/errors"))
+}
@@ -14,12 +14,16 @@ import (
 	"github.com/0xsoniclabs/consensus/consensus"
 	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
 	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 )
 
 type OrdererCallbacks struct {
-	ApplyAtropos func(decidedFrame consensus.Frame, atropos consensus.EventHash) (sealEpoch *consensus.Validators)
+	ApplyAtropos func(decidedFrame consensus.Frame, atropos consensus.EventHash) (sealEpoch *consensus.Validators, err error)
 
 	EpochDBLoaded func(consensus.Epoch)
+	// EpochRestored is called on Bootstrap and Rewind, once the decided state of the epoch is restored,
+	// before the following frames are decided
+	EpochRestored func() error
 }
 
 // Orderer processes events to reach finality on their order.
@@ -32,13 +36,27 @@ type Orderer struct {
 
 	election *election
 	dagIndex *dagindexer.Index
+	// rewound is set if the election is reset by Rewind, and the roots must be replayed
+	rewound bool
+	// snapshotFrame is the frame to deliver of the last election snapshot
+	snapshotFrame consensus.Frame
 
 	callback OrdererCallbacks
+	feed     *Feed
+
+	metrics struct {
+		events           metrics.Counter
+		roots            metrics.Counter
+		lastDecidedFrame metrics.Gauge
+		decisionLag      metrics.Histogram
+		calcFrameIdx     metrics.Histogram
+	}
 }
 
 // NewOrderer creates Orderer instance.
 // Unlike Lachesis, Orderer doesn't updates DAG indexes for events, and doesn't detect cheaters
 // It has only one purpose - reaching consensus on events order.
+// crit is an optional hook, notified of failures which leave the consensus state inconsistent, before they are returned.
 func NewOrderer(store *consensusstore.Store, input EventSource, dagIndex *dagindexer.Index, crit func(error), config Config) *Orderer {
 	p := &Orderer{
 		config:   config,
@@ -46,7 +64,29 @@ func NewOrderer(store *consensusstore.Store, input EventSource, dagIndex *dagind
 		Input:    input,
 		crit:     crit,
 		dagIndex: dagIndex,
+		feed:     NewFeed(),
 	}
+	registry := metrics.OrNoop(config.Metrics)
+	p.metrics.events = registry.Counter("orderer/events")
+	p.metrics.roots = registry.Counter("orderer/roots")
+	p.metrics.lastDecidedFrame = registry.Gauge("orderer/last_decided_frame")
+	// number of frames between the decided frame and the root which decided it
+	p.metrics.decisionLag = registry.Histogram("orderer/decision_lag_frames")
+	p.metrics.calcFrameIdx = registry.Histogram("orderer/calc_frame_idx_seconds")
 
 	return p
 }
+
+// Subscribe to notifications of consensus progress.
+// Slow subscribers don't block the processing, see Feed.
+func (p *Orderer) Subscribe(buffer int) *Subscription {
+	return p.feed.Subscribe(buffer)
+}
+
+// critical notifies the optional crit hook and returns err unchanged.
+func (p *Orderer) critical(err error) error {
+	if p.crit != nil {
+		p.crit(err)
+	}
+	return err
+}
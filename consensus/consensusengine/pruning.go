@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// PruneReport is the outcome of a pruning pass.
+type PruneReport struct {
+	// Frame is the last frame, whose roots are pruned
+	Frame consensus.Frame
+	// Lamport is the Lamport time, below which the DAG index entries of events are pruned
+	Lamport consensus.Lamport
+	// Roots is the number of the deleted roots
+	Roots int
+	// IndexRecords is the number of the deleted DAG index records
+	IndexRecords int
+	// Bytes is the size of the deleted keys and values
+	Bytes uint64
+}
+
+// Prune removes the roots and the DAG index entries of the current epoch, which can't affect
+// the frames calculation and the election anymore, keeping the frames of Config.Pruning below the last decided one.
+// The DAG index entries are pruned for events below the lowest Lamport time of the roots of the first kept frame,
+// as such events can't belong to the kept frames.
+// Afterwards, events which depend on the pruned frames are rejected with consensus.ErrPruned.
+// Prune is safe only if KeepFrames exceeds the lag of the events, see PruningConfig.KeepFrames.
+// Returns an empty report if there's nothing to prune. Prune is not safe for concurrent use with Process.
+func (p *IndexedLachesis) Prune() (PruneReport, error) {
+	cfg := p.config.Pruning
+	lastDecided := p.store.GetLastDecidedFrame()
+	if lastDecided <= cfg.KeepFrames {
+		return PruneReport{}, nil
+	}
+	frame := lastDecided - cfg.KeepFrames
+	pruned, err := p.store.GetPrunedFrame()
+	if err != nil {
+		return PruneReport{}, err
+	}
+	if frame <= pruned || frame-pruned < cfg.MinFrames {
+		return PruneReport{}, nil
+	}
+
+	keptRoots, err := p.store.GetFrameRoots(frame + 1)
+	if err != nil {
+		return PruneReport{}, err
+	}
+	report := PruneReport{Frame: frame}
+	for i, root := range keptRoots {
+		if i == 0 || root.RootHash.Lamport() < report.Lamport {
+			report.Lamport = root.RootHash.Lamport()
+		}
+	}
+
+	report.Roots, report.Bytes, err = p.store.PruneRoots(frame)
+	if err != nil {
+		return PruneReport{}, err
+	}
+	if report.Lamport != 0 {
+		records, size, err := p.DagIndexer.Prune(p.store.GetEpoch(), report.Lamport)
+		if err != nil {
+			return PruneReport{}, err
+		}
+		report.IndexRecords = records
+		report.Bytes += size
+	}
+	return report, nil
+}
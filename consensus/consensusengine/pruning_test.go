@@ -0,0 +1,162 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+func TestIndexedLachesis_Prune(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	pruned, _, prunedInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	pruned.config.Pruning = PruningConfig{KeepFrames: 5, MinFrames: 2}
+	expected, _, expectedInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+
+	var events consensus.Events
+	passes, roots, records, size := 0, 0, 0, uint64(0)
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, TestMaxEpochEvents*5, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			prunedInput.SetEvent(e)
+			assertar.NoError(pruned.Process(e))
+			expectedInput.SetEvent(e)
+			assertar.NoError(expected.Process(e))
+			events = append(events, e)
+
+			report, err := pruned.Prune()
+			assertar.NoError(err)
+			if report.Frame == 0 {
+				return
+			}
+			passes++
+			assertar.Equal(pruned.store.GetLastDecidedFrame()-5, report.Frame)
+			roots += report.Roots
+			records += report.IndexRecords
+			size += report.Bytes
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return pruned.Build(e)
+		},
+	})
+	if t.Failed() {
+		return
+	}
+	assertar.Greater(passes, 1)
+	assertar.NotZero(roots)
+	assertar.NotZero(records)
+	assertar.NotZero(size)
+
+	// pruning doesn't affect the decisions
+	assertar.Greater(len(expected.blocks), 10)
+	compareStates(assertar, expected, pruned)
+	compareBlocks(assertar, expected, pruned)
+
+	// the pruned roots and vectors are gone
+	prunedFrame, err := pruned.store.GetPrunedFrame()
+	assertar.NoError(err)
+	frameRoots, err := pruned.store.GetFrameRoots(prunedFrame)
+	assertar.NoError(err)
+	assertar.Empty(frameRoots)
+	frameRoots, err = pruned.store.GetFrameRoots(prunedFrame + 1)
+	assertar.NoError(err)
+	assertar.NotEmpty(frameRoots)
+	vec, err := pruned.DagIndexer.GetHighestBefore(events[0].ID())
+	assertar.NoError(err)
+	assertar.Nil(vec)
+	_, err = pruned.Certificate(prunedFrame)
+	assertar.ErrorIs(err, consensus.ErrPruned)
+
+	// events which depend on the pruned state are rejected
+	first := events[0]
+	late := &consensustest.TestEvent{}
+	late.SetEpoch(first.Epoch())
+	late.SetCreator(first.Creator())
+	late.SetSeq(first.Seq() + 1)
+	late.SetLamport(first.Lamport() + 1)
+	late.SetFrame(first.Frame())
+	late.AddParent(first.ID())
+	late.SetID([24]byte{0xff})
+	prunedInput.SetEvent(late)
+	assertar.ErrorIs(pruned.Process(late), consensus.ErrPruned)
+}
+
+func TestIndexedLachesis_PruneKeepFramesBound(t *testing.T) {
+	assertar := assert.New(t)
+
+	const keepFrames = 5
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	pruned, _, prunedInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	pruned.config.Pruning = PruningConfig{KeepFrames: keepFrames, MinFrames: 1}
+	full, _, fullInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+
+	firstOfFrame := map[consensus.Frame]consensus.Event{}
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, TestMaxEpochEvents*2, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			prunedInput.SetEvent(e)
+			assertar.NoError(pruned.Process(e))
+			fullInput.SetEvent(e)
+			assertar.NoError(full.Process(e))
+			_, err := pruned.Prune()
+			assertar.NoError(err)
+			if _, ok := firstOfFrame[e.Frame()]; !ok {
+				firstOfFrame[e.Frame()] = e
+			}
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return full.Build(e)
+		},
+	})
+	if t.Failed() {
+		return
+	}
+	decided := pruned.store.GetLastDecidedFrame()
+	prunedFrame, err := pruned.store.GetPrunedFrame()
+	assertar.NoError(err)
+	if !assertar.Equal(decided-keepFrames, prunedFrame) {
+		return
+	}
+
+	// a late event, whose parent lags less than KeepFrames frames behind the last decided frame, is accepted by both,
+	// a longer lag makes the pruned node reject the event which the other one accepts
+	for frame := prunedFrame - 2; frame <= decided; frame++ {
+		parent := firstOfFrame[frame]
+		late := &consensustest.TestEvent{}
+		late.SetEpoch(parent.Epoch())
+		late.SetCreator(parent.Creator())
+		late.SetSeq(parent.Seq() + 1)
+		late.SetLamport(parent.Lamport() + 1)
+		late.SetFrame(parent.Frame())
+		late.AddParent(parent.ID())
+		late.SetID([24]byte{0xff, byte(frame)})
+
+		fullInput.SetEvent(late)
+		assertar.NoError(full.Process(late), "frame %d", frame)
+		prunedInput.SetEvent(late)
+		err := pruned.Process(late)
+		if decided-frame < keepFrames {
+			assertar.NoError(err, "frame %d", frame)
+		} else {
+			assertar.ErrorIs(err, consensus.ErrPruned, "frame %d", frame)
+		}
+	}
+}
@@ -129,7 +129,7 @@ func testRestartAndReset(t *testing.T, weights []consensus.Weight, mutateWeights
 					lchs[GENERATOR].Process(e))
 
 				ordered = append(ordered, e)
-				epochStates[lchs[GENERATOR].store.GetEpoch()] = lchs[GENERATOR].store.GetEpochState()
+				epochStates[lchs[GENERATOR].store.GetEpoch()] = &consensusstore.EpochState{Epoch: lchs[GENERATOR].store.GetEpoch(), Validators: lchs[GENERATOR].store.GetValidators()}
 			},
 			Build: func(e consensus.MutableEvent, name string) error {
 				if epoch != lchs[GENERATOR].store.GetEpoch() {
@@ -163,35 +163,8 @@ func testRestartAndReset(t *testing.T, weights []consensus.Weight, mutateWeights
 			continue
 		}
 		if r.IntN(10) == 0 {
-			prev := lchs[RESTORED]
-
-			store := consensusstore.NewMemStore()
-			// copy prev DB into new one
-			{
-				it := prev.store.MainDB.NewIterator(nil, nil)
-				for it.Next() {
-					assertar.NoError(store.MainDB.Put(it.Key(), it.Value()))
-				}
-				it.Release()
-			}
-			restartEpochDB := memorydb.New()
-			{
-				it := prev.store.EpochDB.NewIterator(nil, nil)
-				for it.Next() {
-					assertar.NoError(restartEpochDB.Put(it.Key(), it.Value()))
-				}
-				it.Release()
-			}
-			restartEpoch := prev.store.GetEpoch()
-			store.GetEpochDB = func(epoch consensus.Epoch) kvdb.Store {
-				if epoch == restartEpoch {
-					return restartEpochDB
-				}
-				return memorydb.New()
-			}
-
-			restored := NewIndexedLachesis(store, prev.Input, dagindexer.NewIndex(prev.crit, dagindexer.LiteConfig()), prev.crit, prev.config)
-			assertar.NoError(restored.Bootstrap(prev.callback))
+			restored := restartLachesis(assertar, lchs[RESTORED])
+			assertar.NoError(restored.Bootstrap(lchs[RESTORED].callback))
 
 			lchs[RESTORED].IndexedLachesis = restored
 		}
@@ -219,9 +192,11 @@ func testRestartAndReset(t *testing.T, weights []consensus.Weight, mutateWeights
 
 func compareStates(assertar *assert.Assertions, expected, restored *CoreLachesis) {
 	assertar.Equal(
-		*(expected.store.GetLastDecidedState()), *(restored.store.GetLastDecidedState()))
+		expected.store.GetLastDecidedFrame(), restored.store.GetLastDecidedFrame())
 	assertar.Equal(
-		expected.store.GetEpochState().String(), restored.store.GetEpochState().String())
+		expected.store.GetEpoch(), restored.store.GetEpoch())
+	assertar.Equal(
+		expected.store.GetValidators().String(), restored.store.GetValidators().String())
 	// check last block
 	if len(expected.blocks) != 0 {
 		assertar.Equal(expected.lastBlock, restored.lastBlock)
@@ -245,3 +220,33 @@ func compareBlocks(assertar *assert.Assertions, expected, restored *CoreLachesis
 		}
 	}
 }
+
+// restartLachesis creates a new instance over a copy of the databases of prev, without bootstrapping it.
+func restartLachesis(assertar *assert.Assertions, prev *CoreLachesis) *IndexedLachesis {
+	store := consensusstore.NewMemStore()
+	// copy prev DB into new one
+	{
+		it := prev.store.MainDB.NewIterator(nil, nil)
+		for it.Next() {
+			assertar.NoError(store.MainDB.Put(it.Key(), it.Value()))
+		}
+		it.Release()
+	}
+	restartEpochDB := memorydb.New()
+	{
+		it := prev.store.EpochDB.NewIterator(nil, nil)
+		for it.Next() {
+			assertar.NoError(restartEpochDB.Put(it.Key(), it.Value()))
+		}
+		it.Release()
+	}
+	restartEpoch := prev.store.GetEpoch()
+	store.GetEpochDB = func(epoch consensus.Epoch) kvdb.Store {
+		if epoch == restartEpoch {
+			return restartEpochDB
+		}
+		return memorydb.New()
+	}
+
+	return NewIndexedLachesis(store, prev.Input, dagindexer.NewIndex(prev.crit, dagindexer.LiteConfig()), prev.crit, prev.config)
+}
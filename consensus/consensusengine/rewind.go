@@ -0,0 +1,62 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// Rewind rewinds the current epoch to the decided frame, e.g. to re-apply the blocks after a failure of the application.
+// The decisions of the later frames are forgotten, together with the confirmations of their events.
+// The same atropoi are decided again and delivered through BeginBlock on the next Process,
+// or on Bootstrap after a restart. The DAG index isn't rewound, so the detected cheaters aren't published again.
+// Returns consensus.ErrPruned if the roots of the following frame are pruned.
+// Rewind is not safe for concurrent use.
+func (p *Orderer) Rewind(frame consensus.Frame) error {
+	if p.election == nil {
+		return ErrNotBootstrapped
+	}
+	if err := p.store.Rewind(frame); err != nil {
+		return err
+	}
+	p.election.ResetEpoch(frame+1, p.store.GetValidators())
+	p.rewound = true
+	if p.callback.EpochRestored != nil {
+		if err := p.callback.EpochRestored(); err != nil {
+			return err
+		}
+	}
+	p.metrics.lastDecidedFrame.Set(int64(frame))
+	p.feed.Send(Rewound{
+		Epoch: p.store.GetEpoch(),
+		Frame: frame,
+	})
+	return nil
+}
+
+// replayRewound replays the election on the stored roots after Rewind, which re-delivers the decided frames.
+// Returns ErrWrongEpoch if the re-delivered blocks seal the epoch of the event being processed.
+func (p *Orderer) replayRewound() error {
+	if !p.rewound {
+		return nil
+	}
+	p.rewound = false
+	epoch := p.store.GetEpoch()
+	if err := p.bootstrapElection(); err != nil {
+		return err
+	}
+	if p.store.GetEpoch() != epoch {
+		return fmt.Errorf("%w: epoch %d is sealed by the re-delivered blocks", ErrWrongEpoch, epoch)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+)
+
+// newDeliveringConsensus creates a bootstrapped consensus, which records the delivered atropoi.
+func newDeliveringConsensus(nodes []consensus.ValidatorID, weights []consensus.Weight) (*CoreLachesis, *consensustest.TestEventSource, *[]consensus.EventHash) {
+	lch, _, input, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	delivered := &[]consensus.EventHash{}
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		*delivered = append(*delivered, block.Atropos)
+		return nil
+	}
+	return lch, input, delivered
+}
+
+func TestOrderer_Rewind(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	expected, input, expectedDelivered := newDeliveringConsensus(nodes, weights)
+	var ordered consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, TestMaxEpochEvents, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			assertar.NoError(expected.Process(e))
+			ordered = append(ordered, e)
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return expected.Build(e)
+		},
+	})
+	if !assertar.NotEmpty(*expectedDelivered) {
+		return
+	}
+
+	for _, restart := range []bool{false, true} {
+		lch, lchInput, delivered := newDeliveringConsensus(nodes, weights)
+		half := len(ordered) / 2
+		for _, e := range ordered[:half] {
+			lchInput.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		}
+		decided := lch.store.GetLastDecidedFrame()
+		if !assertar.Greater(decided, consensus.Frame(2)) {
+			return
+		}
+		rewindTo := decided / 2
+		before := slices.Clone(*delivered)
+
+		assertar.ErrorIs(lch.Rewind(decided+1), consensusstore.ErrNotDecided)
+		sub := lch.Subscribe(1)
+		assertar.NoError(lch.Rewind(rewindTo))
+		assertar.Equal(Rewound{Epoch: consensus.FirstEpoch, Frame: rewindTo}, <-sub.C())
+		sub.Unsubscribe()
+
+		// the later decisions are forgotten
+		assertar.Equal(rewindTo, lch.store.GetLastDecidedFrame())
+		for i, atropos := range before {
+			frame, err := lch.store.GetEventConfirmedOn(atropos)
+			assertar.NoError(err)
+			n, err := lch.store.GetBlockNumber(atropos)
+			assertar.NoError(err)
+			if consensus.Frame(i+1) <= rewindTo {
+				assertar.Equal(consensus.Frame(i+1), frame)
+				assertar.Equal(consensus.BlockID(i+1), n)
+			} else {
+				assertar.Zero(frame)
+				assertar.Zero(n)
+			}
+		}
+		last, err := lch.store.GetLastBlockNumber()
+		assertar.NoError(err)
+		assertar.Equal(consensus.BlockID(rewindTo), last)
+
+		if restart {
+			// the atropoi are re-delivered on bootstrap
+			callback := lch.callback
+			lch.IndexedLachesis = restartLachesis(assertar, lch)
+			assertar.NoError(lch.Bootstrap(callback))
+			assertar.Equal(append(slices.Clone(before), before[rewindTo:]...), *delivered)
+		} else {
+			assertar.Equal(before, *delivered)
+		}
+
+		// the atropoi are re-delivered on the next event
+		for _, e := range ordered[half:] {
+			lchInput.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		}
+		assertar.Equal(append(slices.Clone(before), (*expectedDelivered)[rewindTo:]...), *delivered)
+		assertar.Equal(len(expected.blocks), len(lch.blocks))
+		for key, block := range expected.blocks {
+			if assertar.NotNil(lch.blocks[key]) {
+				assertar.Equal(block.Atropos, lch.blocks[key].Atropos)
+				assertar.Equal(block.Cheaters, lch.blocks[key].Cheaters)
+			}
+		}
+		assertar.Equal(expected.store.GetLastDecidedFrame(), lch.store.GetLastDecidedFrame())
+		assertar.Equal(storedBlocks(t, expected.store), storedBlocks(t, lch.store))
+	}
+}
+
+func TestOrderer_RewindNotifications(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	generator, _, generatorInput, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	var events consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events = append(events, e)
+			generatorInput.SetEvent(e)
+			assertar.NoError(generator.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return generator.Build(e)
+		},
+	})
+	sealAfter := uint64(generator.store.GetLastDecidedFrame())
+
+	// run processes the events until the epoch is sealed, optionally rewinding in the middle,
+	// and returns the published notifications except RootAdded
+	run := func(rewind bool) (*IndexedLachesis, consensus.Frame, []Notification) {
+		lch, _, input, _ := NewCoreConsensus(nodes, weights)
+		sealer := consensus.NewSealer(consensus.SealAfterBlocks(sealAfter, nil), generator.store.GetValidators(), nil, nil)
+		assertar.NoError(lch.Bootstrap(sealer.Wrap(consensus.ConsensusCallbacks{})))
+		sub := lch.Subscribe(len(events) * 4)
+		defer sub.Unsubscribe()
+
+		rewindTo := consensus.Frame(0)
+		half := len(events) / 2
+		for i, e := range events {
+			if rewind && i == half {
+				rewindTo = lch.store.GetLastDecidedFrame() / 2
+				assertar.NoError(lch.Rewind(rewindTo))
+			}
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+			if lch.store.GetEpoch() != consensus.FirstEpoch {
+				break
+			}
+		}
+		assertar.Zero(sub.Dropped())
+		var notifications []Notification
+		for len(sub.C()) != 0 {
+			if n := <-sub.C(); !isRootAdded(n) {
+				notifications = append(notifications, n)
+			}
+		}
+		return lch, rewindTo, notifications
+	}
+	expected, _, expectedNotifications := run(false)
+	lch, rewindTo, notifications := run(true)
+	if !assertar.NotZero(rewindTo) {
+		return
+	}
+
+	// byType splits the notifications into the decided atropoi after the frame, the detected cheaters and the sealed epochs
+	byType := func(notifications []Notification, after consensus.Frame) (decided []AtroposDecided, cheaters []CheatersDetected, sealed []EpochSealed, sealedBy consensus.EventHash) {
+		for _, n := range notifications {
+			switch n := n.(type) {
+			case AtroposDecided:
+				if n.Frame > after {
+					decided = append(decided, n)
+				}
+			case CheatersDetected:
+				cheaters = append(cheaters, n)
+			case EpochSealed:
+				sealed = append(sealed, n)
+				sealedBy = decided[len(decided)-1].Atropos
+			case Rewound:
+				decided = nil
+			}
+		}
+		return
+	}
+	expectedDecided, expectedCheaters, expectedSealed, expectedSealedBy := byType(expectedNotifications, rewindTo)
+	decided, cheaters, sealed, sealedBy := byType(notifications, rewindTo)
+
+	// the rewound frames are decided again, the cheaters aren't reported again
+	assertar.Contains(notifications, Rewound{Epoch: consensus.FirstEpoch, Frame: rewindTo})
+	assertar.Equal(expectedDecided, decided)
+	assertar.NotEmpty(expectedCheaters)
+	assertar.Equal(expectedCheaters, cheaters)
+
+	// the epoch is sealed by the same block
+	assertar.Len(expectedSealed, 1)
+	assertar.Equal(expectedSealed, sealed)
+	assertar.Equal(expectedSealedBy, sealedBy)
+	assertar.Equal(storedBlocks(t, expected.store), storedBlocks(t, lch.store))
+}
+
+func isRootAdded(n Notification) bool {
+	_, ok := n.(RootAdded)
+	return ok
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/cacheutils/cachescale"
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestSnapshot_ImportContinuesFromExport(t *testing.T) {
+	t.Run("lite", func(t *testing.T) {
+		testSnapshotImportContinuesFromExport(t, dagindexer.LiteConfig())
+	})
+	// the vectors are held by the cache of the DAG index, until it's written through on export
+	t.Run("default", func(t *testing.T) {
+		testSnapshotImportContinuesFromExport(t, dagindexer.DefaultConfig(cachescale.Identity))
+	})
+}
+
+// newSnapshotConsensus creates a bootstrapped engine, which delivers the blocks, with the DAG index config.
+func newSnapshotConsensus(nodes []consensus.ValidatorID, weights []consensus.Weight, indexConfig dagindexer.IndexConfig) (*CoreLachesis, *consensustest.TestEventSource) {
+	engine, _, input, _ := newCoreConsensus(nodes, weights, consensusstore.NewMemStore(), indexConfig)
+	lch := bootstrapCoreConsensus(engine)
+	lch.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		return nil
+	}
+	return lch, input
+}
+
+func testSnapshotImportContinuesFromExport(t *testing.T, indexConfig dagindexer.IndexConfig) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	weights := []consensus.Weight{1, 2, 3, 4, 5}
+	expected, input, _ := newDeliveringConsensus(nodes, weights)
+	var ordered consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandFork(nodes, nodes[:1], TestMaxEpochEvents, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			assertar.NoError(expected.Process(e))
+			ordered = append(ordered, e)
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return expected.Build(e)
+		},
+	})
+
+	lch, lchInput := newSnapshotConsensus(nodes, weights, indexConfig)
+	half := len(ordered) / 2
+	for _, e := range ordered[:half] {
+		lchInput.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+	}
+	if !assertar.NotZero(lch.store.GetLastDecidedFrame()) {
+		return
+	}
+	snapshot := &bytes.Buffer{}
+	exported, err := lch.store.ExportSnapshot(snapshot)
+	if !assertar.NoError(err) {
+		return
+	}
+	assertar.Equal(lch.store.GetLastDecidedFrame(), exported.LastDecidedFrame)
+
+	// a new node imports the snapshot and continues from it
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{}
+	getDB := func(epoch consensus.Epoch) kvdb.Store {
+		if epochDBs[epoch] == nil {
+			epochDBs[epoch] = memorydb.New()
+		}
+		return epochDBs[epoch]
+	}
+	imported, err := consensusstore.ImportSnapshot(snapshot, mainDB, getDB)
+	if !assertar.NoError(err) {
+		return
+	}
+	assertar.Equal(exported, imported)
+	store := consensusstore.NewStore(mainDB, getDB, nil, consensusstore.LiteStoreConfig())
+	callback := lch.callback
+	lch.IndexedLachesis = NewIndexedLachesis(store, lch.Input, dagindexer.NewIndex(lch.crit, indexConfig), lch.crit, lch.config)
+	assertar.NoError(lch.Bootstrap(callback))
+	assertar.Equal(exported.LastDecidedFrame, lch.store.GetLastDecidedFrame())
+
+	for _, e := range ordered[half:] {
+		lchInput.SetEvent(e)
+		assertar.NoError(lch.Process(e))
+	}
+	assertar.Equal(len(expected.blocks), len(lch.blocks))
+	for key, block := range expected.blocks {
+		if assertar.NotNil(lch.blocks[key]) {
+			assertar.Equal(block.Atropos, lch.blocks[key].Atropos)
+			assertar.Equal(block.Cheaters, lch.blocks[key].Cheaters)
+		}
+	}
+
+	// the states are equal
+	expectedInfo, err := expected.store.ExportSnapshot(io.Discard)
+	assertar.NoError(err)
+	info, err := lch.store.ExportSnapshot(io.Discard)
+	assertar.NoError(err)
+	assertar.Equal(expectedInfo, info)
+}
+
+func TestSnapshot_MemoryIndexBackendIsNotExported(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	indexConfig := dagindexer.LiteConfig()
+	indexConfig.Backend = dagindexer.MemoryBackend
+	lch, input := newSnapshotConsensus(nodes, nil, indexConfig)
+	r := consensustest.NewIntSeededRandGenerator(0)
+	consensustest.ForEachRandEvent(nodes, 50, 3, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			input.SetEvent(e)
+			assertar.NoError(lch.Process(e))
+		},
+		Build: func(e consensus.MutableEvent, name string) error {
+			e.SetEpoch(consensus.FirstEpoch)
+			return lch.Build(e)
+		},
+	})
+
+	// the epoch DB has no vectors
+	_, err := lch.store.ExportSnapshot(io.Discard)
+	assertar.ErrorIs(err, dagindexer.ErrNotPersisted)
+}
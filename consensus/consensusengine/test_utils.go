@@ -49,14 +49,27 @@ func NewBootstrappedCoreConsensus(
 	mods ...memorydb.Mod,
 ) (*CoreLachesis, *consensusstore.Store, *consensustest.TestEventSource, *dagindexer.Index) {
 	engine, store, eventSource, dagIndexer := NewCoreConsensus(nodes, weights)
+	return bootstrapCoreConsensus(engine), store, eventSource, dagIndexer
+}
 
+// bootstrapCoreConsensus bootstraps the engine with the callbacks, which track the blocks
+func bootstrapCoreConsensus(engine *IndexedLachesis) *CoreLachesis {
 	extended := &CoreLachesis{
 		IndexedLachesis: engine,
 		blocks:          map[BlockKey]*BlockResult{},
 		epochBlocks:     map[consensus.Epoch]consensus.Frame{},
 	}
 
-	if err := extended.Bootstrap(consensus.ConsensusCallbacks{
+	if err := extended.Bootstrap(extended.consensusCallbacks()); err != nil {
+		panic(err)
+	}
+
+	return extended
+}
+
+// consensusCallbacks tracks the blocks.
+func (extended *CoreLachesis) consensusCallbacks() consensus.ConsensusCallbacks {
+	return consensus.ConsensusCallbacks{
 		BeginBlock: func(block *consensus.Block) consensus.BlockCallbacks {
 			return consensus.BlockCallbacks{
 				EndBlock: func() (sealEpoch *consensus.Validators) {
@@ -83,17 +96,23 @@ func NewBootstrappedCoreConsensus(
 				},
 			}
 		},
-	}); err != nil {
-		panic(err)
 	}
-
-	return extended, store, eventSource, dagIndexer
 }
 
 // NewCoreConsensus creates a simple consensus engine with mem store and optional node weights
 func NewCoreConsensus(
 	nodes []consensus.ValidatorID,
 	weights []consensus.Weight,
+) (*IndexedLachesis, *consensusstore.Store, *consensustest.TestEventSource, *dagindexer.Index) {
+	return newCoreConsensus(nodes, weights, consensusstore.NewMemStore(), dagindexer.LiteConfig())
+}
+
+// newCoreConsensus creates a simple consensus engine over the blank store
+func newCoreConsensus(
+	nodes []consensus.ValidatorID,
+	weights []consensus.Weight,
+	store *consensusstore.Store,
+	indexConfig dagindexer.IndexConfig,
 ) (*IndexedLachesis, *consensusstore.Store, *consensustest.TestEventSource, *dagindexer.Index) {
 	validators := make(consensus.ValidatorsBuilder, len(nodes))
 	for i, v := range nodes {
@@ -103,8 +122,6 @@ func NewCoreConsensus(
 			validators[v] = weights[i]
 		}
 	}
-	store := consensusstore.NewMemStore()
-
 	err := store.ApplyGenesis(&consensusstore.Genesis{
 		Validators: validators.Build(),
 		Epoch:      consensus.FirstEpoch,
@@ -119,7 +136,7 @@ func NewCoreConsensus(
 	crit := func(err error) {
 		panic(err)
 	}
-	dagIndexer := dagindexer.NewIndex(crit, dagindexer.LiteConfig())
+	dagIndexer := dagindexer.NewIndex(crit, indexConfig)
 	return NewIndexedLachesis(store, input, dagIndexer, crit, config), store, input, dagIndexer
 }
 
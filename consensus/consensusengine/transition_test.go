@@ -0,0 +1,264 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensusstore"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+var errCrash = errors.New("crash")
+
+// crasher simulates a crash by panicking on the write, once the writes limit is over.
+// Only the writes of the journaled transitions are counted.
+type crasher struct {
+	writes int
+	store  *consensusstore.Store
+}
+
+func (c *crasher) write() {
+	if c.writes == 0 {
+		return
+	}
+	if t, err := c.store.GetTransition(); err != nil || t == nil {
+		return
+	}
+	c.writes--
+	if c.writes == 0 {
+		panic(errCrash)
+	}
+}
+
+// crashingDB is a kvdb.Store, which crashes on the writes counted by crasher. A batch is written atomically.
+type crashingDB struct {
+	kvdb.Store
+	crasher *crasher
+	drop    func()
+}
+
+func (db *crashingDB) Put(key []byte, value []byte) error {
+	db.crasher.write()
+	return db.Store.Put(key, value)
+}
+
+func (db *crashingDB) Delete(key []byte) error {
+	db.crasher.write()
+	return db.Store.Delete(key)
+}
+
+func (db *crashingDB) NewBatch() kvdb.Batch {
+	return &crashingBatch{db.Store.NewBatch(), db.crasher}
+}
+
+func (db *crashingDB) Close() error {
+	return nil
+}
+
+func (db *crashingDB) Drop() {
+	db.crasher.write()
+	db.drop()
+}
+
+type crashingBatch struct {
+	kvdb.Batch
+	crasher *crasher
+}
+
+func (b *crashingBatch) Write() error {
+	b.crasher.write()
+	return b.Batch.Write()
+}
+
+// crashingDBs are the databases of a node, which survive the crashes.
+type crashingDBs struct {
+	crasher crasher
+	main    kvdb.Store
+	epochs  map[consensus.Epoch]kvdb.Store
+}
+
+func (dbs *crashingDBs) newStore() *consensusstore.Store {
+	main := &crashingDB{Store: dbs.main, crasher: &dbs.crasher}
+	dbs.crasher.store = consensusstore.NewStore(main, func(epoch consensus.Epoch) kvdb.Store {
+		if dbs.epochs[epoch] == nil {
+			dbs.epochs[epoch] = memorydb.New()
+		}
+		return &crashingDB{
+			Store:   dbs.epochs[epoch],
+			crasher: &dbs.crasher,
+			drop: func() {
+				delete(dbs.epochs, epoch)
+			},
+		}
+	}, nil, consensusstore.LiteStoreConfig())
+	return dbs.crasher.store
+}
+
+func TestOrderer_CrashDuringTransitions(t *testing.T) {
+	assertar := assert.New(t)
+
+	const (
+		epochs    = 3
+		sealFrame = 4
+	)
+	nodes := consensustest.GenNodes(4)
+	weights := []consensus.Weight{1, 2, 3, 4}
+	sealOn := func(store *consensusstore.Store) *consensus.Validators {
+		if store.GetLastDecidedFrame()+1 == sealFrame {
+			return store.GetValidators()
+		}
+		return nil
+	}
+
+	generator, _, input, _ := NewBootstrappedCoreConsensus(nodes, weights)
+	generator.applyBlock = func(block *consensus.Block) *consensus.Validators {
+		return sealOn(generator.store)
+	}
+	var ordered consensus.Events
+	r := consensustest.NewIntSeededRandGenerator(0)
+	for epoch := consensus.Epoch(1); epoch <= epochs; epoch++ {
+		consensustest.ForEachRandFork(nodes, nodes[:1], 60, 3, 10, r, consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				input.SetEvent(e)
+				assertar.NoError(generator.Process(e))
+				ordered = append(ordered, e)
+			},
+			Build: func(e consensus.MutableEvent, name string) error {
+				if epoch != generator.store.GetEpoch() {
+					return errors.New("epoch already sealed, skip")
+				}
+				e.SetEpoch(epoch)
+				return generator.Build(e)
+			},
+		})
+	}
+	if !assertar.Equal(consensus.Epoch(epochs+1), generator.store.GetEpoch()) {
+		return
+	}
+	expectedBlocks := storedBlocks(t, generator.store)
+	crit := func(err error) {
+		panic(err)
+	}
+
+	// crash on every write of the transitions, until the events are processed without a crash
+	recovered := map[consensusstore.TransitionPhase]int{}
+	for crashAt := 1; ; crashAt++ {
+		dbs := &crashingDBs{
+			main:   memorydb.New(),
+			epochs: map[consensus.Epoch]kvdb.Store{},
+		}
+		store := dbs.newStore()
+		assertar.NoError(store.ApplyGenesis(&consensusstore.Genesis{
+			Epoch:      consensus.FirstEpoch,
+			Validators: generator.store.GetValidators(),
+		}))
+		// the app is crash-safe: the blocks are recorded idempotently
+		blocks := map[BlockKey]consensus.EventHash{}
+		var lch *IndexedLachesis
+		start := func() {
+			lch = NewIndexedLachesis(store, input, dagindexer.NewIndex(crit, dagindexer.LiteConfig()), crit, DefaultConfig())
+			assertar.NoError(lch.Bootstrap(consensus.ConsensusCallbacks{
+				BeginBlock: func(block *consensus.Block) consensus.BlockCallbacks {
+					return consensus.BlockCallbacks{
+						EndBlock: func() *consensus.Validators {
+							blocks[BlockKey{lch.store.GetEpoch(), lch.store.GetLastDecidedFrame() + 1}] = block.Atropos
+							return sealOn(lch.store)
+						},
+					}
+				},
+			}))
+		}
+		start()
+		dbs.crasher.writes = crashAt
+
+		crashed := false
+		for _, e := range ordered {
+			if e.Epoch() != lch.store.GetEpoch() {
+				continue
+			}
+			if processCrashing(lch, e) {
+				// the event is already indexed and saved as a root, Bootstrap replays its election
+				crashed = true
+				store = dbs.newStore()
+				transition, err := store.GetTransition()
+				assertar.NoError(err)
+				if assertar.NotNil(transition, "crash at write %d", crashAt) {
+					recovered[transition.Phase]++
+				}
+				start()
+			}
+		}
+		if t.Failed() {
+			return
+		}
+
+		transition, err := store.GetTransition()
+		assertar.NoError(err)
+		assertar.Nil(transition, "crash at write %d", crashAt)
+		assertar.Equal(generator.store.GetEpoch(), store.GetEpoch(), "crash at write %d", crashAt)
+		assertar.Equal(generator.store.GetValidators().String(), store.GetValidators().String(), "crash at write %d", crashAt)
+		assertar.Equal(generator.store.GetLastDecidedFrame(), store.GetLastDecidedFrame(), "crash at write %d", crashAt)
+		assertar.Equal(expectedBlocks, storedBlocks(t, store), "crash at write %d", crashAt)
+		assertar.Equal(len(generator.blocks), len(blocks), "crash at write %d", crashAt)
+		for key, block := range generator.blocks {
+			assertar.Equal(block.Atropos, blocks[key], "crash at write %d", crashAt)
+		}
+		// the DBs of the sealed epochs are dropped
+		assertar.Len(dbs.epochs, 1, "crash at write %d", crashAt)
+		if t.Failed() {
+			return
+		}
+		if !crashed {
+			break
+		}
+	}
+	// the transitions are interrupted at every phase
+	assertar.NotZero(recovered[consensusstore.TransitionApplying])
+	assertar.NotZero(recovered[consensusstore.TransitionSealing])
+	assertar.NotZero(recovered[consensusstore.TransitionEpochDropped])
+}
+
+// processCrashing processes the event, returns true if the processing crashed.
+func processCrashing(lch *IndexedLachesis, e consensus.Event) (crashed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errCrash {
+				panic(r)
+			}
+			crashed = true
+		}
+	}()
+	if err := lch.Process(e); err != nil {
+		panic(err)
+	}
+	return false
+}
+
+func storedBlocks(t *testing.T, store *consensusstore.Store) map[consensus.BlockID]consensusstore.BlockRecord {
+	t.Helper()
+	blocks := map[consensus.BlockID]consensusstore.BlockRecord{}
+	err := store.ForEachBlock(consensus.FirstEpoch, store.GetEpoch(), func(n consensus.BlockID, block *consensusstore.BlockRecord) bool {
+		blocks[n] = *block
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks
+}
@@ -11,12 +11,10 @@
 package consensusengine
 
 import (
-	"errors"
-
 	"github.com/0xsoniclabs/consensus/consensus"
 )
 
-type eventFilterFn func(event consensus.Event) bool
+type eventFilterFn func(event consensus.Event) (bool, error)
 
 // dfsSubgraph iterates all the events which are observed by head, and accepted by a filter.
 // filter MAY BE called twice for the same event.
@@ -26,13 +24,17 @@ func (p *Orderer) dfsSubgraph(head consensus.EventHash, filter eventFilterFn) er
 	for pwalk := &head; pwalk != nil; pwalk = stack.Pop() {
 		walk := *pwalk
 
-		event := p.Input.GetEvent(walk)
-		if event == nil {
-			return errors.New("event not found " + walk.String())
+		event, err := p.getEvent(walk)
+		if err != nil {
+			return err
 		}
 
 		// filter
-		if !filter(event) {
+		godeeper, err := filter(event)
+		if err != nil {
+			return err
+		}
+		if !godeeper {
 			continue
 		}
 
@@ -1,6 +1,7 @@
 package consensusengine
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/0xsoniclabs/consensus/consensus"
@@ -16,8 +17,8 @@ func TestTraversal_EventNotFound(t *testing.T) {
 	childEvent.SetParents(consensus.EventHashes{parentEvent.ID()})
 	eventSource.SetEvent(childEvent)
 
-	if err := engine.dfsSubgraph(consensus.EventHash{0}, func(event consensus.Event) bool { return true }); err == nil {
-		t.Fatal("expected event not found error but recieved none")
+	if err := engine.dfsSubgraph(consensus.EventHash{0}, func(event consensus.Event) (bool, error) { return true, nil }); !errors.Is(err, consensus.ErrEventNotFound) {
+		t.Fatalf("expected event not found error but recieved: %v", err)
 	}
 }
 
@@ -39,13 +40,13 @@ func TestTraversal_CorrectEventsVisitedMultipleTraversals(t *testing.T) {
 	firstAnchor := events[nodes[0]][len(events[nodes[0]])/2]
 	alreadyVisitedEvents := consensus.EventHashSet{}
 	numVisited := 0
-	filterFn := func(event consensus.Event) bool {
+	filterFn := func(event consensus.Event) (bool, error) {
 		if alreadyVisitedEvents.Contains(event.ID()) {
-			return false
+			return false, nil
 		}
 		alreadyVisitedEvents.Add(event.ID())
 		numVisited++
-		return true
+		return true, nil
 	}
 	// first traversal stops at genesis events
 	if err := engine.dfsSubgraph(firstAnchor.ID(), filterFn); err != nil {
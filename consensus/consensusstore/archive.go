@@ -0,0 +1,266 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+// EpochArchive retains the DBs of sealed epochs, instead of dropping them, see StoreConfig.Archive.
+// Implementations must be safe for concurrent use, as views may be opened during processing.
+type EpochArchive interface {
+	// Archive takes over the DB of the sealed epoch.
+	Archive(epoch consensus.Epoch, db kvdb.Store) error
+	// Get returns the DB of the archived epoch, or nil if it isn't retained.
+	// The DB is owned by the archive and must not be modified.
+	Get(epoch consensus.Epoch) (kvdb.Store, error)
+	// Epochs returns the epochs, which are reopened by Restore after a restart.
+	Epochs() []consensus.Epoch
+	// Restore reopens the epochs retained before a restart, with the DB producer of the store.
+	Restore(epochs []consensus.Epoch, open EpochDBProducer) error
+	// Close closes the retained DBs.
+	Close() error
+}
+
+const (
+	archivedEpochsKey = "a"
+	// archivedEpochsPrefix is the table prefix of mainTables.ArchivedEpochs, the archive isn't a part of snapshots
+	archivedEpochsPrefix = "A"
+)
+
+// saveArchivedEpochs persists the epochs retained by the archive, so they're restored after a restart.
+func (s *Store) saveArchivedEpochs() error {
+	return s.set(s.table.ArchivedEpochs, []byte(archivedEpochsKey), s.cfg.Archive.Epochs())
+}
+
+// isArchived returns true if the epoch is in the persisted list of the epochs retained by the archive.
+func (s *Store) isArchived(epoch consensus.Epoch) (bool, error) {
+	if s.cfg.Archive == nil {
+		return false, nil
+	}
+	var epochs []consensus.Epoch
+	if _, err := s.get(s.table.ArchivedEpochs, []byte(archivedEpochsKey), &epochs); err != nil {
+		return false, err
+	}
+	return slices.Contains(epochs, epoch), nil
+}
+
+// restoreArchive reopens the epochs retained by the archive before a restart.
+func (s *Store) restoreArchive() error {
+	var epochs []consensus.Epoch
+	if _, err := s.get(s.table.ArchivedEpochs, []byte(archivedEpochsKey), &epochs); err != nil {
+		return err
+	}
+	if err := s.cfg.Archive.Restore(epochs, s.GetEpochDB); err != nil {
+		return s.ioErr(err)
+	}
+	// the archive may drop the oldest epochs on Restore
+	return s.saveArchivedEpochs()
+}
+
+// keepingArchive retains the open DBs of the last epochs.
+type keepingArchive struct {
+	mu     sync.Mutex
+	keep   int
+	epochs []consensus.Epoch
+	dbs    map[consensus.Epoch]kvdb.Store
+}
+
+// KeepLastEpochs retains the DBs of the last n sealed epochs, older ones are dropped.
+// The DBs are kept open, and are reopened with the EpochDBProducer of the store after a restart.
+func KeepLastEpochs(n int) EpochArchive {
+	return &keepingArchive{
+		keep: n,
+		dbs:  make(map[consensus.Epoch]kvdb.Store),
+	}
+}
+
+// KeepAllEpochs retains the DBs of all the sealed epochs, see KeepLastEpochs.
+func KeepAllEpochs() EpochArchive {
+	return KeepLastEpochs(0)
+}
+
+func (a *keepingArchive) Archive(epoch consensus.Epoch, db kvdb.Store) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if prev, ok := a.dbs[epoch]; ok {
+		if err := closeAndDrop(prev); err != nil {
+			return err
+		}
+		a.epochs = slices.DeleteFunc(a.epochs, func(e consensus.Epoch) bool { return e == epoch })
+	}
+	a.dbs[epoch] = db
+	a.epochs = append(a.epochs, epoch)
+	return a.dropOldest()
+}
+
+// dropOldest drops the epochs beyond the limit.
+func (a *keepingArchive) dropOldest() error {
+	for a.keep != 0 && len(a.epochs) > a.keep {
+		oldest := a.epochs[0]
+		a.epochs = a.epochs[1:]
+		if err := closeAndDrop(a.dbs[oldest]); err != nil {
+			return err
+		}
+		delete(a.dbs, oldest)
+	}
+	return nil
+}
+
+func (a *keepingArchive) Epochs() []consensus.Epoch {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.epochs)
+}
+
+func (a *keepingArchive) Restore(epochs []consensus.Epoch, open EpochDBProducer) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, epoch := range epochs {
+		if _, ok := a.dbs[epoch]; ok {
+			continue
+		}
+		db := open(epoch)
+		if db == nil {
+			continue
+		}
+		a.dbs[epoch] = db
+		a.epochs = append(a.epochs, epoch)
+	}
+	// the limit may be lowered since the restart
+	return a.dropOldest()
+}
+
+func (a *keepingArchive) Get(epoch consensus.Epoch) (kvdb.Store, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dbs[epoch], nil
+}
+
+func (a *keepingArchive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for _, epoch := range a.epochs {
+		errs = append(errs, a.dbs[epoch].Close())
+	}
+	a.epochs = nil
+	clear(a.dbs)
+	return errors.Join(errs...)
+}
+
+// coldArchive copies the DBs of sealed epochs to cold storage.
+type coldArchive struct {
+	mu   sync.Mutex
+	cold EpochDBProducer
+	dbs  map[consensus.Epoch]kvdb.Store
+}
+
+// MoveToColdStorage copies the DBs of sealed epochs into the DBs produced by cold, and drops the originals.
+// cold is called once per epoch, on Archive or on the first Get after a restart, so it may reopen an existing DB.
+func MoveToColdStorage(cold EpochDBProducer) EpochArchive {
+	return &coldArchive{
+		cold: cold,
+		dbs:  make(map[consensus.Epoch]kvdb.Store),
+	}
+}
+
+func (a *coldArchive) Archive(epoch consensus.Epoch, db kvdb.Store) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	dst, err := a.open(epoch)
+	if err != nil {
+		return err
+	}
+	if dst == nil {
+		return fmt.Errorf("%w: no cold storage for epoch %d", consensus.ErrStorageIO, epoch)
+	}
+	if err := copyDB(dst, db); err != nil {
+		return err
+	}
+	return closeAndDrop(db)
+}
+
+// Epochs returns nil, as the cold DBs are reopened on Get.
+func (a *coldArchive) Epochs() []consensus.Epoch {
+	return nil
+}
+
+func (a *coldArchive) Restore([]consensus.Epoch, EpochDBProducer) error {
+	return nil
+}
+
+func (a *coldArchive) Get(epoch consensus.Epoch) (kvdb.Store, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.open(epoch)
+}
+
+func (a *coldArchive) open(epoch consensus.Epoch) (kvdb.Store, error) {
+	if db, ok := a.dbs[epoch]; ok {
+		return db, nil
+	}
+	db := a.cold(epoch)
+	if db != nil {
+		a.dbs[epoch] = db
+	}
+	return db, nil
+}
+
+func (a *coldArchive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for _, db := range a.dbs {
+		errs = append(errs, db.Close())
+	}
+	clear(a.dbs)
+	return errors.Join(errs...)
+}
+
+// copyDB copies all the records of src into dst in a batch.
+func copyDB(dst, src kvdb.Store) error {
+	batch := dst.NewBatch()
+	it := src.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+		}
+		if batch.ValueSize() > kvdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+			}
+			batch.Reset()
+		}
+	}
+	if it.Error() != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, it.Error())
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return nil
+}
+
+func closeAndDrop(db kvdb.Store) error {
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	db.Drop()
+	return nil
+}
@@ -0,0 +1,156 @@
+package consensusstore
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// testArchiveEpochs seals the epochs [1, epochs] of a store with the archive,
+// each epoch has a root, confirmed in frame equal to the epoch.
+func testArchiveEpochs(t *testing.T, archive EpochArchive, epochs consensus.Epoch) (*Store, map[consensus.Epoch]consensus.Event) {
+	cfg := LiteStoreConfig()
+	cfg.Archive = archive
+	// the epoch DBs are reopened after a restart
+	dbs := map[consensus.Epoch]kvdb.Store{}
+	store := NewStore(memorydb.New(), func(epoch consensus.Epoch) kvdb.Store {
+		if dbs[epoch] == nil {
+			dbs[epoch] = memorydb.New()
+		}
+		return dbs[epoch]
+	}, nil, cfg)
+
+	roots := map[consensus.Epoch]consensus.Event{}
+	for epoch := consensus.Epoch(1); epoch <= epochs+1; epoch++ {
+		if err := store.DropEpochDB(); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.OpenEpochDB(epoch); err != nil {
+			t.Fatal(err)
+		}
+		root := &consensustest.TestEvent{}
+		root.SetEpoch(epoch)
+		root.SetFrame(consensus.FirstFrame)
+		root.SetCreator(consensus.ValidatorID(epoch))
+		root.SetID([24]byte{byte(epoch)})
+		if err := store.AddRoot(root); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetEventConfirmedOn(root.ID(), consensus.Frame(epoch)); err != nil {
+			t.Fatal(err)
+		}
+		roots[epoch] = root
+	}
+	return store, roots
+}
+
+func testArchivedEpoch(t *testing.T, store *Store, epoch consensus.Epoch, root consensus.Event) {
+	t.Helper()
+	view, err := store.OpenEpochView(epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Epoch() != epoch {
+		t.Fatalf("unexpected view epoch, want: %d, got: %d", epoch, view.Epoch())
+	}
+	roots, err := view.GetFrameRoots(consensus.FirstFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].RootHash != root.ID() || roots[0].ValidatorID != root.Creator() {
+		t.Fatalf("unexpected roots of epoch %d: %v", epoch, roots)
+	}
+	frame, err := view.GetEventConfirmedOn(root.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != consensus.Frame(epoch) {
+		t.Fatalf("unexpected confirmation frame of epoch %d: %d", epoch, frame)
+	}
+	if err := view.table.Roots.Put([]byte{1}, []byte{}); !errors.Is(err, kvdb.ErrUnsupportedOp) {
+		t.Fatalf("view isn't read-only: %v", err)
+	}
+}
+
+func TestArchive_KeepLastEpochs(t *testing.T) {
+	store, roots := testArchiveEpochs(t, KeepLastEpochs(2), 4)
+
+	for _, epoch := range []consensus.Epoch{1, 2, 5} {
+		if _, err := store.OpenEpochView(epoch); !errors.Is(err, ErrEpochNotArchived) {
+			t.Fatalf("unexpected view of epoch %d: %v", epoch, err)
+		}
+	}
+	for _, epoch := range []consensus.Epoch{3, 4} {
+		testArchivedEpoch(t, store, epoch, roots[epoch])
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchive_KeepLastEpochsRestart(t *testing.T) {
+	store, roots := testArchiveEpochs(t, KeepLastEpochs(3), 4)
+
+	// the archived epochs are reopened by a new instance
+	cfg := LiteStoreConfig()
+	cfg.Archive = KeepLastEpochs(3)
+	restarted, err := OpenStore(store.MainDB, store.GetEpochDB, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []consensus.Epoch{2, 3, 4}, cfg.Archive.Epochs(); !slices.Equal(want, got) {
+		t.Fatalf("unexpected archived epochs, want: %v, got: %v", want, got)
+	}
+	for _, epoch := range []consensus.Epoch{2, 3, 4} {
+		testArchivedEpoch(t, restarted, epoch, roots[epoch])
+	}
+
+	// the lowered limit drops the oldest epochs
+	cfg.Archive = KeepLastEpochs(1)
+	restarted, err = OpenStore(store.MainDB, store.GetEpochDB, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.OpenEpochView(3); !errors.Is(err, ErrEpochNotArchived) {
+		t.Fatalf("unexpected view of epoch 3: %v", err)
+	}
+	testArchivedEpoch(t, restarted, 4, roots[4])
+}
+
+func TestArchive_KeepAllEpochs(t *testing.T) {
+	store, roots := testArchiveEpochs(t, KeepAllEpochs(), 4)
+
+	for epoch := consensus.Epoch(1); epoch <= 4; epoch++ {
+		testArchivedEpoch(t, store, epoch, roots[epoch])
+	}
+}
+
+func TestArchive_MoveToColdStorage(t *testing.T) {
+	cold := map[consensus.Epoch]kvdb.Store{}
+	archive := MoveToColdStorage(func(epoch consensus.Epoch) kvdb.Store {
+		if cold[epoch] == nil {
+			cold[epoch] = memorydb.New()
+		}
+		return cold[epoch]
+	})
+	store, roots := testArchiveEpochs(t, archive, 3)
+
+	for epoch := consensus.Epoch(1); epoch <= 3; epoch++ {
+		testArchivedEpoch(t, store, epoch, roots[epoch])
+	}
+	if _, ok := cold[4]; ok {
+		t.Fatal("the current epoch is moved to cold storage")
+	}
+}
+
+func TestArchive_Disabled(t *testing.T) {
+	store := NewMemStore()
+	if _, err := store.OpenEpochView(1); !errors.Is(err, ErrEpochNotArchived) {
+		t.Fatalf("unexpected view: %v", err)
+	}
+}
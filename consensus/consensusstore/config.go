@@ -1,6 +1,10 @@
 package consensusstore
 
-import "github.com/0xsoniclabs/cacheutils/cachescale"
+import (
+	"github.com/0xsoniclabs/cacheutils/cachescale"
+
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
+)
 
 // StoreCacheConfig is a cache config for store db.
 type StoreCacheConfig struct {
@@ -12,12 +16,18 @@ type StoreCacheConfig struct {
 // StoreConfig is a config for store db.
 type StoreConfig struct {
 	Cache StoreCacheConfig
+	// Metrics receives the store metrics, optional
+	Metrics metrics.Registry
+	// Archive retains the DBs of sealed epochs, optional. The DBs are dropped if it's nil
+	Archive EpochArchive
+	// MigrationProgress receives the progress of the DB schema migrations, optional
+	MigrationProgress func(MigrationProgress)
 }
 
 // DefaultStoreConfig for livenet.
 func DefaultStoreConfig(scale cachescale.Func) StoreConfig {
 	return StoreConfig{
-		StoreCacheConfig{
+		Cache: StoreCacheConfig{
 			RootsNum:    scale.U(1000),
 			RootsFrames: scale.I(100),
 		},
@@ -0,0 +1,530 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/readonlystore"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// The checks of Fsck.
+const (
+	CheckSchema           = "schema"
+	CheckLastDecidedState = "last-decided-state"
+	CheckEpochState       = "epoch-state"
+	CheckTransition       = "transition"
+	CheckConfirmedEvents  = "confirmed-events"
+	CheckBranchesInfo     = "branches-info"
+	CheckEventBranches    = "event-branches"
+	CheckVectors          = "vectors"
+	CheckRoots            = "roots"
+	CheckForklessCause    = "forkless-cause"
+)
+
+// FsckConfig configures Fsck.
+type FsckConfig struct {
+	// ForklessCauseSamples is the number of the roots, whose frames are verified by recalculating ForklessCause
+	ForklessCauseSamples int
+	// Seed of the roots sampling
+	Seed uint64
+	// GetEvent returns the event, or nil if it's unknown, optional.
+	// If it's nil, the roots are checked against the DAG index.
+	GetEvent func(consensus.EventHash) consensus.Event
+	// IndexPersisted is set if the DAG index is written through to the epoch DB, e.g. with the zero
+	// dagindexer.IndexCacheConfig.DBCache, or by dagindexer.Index.Persist. Otherwise the vectors held by
+	// the cache of the index are missing from the DB, so the records missing them are counted as unverifiable.
+	IndexPersisted bool
+}
+
+// FsckViolation is a violated invariant of the consensus DBs.
+type FsckViolation struct {
+	// Check is the violated check, see CheckRoots and others
+	Check string `json:"check"`
+	// Key is the key of the inconsistent record in its table, if any
+	Key     hexutil.Bytes `json:"key,omitempty"`
+	Message string        `json:"message"`
+}
+
+// FsckReport is the outcome of Fsck.
+type FsckReport struct {
+	Epoch            consensus.Epoch `json:"epoch"`
+	LastDecidedFrame consensus.Frame `json:"lastDecidedFrame"`
+	// Checked is the number of the checked records by check
+	Checked    map[string]int  `json:"checked"`
+	Violations []FsckViolation `json:"violations"`
+	// Skipped are the checks, which couldn't be run, with the reasons
+	Skipped []string `json:"skipped,omitempty"`
+	// Unverifiable is the number of the records by check, which couldn't be verified, see FsckConfig.IndexPersisted
+	Unverifiable map[string]int `json:"unverifiable,omitempty"`
+}
+
+// OK returns true if no violations are found.
+func (r *FsckReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// fsck is the state of a Fsck run.
+type fsck struct {
+	cfg    FsckConfig
+	store  *Store
+	report *FsckReport
+
+	lastDecided  *LastDecidedState
+	epochState   *EpochState
+	transition   *Transition
+	vectors      *dagindexer.VectorsView
+	hasForks     bool
+	prunedBefore consensus.Lamport
+	prunedFrame  consensus.Frame
+	roots        map[consensus.Frame][]RootDescriptor
+}
+
+// Fsck validates the invariants of the main DB and the DB of its current epoch, without modifying them.
+// The violations are reported, while errors are returned only for failures of the DBs.
+// The DBs must not be used by a running store.
+func Fsck(mainDB, epochDB kvdb.Store, cfg FsckConfig) (*FsckReport, error) {
+	c := &fsck{
+		cfg:   cfg,
+		store: &Store{cfg: LiteStoreConfig()},
+		report: &FsckReport{
+			Checked:      map[string]int{},
+			Violations:   []FsckViolation{},
+			Unverifiable: map[string]int{},
+		},
+		roots: map[consensus.Frame][]RootDescriptor{},
+	}
+	c.store.initMetrics()
+	table.MigrateTables(&c.store.table, readonlystore.Wrap(mainDB))
+	table.MigrateTables(&c.store.EpochTable, readonlystore.Wrap(epochDB))
+
+	for _, check := range []func() error{
+		c.checkSchema,
+		c.checkStates,
+		c.checkConfirmedEvents,
+		c.checkVectors,
+		c.checkRoots,
+		c.checkForklessCause,
+	} {
+		if err := check(); err != nil {
+			return nil, err
+		}
+	}
+	return c.report, nil
+}
+
+func (c *fsck) violation(check string, key []byte, format string, args ...any) {
+	c.report.Violations = append(c.report.Violations, FsckViolation{
+		Check:   check,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// missingVectors reports the record, whose vectors are missing from the DAG index, as a violation,
+// or as unverifiable if the index may be held by its cache, see FsckConfig.IndexPersisted.
+func (c *fsck) missingVectors(check string, key []byte, format string, args ...any) {
+	if !c.cfg.IndexPersisted {
+		c.report.Unverifiable[check]++
+		return
+	}
+	c.violation(check, key, format, args...)
+}
+
+func (c *fsck) skip(check string, reason string) {
+	c.report.Skipped = append(c.report.Skipped, check+": "+reason)
+}
+
+// inconsistent reports err as a violation if it's an inconsistency, or returns it otherwise.
+func (c *fsck) inconsistent(check string, key []byte, err error) error {
+	if errors.Is(err, consensus.ErrInconsistentDB) {
+		c.violation(check, key, "%v", err)
+		return nil
+	}
+	return err
+}
+
+func (c *fsck) checkSchema() error {
+	for _, db := range []struct {
+		name   string
+		table  kvdb.Store
+		latest uint32
+	}{
+		{"main", c.store.table.Schema, MainSchemaVersion()},
+		{"epoch", c.store.EpochTable.Schema, EpochSchemaVersion()},
+	} {
+		c.report.Checked[CheckSchema]++
+		version, stored, err := c.store.getSchemaVersion(db.table)
+		if err != nil {
+			if err := c.inconsistent(CheckSchema, []byte(schemaVersionKey), err); err != nil {
+				return err
+			}
+			continue
+		}
+		if stored && version > db.latest {
+			c.violation(CheckSchema, []byte(schemaVersionKey), "%s DB has version %d, the latest known is %d", db.name, version, db.latest)
+		}
+	}
+	return nil
+}
+
+func (c *fsck) checkStates() error {
+	c.report.Checked[CheckLastDecidedState]++
+	ds, err := c.store.get(c.store.table.LastDecidedState, []byte(dsKey), &LastDecidedState{})
+	if err != nil {
+		if err := c.inconsistent(CheckLastDecidedState, []byte(dsKey), err); err != nil {
+			return err
+		}
+	} else if ds == nil {
+		c.violation(CheckLastDecidedState, []byte(dsKey), "missing")
+	} else {
+		c.lastDecided = ds.(*LastDecidedState)
+		c.report.LastDecidedFrame = c.lastDecided.LastDecidedFrame
+	}
+
+	c.report.Checked[CheckEpochState]++
+	es, err := c.store.getEpochState([]byte(esKey))
+	if err != nil {
+		return c.inconsistent(CheckEpochState, []byte(esKey), err)
+	}
+	if es == nil {
+		c.violation(CheckEpochState, []byte(esKey), "missing")
+		return nil
+	}
+	if es.Validators == nil || es.Validators.Len() == 0 {
+		c.violation(CheckEpochState, []byte(esKey), "no validators in epoch %d", es.Epoch)
+		return nil
+	}
+	c.epochState = es
+	c.report.Epoch = es.Epoch
+	return c.checkTransition()
+}
+
+// checkTransition checks the transition interrupted by a crash, which is recovered on bootstrap.
+func (c *fsck) checkTransition() error {
+	t, err := c.store.GetTransition()
+	if err != nil {
+		return c.inconsistent(CheckTransition, []byte(transitionKey), err)
+	}
+	if t == nil {
+		return nil
+	}
+	c.report.Checked[CheckTransition]++
+	switch t.Phase {
+	case TransitionApplying:
+		if t.Epoch != c.epochState.Epoch {
+			c.violation(CheckTransition, []byte(transitionKey), "decision of epoch %d is applied in epoch %d", t.Epoch, c.epochState.Epoch)
+		} else if c.lastDecided != nil && t.Frame != c.lastDecided.LastDecidedFrame+1 {
+			c.violation(CheckTransition, []byte(transitionKey), "decision of frame %d is applied after the last decided frame %d", t.Frame, c.lastDecided.LastDecidedFrame)
+		} else {
+			c.transition = t
+		}
+	case TransitionRewinding:
+		if t.Epoch != c.epochState.Epoch {
+			c.violation(CheckTransition, []byte(transitionKey), "rewind of epoch %d is interrupted in epoch %d", t.Epoch, c.epochState.Epoch)
+		} else if c.lastDecided != nil && t.Frame != c.lastDecided.LastDecidedFrame {
+			c.violation(CheckTransition, []byte(transitionKey), "rewind to frame %d is interrupted at the last decided frame %d", t.Frame, c.lastDecided.LastDecidedFrame)
+		} else {
+			c.transition = t
+		}
+	case TransitionSealing, TransitionEpochDropped:
+		if t.Epoch+1 != c.epochState.Epoch {
+			c.violation(CheckTransition, []byte(transitionKey), "seal of epoch %d is interrupted in epoch %d", t.Epoch, c.epochState.Epoch)
+		}
+	default:
+		c.violation(CheckTransition, []byte(transitionKey), "unknown phase %d", t.Phase)
+	}
+	return nil
+}
+
+func (c *fsck) checkConfirmedEvents() error {
+	it := c.store.EpochTable.ConfirmedEvent.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		c.report.Checked[CheckConfirmedEvents]++
+		key := it.Key()
+		if len(key) != eventIDSize || len(it.Value()) != frameSize {
+			c.violation(CheckConfirmedEvents, key, "malformed record, key len=%d, value len=%d", len(key), len(it.Value()))
+			continue
+		}
+		id := consensus.BytesToEvent(key)
+		frame := consensus.BytesToFrame(it.Value())
+		if c.epochState != nil && id.Epoch() != c.epochState.Epoch {
+			c.violation(CheckConfirmedEvents, key, "event %s of epoch %d in epoch %d", id, id.Epoch(), c.epochState.Epoch)
+		}
+		if frame == 0 {
+			c.violation(CheckConfirmedEvents, key, "event %s is confirmed on frame 0", id)
+		}
+		// the confirmations of the interrupted decision or rewind are deleted on bootstrap
+		rolledBack := c.transition != nil && (c.transition.Phase == TransitionRewinding || frame == c.transition.Frame)
+		if c.lastDecided != nil && frame > c.lastDecided.LastDecidedFrame && !rolledBack {
+			c.violation(CheckConfirmedEvents, key, "event %s is confirmed on frame %d after the last decided frame %d", id, frame, c.lastDecided.LastDecidedFrame)
+		}
+	}
+	if it.Error() != nil {
+		return c.store.ioErr(it.Error())
+	}
+	return nil
+}
+
+func (c *fsck) checkVectors() error {
+	if c.epochState == nil {
+		c.skip(CheckVectors, "no epoch state")
+		return nil
+	}
+	validators := c.epochState.Validators
+	c.vectors = dagindexer.NewVectorsView(c.store.EpochTable.VectorIndex)
+
+	prunedBefore, err := c.vectors.GetPrunedBefore()
+	if err != nil {
+		return err
+	}
+	c.prunedBefore = prunedBefore
+
+	c.report.Checked[CheckBranchesInfo]++
+	branches := int(validators.Len())
+	info, err := c.vectors.GetBranchesInfo()
+	if err != nil {
+		return c.inconsistent(CheckBranchesInfo, nil, err)
+	}
+	if info != nil {
+		branches = len(info.BranchIDCreatorIdxs)
+		c.hasForks = branches > int(validators.Len())
+		if len(info.BranchIDLastSeq) != branches {
+			c.violation(CheckBranchesInfo, nil, "%d last seqs of %d branches", len(info.BranchIDLastSeq), branches)
+		}
+		if branches < int(validators.Len()) {
+			c.violation(CheckBranchesInfo, nil, "%d branches of %d validators", branches, validators.Len())
+		}
+		if len(info.BranchIDByCreators) != int(validators.Len()) {
+			c.violation(CheckBranchesInfo, nil, "branches of %d creators, but %d validators", len(info.BranchIDByCreators), validators.Len())
+		}
+		for branchID, creatorIdx := range info.BranchIDCreatorIdxs {
+			if creatorIdx >= validators.Len() {
+				c.violation(CheckBranchesInfo, nil, "branch %d of unknown validator index %d", branchID, creatorIdx)
+			}
+		}
+		for creatorIdx, branchIDs := range info.BranchIDByCreators {
+			for _, branchID := range branchIDs {
+				if int(branchID) >= branches {
+					c.violation(CheckBranchesInfo, nil, "unknown branch %d of validator index %d", branchID, creatorIdx)
+				} else if info.BranchIDCreatorIdxs[branchID] != consensus.ValidatorIndex(creatorIdx) {
+					c.violation(CheckBranchesInfo, nil, "branch %d of validator index %d belongs to %d", branchID, creatorIdx, info.BranchIDCreatorIdxs[branchID])
+				}
+			}
+		}
+	}
+
+	// vectors are created with the number of branches known at the time, so they may be shorter than it
+	validSize := func(size int) bool {
+		return size >= int(validators.Len()) && size <= branches
+	}
+	var ioErr error
+	err = c.vectors.ForEachEvent(func(id consensus.EventHash, branchID consensus.ValidatorIndex) bool {
+		c.report.Checked[CheckEventBranches]++
+		if int(branchID) >= branches {
+			c.violation(CheckEventBranches, id.Bytes(), "event %s has branch %d of %d", id, branchID, branches)
+		}
+		if id.Epoch() != c.epochState.Epoch {
+			c.violation(CheckEventBranches, id.Bytes(), "event %s of epoch %d in epoch %d", id, id.Epoch(), c.epochState.Epoch)
+		}
+
+		c.report.Checked[CheckVectors]++
+		before, err := c.vectors.GetHighestBefore(id)
+		if err != nil {
+			ioErr = err
+			return false
+		}
+		// LowestAfter vectors are completed on read by the index, so only HighestBefore is checked, see dagindexer.VectorsView
+		if before == nil {
+			c.missingVectors(CheckVectors, id.Bytes(), "vectors of event %s are missing", id)
+			return true
+		}
+		seqLen, timeLen := len(*before.VSeq), len(*before.VTime)
+		if seqLen%8 != 0 || timeLen%8 != 0 || seqLen != timeLen {
+			c.violation(CheckVectors, id.Bytes(), "vectors of event %s have malformed lengths %d/%d", id, seqLen, timeLen)
+			return true
+		}
+		if !validSize(seqLen / 8) {
+			c.violation(CheckVectors, id.Bytes(), "vectors of event %s have %d branches, but %d validators and %d branches", id, seqLen/8, validators.Len(), branches)
+		}
+		return true
+	})
+	if ioErr != nil {
+		return ioErr
+	}
+	if err != nil {
+		return c.inconsistent(CheckEventBranches, nil, err)
+	}
+	return nil
+}
+
+func (c *fsck) checkRoots() error {
+	prunedFrame := consensus.Frame(0)
+	if _, err := c.store.get(c.store.EpochTable.PruningState, []byte(prunedFrameKey), &prunedFrame); err != nil {
+		if err := c.inconsistent(CheckRoots, []byte(prunedFrameKey), err); err != nil {
+			return err
+		}
+	}
+	c.prunedFrame = prunedFrame
+
+	it := c.store.EpochTable.Roots.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		c.report.Checked[CheckRoots]++
+		key := it.Key()
+		if len(key) != frameSize+validatorIDSize+eventIDSize {
+			c.violation(CheckRoots, key, "incorrect key len=%d", len(key))
+			continue
+		}
+		frame := consensus.BytesToFrame(key[:frameSize])
+		root := RootDescriptor{
+			ValidatorID: consensus.BytesToValidatorID(key[frameSize : frameSize+validatorIDSize]),
+			RootHash:    consensus.BytesToEvent(key[frameSize+validatorIDSize:]),
+		}
+		if frame == 0 {
+			c.violation(CheckRoots, key, "root %s of frame 0", root.RootHash)
+		}
+		if frame <= c.prunedFrame {
+			c.violation(CheckRoots, key, "root %s of frame %d, which is pruned up to %d", root.RootHash, frame, c.prunedFrame)
+		}
+		if c.epochState != nil {
+			if root.RootHash.Epoch() != c.epochState.Epoch {
+				c.violation(CheckRoots, key, "root %s of epoch %d in epoch %d", root.RootHash, root.RootHash.Epoch(), c.epochState.Epoch)
+			}
+			if !c.epochState.Validators.Exists(root.ValidatorID) {
+				c.violation(CheckRoots, key, "root %s of unknown validator %d", root.RootHash, root.ValidatorID)
+			}
+		}
+		if err := c.checkRootEvent(key, frame, root); err != nil {
+			return err
+		}
+		c.roots[frame] = append(c.roots[frame], root)
+	}
+	if it.Error() != nil {
+		return c.store.ioErr(it.Error())
+	}
+	return nil
+}
+
+// checkRootEvent checks that the root refers to a known event.
+func (c *fsck) checkRootEvent(key []byte, frame consensus.Frame, root RootDescriptor) error {
+	if c.cfg.GetEvent != nil {
+		e := c.cfg.GetEvent(root.RootHash)
+		if e == nil {
+			c.violation(CheckRoots, key, "root %s is unknown", root.RootHash)
+		} else if e.Creator() != root.ValidatorID || e.Frame() != frame {
+			c.violation(CheckRoots, key, "root %s is created by %d on frame %d", root.RootHash, e.Creator(), e.Frame())
+		}
+		return nil
+	}
+	if c.vectors == nil {
+		return nil
+	}
+	if root.RootHash.Lamport() < c.prunedBefore {
+		c.violation(CheckRoots, key, "root %s of frame %d is pruned from the DAG index", root.RootHash, frame)
+		return nil
+	}
+	before, err := c.vectors.GetHighestBefore(root.RootHash)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		c.missingVectors(CheckRoots, key, "root %s isn't indexed", root.RootHash)
+	}
+	return nil
+}
+
+// checkForklessCause recalculates the frames of a sample of roots: without forks, a root of frame F
+// forkless causes a quorum of the roots of frame F-1, as the root or its ancestor is a root of F with the quorum.
+func (c *fsck) checkForklessCause() error {
+	if c.cfg.ForklessCauseSamples == 0 {
+		return nil
+	}
+	if c.vectors == nil {
+		c.skip(CheckForklessCause, "no DAG index")
+		return nil
+	}
+	if c.hasForks {
+		c.skip(CheckForklessCause, "forks in the DAG index")
+		return nil
+	}
+	validators := c.epochState.Validators
+
+	var candidates []consensus.EventHash
+	frames := map[consensus.EventHash]consensus.Frame{}
+	for frame, roots := range c.roots {
+		if frame < 2 || frame-1 <= c.prunedFrame {
+			continue
+		}
+		for _, root := range roots {
+			candidates = append(candidates, root.RootHash)
+			frames[root.RootHash] = frame
+		}
+	}
+	// sort to sample deterministically
+	slices.SortFunc(candidates, func(a, b consensus.EventHash) int {
+		return bytes.Compare(a.Bytes(), b.Bytes())
+	})
+
+	index := dagindexer.NewIndex(nil, dagindexer.LiteConfig())
+	index.Reset(validators, index.WrapWithFlushable(c.store.EpochTable.VectorIndex), func(consensus.EventHash) consensus.Event {
+		return nil
+	})
+	r := rand.New(rand.NewPCG(c.cfg.Seed, 0))
+	sampled := 0
+	for _, i := range r.Perm(len(candidates)) {
+		if sampled == c.cfg.ForklessCauseSamples {
+			break
+		}
+		root := candidates[i]
+		frame := frames[root]
+		counter := validators.NewCounter()
+		available := true
+		for _, prev := range c.roots[frame-1] {
+			ok, err := index.ForklessCause(root, prev.RootHash)
+			if errors.Is(err, consensus.ErrEventNotFound) || errors.Is(err, consensus.ErrPruned) {
+				// the vectors are checked by CheckVectors and CheckRoots
+				available = false
+				break
+			}
+			if err != nil {
+				if err := c.inconsistent(CheckForklessCause, root.Bytes(), err); err != nil {
+					return err
+				}
+				available = false
+				break
+			}
+			if ok {
+				counter.CountVoteByID(prev.ValidatorID)
+			}
+		}
+		if !available {
+			continue
+		}
+		sampled++
+		c.report.Checked[CheckForklessCause]++
+		if !counter.HasQuorum() {
+			c.violation(CheckForklessCause, root.Bytes(), "root %s of frame %d doesn't forkless cause a quorum of the roots of frame %d", root, frame, frame-1)
+		}
+	}
+	return nil
+}
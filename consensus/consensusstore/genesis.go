@@ -23,9 +23,24 @@ type Genesis struct {
 }
 
 func (s *Store) ApplyGenesis(g *Genesis) error {
-	if ok, _ := s.table.LastDecidedState.Has([]byte(dsKey)); ok {
+	if s.openErr != nil {
+		return s.openErr
+	}
+	ok, err := s.table.LastDecidedState.Has([]byte(dsKey))
+	if err != nil {
+		return s.ioErr(err)
+	}
+	if ok {
 		return fmt.Errorf("genesis already applied")
 	}
+	// the DB is initialized by the genesis, so it has the latest layout
+	if _, stored, err := s.getSchemaVersion(s.table.Schema); err != nil {
+		return err
+	} else if !stored {
+		if err := s.set(s.table.Schema, []byte(schemaVersionKey), MainSchemaVersion()); err != nil {
+			return err
+		}
+	}
 	return s.SwitchGenesis(g)
 }
 
@@ -41,7 +56,24 @@ func (s *Store) SwitchGenesis(g *Genesis) error {
 	es.Validators = g.Validators
 	es.Epoch = g.Epoch
 	ds.LastDecidedFrame = consensus.FirstFrame - 1
-	s.SetEpochState(es)
-	s.SetLastDecidedState(ds)
+	// the genesis supersedes the interrupted transition, if any
+	err := s.writeAtomically(func(tables *mainTables) error {
+		if err := s.set(tables.EpochState, []byte(esKey), es); err != nil {
+			return err
+		}
+		if err := s.set(tables.LastDecidedState, []byte(dsKey), ds); err != nil {
+			return err
+		}
+		if err := tables.Journal.Delete([]byte(transitionKey)); err != nil {
+			return s.ioErr(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.EpochState = es
+	s.cache.LastDecidedState = ds
+	s.cache.Transition = nil
 	return nil
 }
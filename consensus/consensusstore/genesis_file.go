@@ -0,0 +1,212 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// GenesisFileVersion is the latest version of the genesis file format.
+const GenesisFileVersion = 1
+
+// ErrInvalidGenesis is returned when a genesis file doesn't pass the validation.
+var ErrInvalidGenesis = errors.New("invalid genesis")
+
+// GenesisFile is the JSON representation of Genesis.
+type GenesisFile struct {
+	Version    uint32             `json:"version"`
+	Epoch      consensus.Epoch    `json:"epoch"`
+	Validators []GenesisValidator `json:"validators"`
+	// Metadata isn't interpreted by the consensus, but it's covered by the hash
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// GenesisValidator is a validator of GenesisFile, it has either the weight or the stake.
+// The stakes are big integers, which are downscaled to the weights by consensus.ValidatorsBigBuilder.
+// All the validators of a file must have the same kind of the weight.
+type GenesisValidator struct {
+	ID     consensus.ValidatorID `json:"id"`
+	Weight consensus.Weight      `json:"weight,omitempty"`
+	Stake  *big.Int              `json:"stake,omitempty"`
+}
+
+// NewGenesisFile creates the file of the genesis, with the optional metadata.
+func NewGenesisFile(g *Genesis, metadata map[string]string) *GenesisFile {
+	f := &GenesisFile{
+		Version:  GenesisFileVersion,
+		Epoch:    g.Epoch,
+		Metadata: metadata,
+	}
+	for i, id := range g.Validators.SortedIDs() {
+		f.Validators = append(f.Validators, GenesisValidator{
+			ID:     id,
+			Weight: g.Validators.GetWeightByIdx(consensus.ValidatorIndex(i)),
+		})
+	}
+	return f
+}
+
+// LoadGenesisFile reads and validates the genesis file.
+func LoadGenesisFile(path string) (*GenesisFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadGenesisFile(file)
+}
+
+// ReadGenesisFile decodes and validates the genesis file. Unknown fields are rejected.
+func ReadGenesisFile(r io.Reader) (*GenesisFile, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	f := &GenesisFile{}
+	if err := decoder.Decode(f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGenesis, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write encodes the genesis file as JSON.
+func (f *GenesisFile) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(f)
+}
+
+// Validate checks the version and the validators: there must be no duplicates and zero weights,
+// and the total weight must not exceed math.MaxUint32/2, which is the limit of consensus.Validators.
+func (f *GenesisFile) Validate() error {
+	if f.Version == 0 || f.Version > GenesisFileVersion {
+		return fmt.Errorf("%w: unsupported version %d, the latest is %d", ErrInvalidGenesis, f.Version, GenesisFileVersion)
+	}
+	if len(f.Validators) == 0 {
+		return fmt.Errorf("%w: no validators", ErrInvalidGenesis)
+	}
+	staked := f.Validators[0].Stake != nil
+	seen := make(map[consensus.ValidatorID]struct{}, len(f.Validators))
+	total := uint64(0)
+	for _, v := range f.Validators {
+		if _, ok := seen[v.ID]; ok {
+			return fmt.Errorf("%w: duplicate validator %d", ErrInvalidGenesis, v.ID)
+		}
+		seen[v.ID] = struct{}{}
+		if (v.Stake != nil) != staked || (v.Stake != nil && v.Weight != 0) {
+			return fmt.Errorf("%w: validator %d, weights and stakes are mixed", ErrInvalidGenesis, v.ID)
+		}
+		if staked {
+			if v.Stake.Sign() <= 0 {
+				return fmt.Errorf("%w: validator %d has non-positive stake %s", ErrInvalidGenesis, v.ID, v.Stake)
+			}
+			continue
+		}
+		if v.Weight == 0 {
+			return fmt.Errorf("%w: validator %d has zero weight", ErrInvalidGenesis, v.ID)
+		}
+		total += uint64(v.Weight)
+	}
+	if total > math.MaxUint32/2 {
+		return fmt.Errorf("%w: total weight %d exceeds %d", ErrInvalidGenesis, total, uint64(math.MaxUint32/2))
+	}
+	if staked {
+		// the downscaled stakes mustn't drop the validators
+		if validators := f.buildValidators(); validators.Len() != consensus.ValidatorIndex(len(f.Validators)) {
+			return fmt.Errorf("%w: %d validators have too small stakes to be downscaled to weights", ErrInvalidGenesis, len(f.Validators)-int(validators.Len()))
+		}
+	}
+	return nil
+}
+
+// Genesis validates the file and returns its genesis.
+func (f *GenesisFile) Genesis() (*Genesis, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &Genesis{
+		Epoch:      f.Epoch,
+		Validators: f.buildValidators(),
+	}, nil
+}
+
+func (f *GenesisFile) buildValidators() *consensus.Validators {
+	if f.Validators[0].Stake != nil {
+		builder := consensus.NewBigBuilder()
+		for _, v := range f.Validators {
+			builder.Set(v.ID, v.Stake)
+		}
+		return builder.Build()
+	}
+	builder := consensus.NewValidatorsBuilder()
+	for _, v := range f.Validators {
+		builder.Set(v.ID, v.Weight)
+	}
+	return builder.Build()
+}
+
+// canonicalGenesis is the RLP encoding of GenesisFile, which is independent of the order of the validators and the metadata.
+type canonicalGenesis struct {
+	Version    uint32
+	Epoch      consensus.Epoch
+	Validators []canonicalGenesisValidator
+	Metadata   [][2]string
+}
+
+type canonicalGenesisValidator struct {
+	ID     consensus.ValidatorID
+	Weight consensus.Weight
+	Stake  *big.Int `rlp:"nil"`
+}
+
+// Hash returns the canonical hash of the genesis file, which doesn't depend on the JSON formatting,
+// nor on the order of the validators and the metadata. The file must be valid.
+func (f *GenesisFile) Hash() (consensus.Hash, error) {
+	if err := f.Validate(); err != nil {
+		return consensus.Hash{}, err
+	}
+	c := canonicalGenesis{
+		Version: f.Version,
+		Epoch:   f.Epoch,
+	}
+	for _, v := range f.Validators {
+		c.Validators = append(c.Validators, canonicalGenesisValidator{v.ID, v.Weight, v.Stake})
+	}
+	slices.SortFunc(c.Validators, func(a, b canonicalGenesisValidator) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for key, value := range f.Metadata {
+		c.Metadata = append(c.Metadata, [2]string{key, value})
+	}
+	slices.SortFunc(c.Metadata, func(a, b [2]string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	buf, err := rlp.EncodeToBytes(&c)
+	if err != nil {
+		return consensus.Hash{}, err
+	}
+	return sha256.Sum256(buf), nil
+}
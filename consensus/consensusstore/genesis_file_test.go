@@ -0,0 +1,156 @@
+package consensusstore
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+func TestGenesisFile_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	data := `{
+		"version": 1,
+		"epoch": 3,
+		"validators": [{"id": 2, "weight": 5}, {"id": 1, "weight": 10}],
+		"metadata": {"network": "test"}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadGenesisFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := f.Genesis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Epoch != 3 {
+		t.Fatalf("unexpected epoch: %d", g.Epoch)
+	}
+	if want, got := consensus.ArrayToValidators([]consensus.ValidatorID{1, 2}, []consensus.Weight{10, 5}).String(), g.Validators.String(); want != got {
+		t.Fatalf("expected validators: %s, got: %s", want, got)
+	}
+	if f.Metadata["network"] != "test" {
+		t.Fatalf("unexpected metadata: %v", f.Metadata)
+	}
+
+	store := NewMemStore()
+	if err := store.ApplyGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	if store.GetEpoch() != 3 {
+		t.Fatalf("unexpected epoch: %d", store.GetEpoch())
+	}
+}
+
+func TestGenesisFile_Stakes(t *testing.T) {
+	stake := new(big.Int).Lsh(big.NewInt(1), 100)
+	f := &GenesisFile{
+		Version: GenesisFileVersion,
+		Epoch:   1,
+		Validators: []GenesisValidator{
+			{ID: 1, Stake: stake},
+			{ID: 2, Stake: new(big.Int).Mul(stake, big.NewInt(3))},
+		},
+	}
+	g, err := f.Genesis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Validators.TotalWeight() > math.MaxUint32/2 {
+		t.Fatalf("stakes aren't downscaled: %d", g.Validators.TotalWeight())
+	}
+	if want, got := 3*g.Validators.Get(1), g.Validators.Get(2); want != got {
+		t.Fatalf("expected weight: %d, got: %d", want, got)
+	}
+
+	// a stake which is downscaled to zero drops the validator
+	f.Validators = append(f.Validators, GenesisValidator{ID: 3, Stake: big.NewInt(1)})
+	if _, err := f.Genesis(); !errors.Is(err, ErrInvalidGenesis) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGenesisFile_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"version":      `{"version": 2, "epoch": 1, "validators": [{"id": 1, "weight": 1}]}`,
+		"no version":   `{"epoch": 1, "validators": [{"id": 1, "weight": 1}]}`,
+		"unknown":      `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1}], "extra": 1}`,
+		"empty":        `{"version": 1, "epoch": 1, "validators": []}`,
+		"duplicate":    `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1}, {"id": 1, "weight": 2}]}`,
+		"zero weight":  `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1}, {"id": 2}]}`,
+		"zero stake":   `{"version": 1, "epoch": 1, "validators": [{"id": 1, "stake": 1}, {"id": 2, "stake": 0}]}`,
+		"neg stake":    `{"version": 1, "epoch": 1, "validators": [{"id": 1, "stake": -1}]}`,
+		"mixed":        `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1}, {"id": 2, "stake": 1}]}`,
+		"both":         `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1, "stake": 1}]}`,
+		"overflow":     `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 2147483647}, {"id": 2, "weight": 1}]}`,
+		"malformed":    `{"version": 1,`,
+		"weight range": `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 4294967296}]}`,
+	} {
+		if _, err := ReadGenesisFile(strings.NewReader(data)); !errors.Is(err, ErrInvalidGenesis) {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// the limit of the total weight is inclusive
+	data := `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 2147483646}, {"id": 2, "weight": 1}]}`
+	if _, err := ReadGenesisFile(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenesisFile_Hash(t *testing.T) {
+	a := `{"version": 1, "epoch": 1, "validators": [{"id": 1, "weight": 1}, {"id": 2, "weight": 2}], "metadata": {"a": "1", "b": "2"}}`
+	b := `{
+		"metadata": {"b": "2", "a": "1"},
+		"validators": [{"weight": 2, "id": 2}, {"weight": 1, "id": 1}],
+		"epoch": 1,
+		"version": 1
+	}`
+	hash := func(data string) consensus.Hash {
+		t.Helper()
+		f, err := ReadGenesisFile(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := f.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	if hash(a) != hash(b) {
+		t.Fatal("hash depends on the formatting")
+	}
+	for _, data := range []string{
+		strings.Replace(a, `"epoch": 1`, `"epoch": 2`, 1),
+		strings.Replace(a, `"weight": 2`, `"weight": 3`, 1),
+		strings.Replace(a, `"b": "2"`, `"b": "3"`, 1),
+		strings.Replace(a, `, "metadata": {"a": "1", "b": "2"}`, ``, 1),
+	} {
+		if hash(a) == hash(data) {
+			t.Fatalf("hash doesn't cover the change: %s", data)
+		}
+	}
+
+	// the written file has the same hash
+	f := NewGenesisFile(&Genesis{
+		Epoch:      1,
+		Validators: consensus.ArrayToValidators([]consensus.ValidatorID{1, 2}, []consensus.Weight{1, 2}),
+	}, map[string]string{"a": "1", "b": "2"})
+	buf := &bytes.Buffer{}
+	if err := f.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if hash(buf.String()) != hash(a) {
+		t.Fatal("written file has a different hash")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// ErrUnsupportedSchema is returned when a DB is written by a newer version of the store.
+var ErrUnsupportedSchema = errors.New("unsupported DB schema version")
+
+const schemaVersionKey = "v"
+
+// Migration upgrades a DB in place from the previous schema version.
+type Migration struct {
+	// Name describes the layout change
+	Name string
+	// Migrate rewrites the DB, reporting the progress in arbitrary units.
+	// It must be idempotent, as it's repeated if the store is closed before the version is saved.
+	Migrate func(db kvdb.Store, progress func(done, total uint64)) error
+}
+
+// MigrationProgress is a progress report of a running migration, see StoreConfig.MigrationProgress.
+type MigrationProgress struct {
+	// DB is "main", or "epoch N" for the DB of the epoch N
+	DB        string
+	Migration string
+	// Version is the schema version, which the migration upgrades to
+	Version uint32
+	Done    uint64
+	Total   uint64
+}
+
+// The ordered registries of migrations of the main DB and the epoch DBs, including the DAG index tables.
+// The schema version of a DB is the number of the applied migrations, so the registries are append only.
+// Unversioned DBs, written before the versioning, have the version 0.
+var (
+	mainMigrations = []Migration{
+		addTables("block index"),
+		addTables("archived epochs"),
+		addTables("transition journal"),
+	}
+	epochMigrations = []Migration{
+		addTables("election state"),
+		addTables("pruning state"),
+		{
+			Name:    "lowest-after vectors completed on read",
+			Migrate: migrateDAGIndex,
+		},
+	}
+)
+
+// vectorIndexPrefix is the table prefix of EpochTable.VectorIndex
+const vectorIndexPrefix = "v"
+
+// addTables is a migration of a layout change, which only adds tables.
+// The DB isn't rewritten, as the absent records mean the initial state, e.g. the blocks are indexed since the upgrade.
+func addTables(name string) Migration {
+	return Migration{
+		Name: name + " tables",
+		Migrate: func(kvdb.Store, func(done, total uint64)) error {
+			return nil
+		},
+	}
+}
+
+// migrateDAGIndex converts the DAG index of the epoch DB, see dagindexer.MigrateLowestAfter.
+func migrateDAGIndex(db kvdb.Store, progress func(done, total uint64)) error {
+	err := dagindexer.MigrateLowestAfter(table.New(db, []byte(vectorIndexPrefix)), progress)
+	if errors.Is(err, dagindexer.ErrRebuildRequired) {
+		return fmt.Errorf("%w: %w", ErrUnsupportedSchema, err)
+	}
+	return err
+}
+
+// MainSchemaVersion returns the schema version of the main DB, which is written by the store.
+func MainSchemaVersion() uint32 {
+	return uint32(len(mainMigrations))
+}
+
+// EpochSchemaVersion returns the schema version of the epoch DBs, which is written by the store.
+func EpochSchemaVersion() uint32 {
+	return uint32(len(epochMigrations))
+}
+
+// GetSchemaVersion returns the schema version of the main DB.
+func (s *Store) GetSchemaVersion() (uint32, error) {
+	version, _, err := s.getSchemaVersion(s.table.Schema)
+	return version, err
+}
+
+// GetEpochSchemaVersion returns the schema version of the current epoch DB.
+func (s *Store) GetEpochSchemaVersion() (uint32, error) {
+	version, _, err := s.getSchemaVersion(s.EpochTable.Schema)
+	return version, err
+}
+
+func (s *Store) getSchemaVersion(schemaTable kvdb.Store) (uint32, bool, error) {
+	version := uint32(0)
+	w, err := s.get(schemaTable, []byte(schemaVersionKey), &version)
+	if err != nil {
+		return 0, false, err
+	}
+	return version, w != nil, nil
+}
+
+// migrate brings the DB to the latest schema version.
+// Blank DBs are stamped with the latest version without migrations.
+func (s *Store) migrate(name string, db, schemaTable kvdb.Store, migrations []Migration) error {
+	latest := uint32(len(migrations))
+	version, stored, err := s.getSchemaVersion(schemaTable)
+	if err != nil {
+		return err
+	}
+	if !stored {
+		blank, err := isBlank(db)
+		if err != nil {
+			return s.ioErr(err)
+		}
+		if blank {
+			return s.set(schemaTable, []byte(schemaVersionKey), latest)
+		}
+	}
+	if version > latest {
+		return fmt.Errorf("%w: %s DB has version %d, the latest known is %d", ErrUnsupportedSchema, name, version, latest)
+	}
+
+	for ; version < latest; version++ {
+		m := migrations[version]
+		progress := func(done, total uint64) {
+			if s.cfg.MigrationProgress != nil {
+				s.cfg.MigrationProgress(MigrationProgress{
+					DB:        name,
+					Migration: m.Name,
+					Version:   version + 1,
+					Done:      done,
+					Total:     total,
+				})
+			}
+		}
+		progress(0, 0)
+		if err := m.Migrate(db, progress); err != nil {
+			return fmt.Errorf("%s DB migration to version %d (%s): %w", name, version+1, m.Name, err)
+		}
+		// saved after every migration, so an interrupted upgrade is resumed
+		if err := s.set(schemaTable, []byte(schemaVersionKey), version+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isBlank(db kvdb.Store) (bool, error) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	return !it.Next(), it.Error()
+}
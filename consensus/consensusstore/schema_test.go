@@ -0,0 +1,269 @@
+package consensusstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// withMigrations replaces the migration registries for the test.
+func withMigrations(t *testing.T, main, epoch []Migration) {
+	prevMain, prevEpoch := mainMigrations, epochMigrations
+	mainMigrations, epochMigrations = main, epoch
+	t.Cleanup(func() {
+		mainMigrations, epochMigrations = prevMain, prevEpoch
+	})
+}
+
+// renameKey is a migration which moves the value of a key.
+func renameKey(from, to string) Migration {
+	return Migration{
+		Name: from + "->" + to,
+		Migrate: func(db kvdb.Store, progress func(done, total uint64)) error {
+			val, err := db.Get([]byte(from))
+			if err != nil || val == nil {
+				return err
+			}
+			if err := db.Put([]byte(to), val); err != nil {
+				return err
+			}
+			progress(1, 1)
+			return db.Delete([]byte(from))
+		},
+	}
+}
+
+func openTestStore(t *testing.T, mainDB kvdb.Store, epochDBs map[consensus.Epoch]kvdb.Store, progress func(MigrationProgress)) (*Store, error) {
+	cfg := LiteStoreConfig()
+	cfg.MigrationProgress = progress
+	return OpenStore(mainDB, func(epoch consensus.Epoch) kvdb.Store {
+		if epochDBs[epoch] == nil {
+			epochDBs[epoch] = memorydb.New()
+		}
+		return epochDBs[epoch]
+	}, nil, cfg)
+}
+
+func TestSchema_BlankDBsHaveLatestVersion(t *testing.T) {
+	withMigrations(t, []Migration{renameKey("a", "b"), renameKey("b", "c")}, []Migration{renameKey("a", "b")})
+
+	store, err := openTestStore(t, memorydb.New(), map[consensus.Epoch]kvdb.Store{}, func(MigrationProgress) {
+		t.Fatal("blank DB is migrated")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.GetSchemaVersion(); err != nil || v != 2 || v != MainSchemaVersion() {
+		t.Fatalf("unexpected main schema version: %d, %v", v, err)
+	}
+	if v, err := store.GetEpochSchemaVersion(); err != nil || v != 1 || v != EpochSchemaVersion() {
+		t.Fatalf("unexpected epoch schema version: %d, %v", v, err)
+	}
+}
+
+func TestSchema_MigratesUnversionedDBs(t *testing.T) {
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{3: memorydb.New()}
+	for _, db := range []kvdb.Store{mainDB, epochDBs[3]} {
+		if err := db.Put([]byte("a"), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withMigrations(t, []Migration{renameKey("a", "b"), renameKey("b", "c")}, []Migration{renameKey("a", "b")})
+
+	var reports []MigrationProgress
+	store, err := openTestStore(t, mainDB, epochDBs, func(p MigrationProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(3); err != nil {
+		t.Fatal(err)
+	}
+
+	for db, key := range map[kvdb.Store]string{mainDB: "c", epochDBs[3]: "b"} {
+		if val, err := db.Get([]byte(key)); err != nil || string(val) != "value" {
+			t.Fatalf("unexpected migrated value of %s: %s, %v", key, val, err)
+		}
+		if val, err := db.Get([]byte("a")); err != nil || val != nil {
+			t.Fatalf("unexpected value of the old key: %s, %v", val, err)
+		}
+	}
+	if v, err := store.GetSchemaVersion(); err != nil || v != 2 {
+		t.Fatalf("unexpected main schema version: %d, %v", v, err)
+	}
+	if v, err := store.GetEpochSchemaVersion(); err != nil || v != 1 {
+		t.Fatalf("unexpected epoch schema version: %d, %v", v, err)
+	}
+
+	expected := []MigrationProgress{
+		{DB: "main", Migration: "a->b", Version: 1},
+		{DB: "main", Migration: "a->b", Version: 1, Done: 1, Total: 1},
+		{DB: "main", Migration: "b->c", Version: 2},
+		{DB: "main", Migration: "b->c", Version: 2, Done: 1, Total: 1},
+		{DB: "epoch 3", Migration: "a->b", Version: 1},
+		{DB: "epoch 3", Migration: "a->b", Version: 1, Done: 1, Total: 1},
+	}
+	if len(reports) != len(expected) {
+		t.Fatalf("unexpected progress reports: %v", reports)
+	}
+	for i := range expected {
+		if reports[i] != expected[i] {
+			t.Fatalf("unexpected progress report %d, want: %v, got: %v", i, expected[i], reports[i])
+		}
+	}
+}
+
+func TestSchema_ResumesInterruptedMigration(t *testing.T) {
+	mainDB := memorydb.New()
+	if err := mainDB.Put([]byte("a"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("interrupted")
+	fail := true
+	flaky := Migration{
+		Name: "flaky",
+		Migrate: func(db kvdb.Store, progress func(done, total uint64)) error {
+			if fail {
+				return failure
+			}
+			return renameKey("b", "c").Migrate(db, progress)
+		},
+	}
+	withMigrations(t, []Migration{renameKey("a", "b"), flaky}, nil)
+
+	if _, err := openTestStore(t, mainDB, nil, nil); !errors.Is(err, failure) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fail = false
+	store, err := openTestStore(t, mainDB, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, err := mainDB.Get([]byte("c")); err != nil || string(val) != "value" {
+		t.Fatalf("unexpected migrated value: %s, %v", val, err)
+	}
+	if v, err := store.GetSchemaVersion(); err != nil || v != 2 {
+		t.Fatalf("unexpected schema version: %d, %v", v, err)
+	}
+}
+
+func TestSchema_RefusesNewerVersions(t *testing.T) {
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{}
+	withMigrations(t, []Migration{renameKey("a", "b")}, []Migration{renameKey("a", "b")})
+	store, err := openTestStore(t, mainDB, epochDBs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+
+	withMigrations(t, nil, nil)
+	if _, err := openTestStore(t, mainDB, epochDBs, nil); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("newer main DB is opened: %v", err)
+	}
+	store, err = openTestStore(t, memorydb.New(), epochDBs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(1); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("newer epoch DB is opened: %v", err)
+	}
+}
+
+func TestSchema_UpgradesUnversionedLayout(t *testing.T) {
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{1: memorydb.New(), 2: memorydb.New()}
+	withSink, err := rlp.EncodeToBytes(&dagindexer.BranchesInfo{
+		BranchIDLastSeq:     []consensus.Seq{1, 1},
+		BranchIDCreatorIdxs: []consensus.ValidatorIndex{0, 0},
+		BranchIDByCreators:  [][]consensus.ValidatorIndex{{0, 1}},
+		SinkBranchIDs:       []consensus.ValidatorIndex{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []struct {
+		db    kvdb.Store
+		key   string
+		value []byte
+	}{
+		{mainDB, "c" + dsKey, []byte("value")},
+		{epochDBs[1], "r1", []byte("value")},
+		{epochDBs[2], "r1", []byte("value")},
+		{epochDBs[2], vectorIndexPrefix + "Bc", withSink},
+	} {
+		if err := record.db.Put([]byte(record.key), record.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the added tables don't need a rewrite
+	store, err := openTestStore(t, mainDB, epochDBs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.GetSchemaVersion(); err != nil || v != MainSchemaVersion() {
+		t.Fatalf("unexpected main schema version: %d, %v", v, err)
+	}
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.GetEpochSchemaVersion(); err != nil || v != EpochSchemaVersion() {
+		t.Fatalf("unexpected epoch schema version: %d, %v", v, err)
+	}
+
+	// the sink branches of the older DAG index aren't converted, see dagindexer.MigrateLowestAfter
+	if err := store.OpenEpochDB(2); !errors.Is(err, ErrUnsupportedSchema) || !errors.Is(err, dagindexer.ErrRebuildRequired) {
+		t.Fatalf("epoch DB with the older DAG index is opened: %v", err)
+	}
+}
+
+func TestSchema_NewStoreMigrates(t *testing.T) {
+	mainDB := memorydb.New()
+	getDB := func(consensus.Epoch) kvdb.Store {
+		return memorydb.New()
+	}
+	store := NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if v, err := store.GetSchemaVersion(); err != nil || v != MainSchemaVersion() {
+		t.Fatalf("unexpected schema version of a blank DB: %d, %v", v, err)
+	}
+	if err := store.ApplyGenesis(&Genesis{Epoch: 1, Validators: consensus.ArrayToValidators([]consensus.ValidatorID{1}, []consensus.Weight{1})}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a newer DB is refused
+	withMigrations(t, nil, nil)
+	var reported error
+	store = NewStore(mainDB, getDB, func(err error) {
+		reported = err
+	}, LiteStoreConfig())
+	if !errors.Is(reported, ErrUnsupportedSchema) {
+		t.Fatalf("newer main DB isn't reported: %v", reported)
+	}
+	if _, err := store.GetEpochState(); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("epoch state of a newer main DB is read: %v", err)
+	}
+	if _, err := store.GetLastDecidedState(); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("last decided state of a newer main DB is read: %v", err)
+	}
+	if err := store.OpenEpochDB(1); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("epoch DB of a newer main DB is opened: %v", err)
+	}
+	store = NewStore(memorydb.New(), getDB, nil, LiteStoreConfig())
+	if err := store.ApplyGenesis(&Genesis{Epoch: 1, Validators: consensus.ArrayToValidators([]consensus.ValidatorID{1}, []consensus.Weight{1})}); err != nil {
+		t.Fatal(err)
+	}
+}
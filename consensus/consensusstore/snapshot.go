@@ -0,0 +1,268 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+// SnapshotVersion is the latest version of the snapshot format.
+const SnapshotVersion = 1
+
+var (
+	// ErrInvalidSnapshot is returned when a snapshot is malformed or its content hash doesn't match.
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	// ErrSnapshotTargetNotEmpty is returned when a snapshot is imported into non-empty DBs.
+	ErrSnapshotTargetNotEmpty = errors.New("snapshot target DB isn't empty")
+	// ErrTransitionPending is returned when a snapshot is exported before an interrupted transition is recovered.
+	ErrTransitionPending = errors.New("transition in progress")
+)
+
+// SnapshotInfo describes a snapshot of the consensus state.
+type SnapshotInfo struct {
+	Version          uint32
+	Epoch            consensus.Epoch
+	LastDecidedFrame consensus.Frame
+	// Hash is the content hash of the snapshot, it's equal for the equal states
+	Hash consensus.Hash
+}
+
+// snapshotHeader opens a snapshot. It's followed by the records of the main DB and the epoch DB, ordered by key,
+// and the end record, which carries the content hash of the header and the DB records.
+type snapshotHeader struct {
+	Version          uint32
+	Epoch            consensus.Epoch
+	LastDecidedFrame consensus.Frame
+}
+
+const (
+	snapshotEnd uint8 = iota
+	snapshotMainDB
+	snapshotEpochDB
+)
+
+type snapshotRecord struct {
+	DB    uint8
+	Key   []byte
+	Value []byte
+}
+
+// ExportSnapshot writes the main DB and the DB of the current epoch, which include the roots, the DAG index,
+// the branches info and the confirmed events, so a node may continue from the snapshot instead of
+// reprocessing the events of the epoch. The election snapshot isn't included, as it's taken at intervals,
+// so it differs between the equal states, and the importing node replays the undecided roots instead.
+// The list of the archived epochs isn't included either, as the archive is local to the node.
+// The DAG index must be flushed, i.e. no event may be in processing. It's written through to the epoch DB first,
+// see SetIndexPersister, and dagindexer.ErrNotPersisted is returned with dagindexer.MemoryBackend, as the epoch DB has no vectors.
+// The events themselves aren't included, the node must be able to provide them to EventSource.
+func (s *Store) ExportSnapshot(w io.Writer) (*SnapshotInfo, error) {
+	if s.EpochDB == nil || s.epochDBEpoch != s.GetEpoch() {
+		return nil, fmt.Errorf("epoch DB %d isn't opened", s.GetEpoch())
+	}
+	if t, err := s.GetTransition(); err != nil {
+		return nil, err
+	} else if t != nil {
+		return nil, fmt.Errorf("%w: frame %d of epoch %d", ErrTransitionPending, t.Frame, t.Epoch)
+	}
+	if err := s.writeIndex(); err != nil {
+		return nil, err
+	}
+
+	hasher := sha256.New()
+	hashed := io.MultiWriter(w, hasher)
+	header := &snapshotHeader{
+		Version:          SnapshotVersion,
+		Epoch:            s.GetEpoch(),
+		LastDecidedFrame: s.GetLastDecidedFrame(),
+	}
+	if err := rlp.Encode(hashed, header); err != nil {
+		return nil, err
+	}
+	for _, src := range []struct {
+		id   uint8
+		db   kvdb.Store
+		skip []byte
+	}{{snapshotMainDB, s.MainDB, []byte(archivedEpochsPrefix)}, {snapshotEpochDB, s.EpochDB, []byte(electionStatePrefix)}} {
+		if err := exportDB(hashed, src.id, src.db, src.skip); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &SnapshotInfo{
+		Version:          header.Version,
+		Epoch:            header.Epoch,
+		LastDecidedFrame: header.LastDecidedFrame,
+		Hash:             consensus.BytesToHash(hasher.Sum(nil)),
+	}
+	if err := rlp.Encode(w, &snapshotRecord{DB: snapshotEnd, Value: info.Hash.Bytes()}); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// exportDB writes the records of the DB, except the ones with the skipped prefix.
+func exportDB(w io.Writer, id uint8, db kvdb.Store, skip []byte) error {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if skip != nil && bytes.HasPrefix(it.Key(), skip) {
+			continue
+		}
+		if err := rlp.Encode(w, &snapshotRecord{DB: id, Key: it.Key(), Value: it.Value()}); err != nil {
+			return err
+		}
+	}
+	if it.Error() != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, it.Error())
+	}
+	return nil
+}
+
+// ImportSnapshot writes the snapshot into the empty main DB and the DB of the snapshot's epoch, produced by getDB.
+// The content hash is verified, and if the snapshot is invalid, the written records are deleted.
+// A Store opened over the DBs continues from the state of the exporting node on Bootstrap,
+// which also restores the DAG index from the epoch DB.
+func ImportSnapshot(r io.Reader, mainDB kvdb.Store, getDB EpochDBProducer) (*SnapshotInfo, error) {
+	stream := rlp.NewStream(r, 0)
+	hasher := sha256.New()
+
+	header := &snapshotHeader{}
+	if err := decodeSnapshot(stream, hasher, header); err != nil {
+		return nil, err
+	}
+	if header.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, the latest is %d", ErrInvalidSnapshot, header.Version, SnapshotVersion)
+	}
+	epochDB := getDB(header.Epoch)
+	for _, db := range []kvdb.Store{mainDB, epochDB} {
+		empty, err := isEmptyDB(db)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrSnapshotTargetNotEmpty
+		}
+	}
+
+	info, err := importRecords(stream, hasher, header, mainDB, epochDB)
+	if err != nil {
+		if clearErr := errors.Join(clearDB(mainDB), clearDB(epochDB)); clearErr != nil {
+			return nil, errors.Join(err, clearErr)
+		}
+		return nil, err
+	}
+	return info, nil
+}
+
+func importRecords(stream *rlp.Stream, hasher hash.Hash, header *snapshotHeader, mainDB, epochDB kvdb.Store) (*SnapshotInfo, error) {
+	batches := map[uint8]kvdb.Batch{
+		snapshotMainDB:  mainDB.NewBatch(),
+		snapshotEpochDB: epochDB.NewBatch(),
+	}
+	var (
+		lastDB  uint8
+		lastKey []byte
+	)
+	for {
+		record := &snapshotRecord{}
+		if err := stream.Decode(record); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if record.DB == snapshotEnd {
+			if !bytes.Equal(record.Value, hasher.Sum(nil)) {
+				return nil, fmt.Errorf("%w: content hash mismatch", ErrInvalidSnapshot)
+			}
+			break
+		}
+		// the records are ordered, so the content hash is canonical
+		if record.DB < lastDB || record.DB == lastDB && bytes.Compare(record.Key, lastKey) <= 0 {
+			return nil, fmt.Errorf("%w: unordered record %x", ErrInvalidSnapshot, record.Key)
+		}
+		batch, ok := batches[record.DB]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown DB %d", ErrInvalidSnapshot, record.DB)
+		}
+		if err := rlp.Encode(hasher, record); err != nil {
+			return nil, err
+		}
+		lastDB, lastKey = record.DB, record.Key
+		if err := batch.Put(record.Key, record.Value); err != nil {
+			return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+		}
+		if batch.ValueSize() > kvdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+			}
+			batch.Reset()
+		}
+	}
+	if _, err := stream.Raw(); err != io.EOF {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidSnapshot)
+	}
+	for _, id := range []uint8{snapshotMainDB, snapshotEpochDB} {
+		if err := batches[id].Write(); err != nil {
+			return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+		}
+	}
+	return &SnapshotInfo{
+		Version:          header.Version,
+		Epoch:            header.Epoch,
+		LastDecidedFrame: header.LastDecidedFrame,
+		Hash:             consensus.BytesToHash(hasher.Sum(nil)),
+	}, nil
+}
+
+// decodeSnapshot decodes the next value of the snapshot, and hashes its canonical encoding.
+func decodeSnapshot(stream *rlp.Stream, hasher hash.Hash, val interface{}) error {
+	if err := stream.Decode(val); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	return rlp.Encode(hasher, val)
+}
+
+func isEmptyDB(db kvdb.Store) (bool, error) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	empty := !it.Next()
+	if it.Error() != nil {
+		return false, fmt.Errorf("%w: %w", consensus.ErrStorageIO, it.Error())
+	}
+	return empty, nil
+}
+
+// clearDB deletes all the records of db in a batch.
+func clearDB(db kvdb.Store) error {
+	batch := db.NewBatch()
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if err := batch.Delete(it.Key()); err != nil {
+			return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+		}
+	}
+	if it.Error() != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, it.Error())
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package consensusstore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func testSnapshotTarget() (kvdb.Store, EpochDBProducer) {
+	epochDBs := map[consensus.Epoch]kvdb.Store{}
+	return memorydb.New(), func(epoch consensus.Epoch) kvdb.Store {
+		if epochDBs[epoch] == nil {
+			epochDBs[epoch] = memorydb.New()
+		}
+		return epochDBs[epoch]
+	}
+}
+
+func TestSnapshot_ExportImport(t *testing.T) {
+	store, _, _ := testTransitionStore(t)
+	for frame := consensus.Frame(1); frame <= 3; frame++ {
+		atropos := consensus.EventHash{byte(frame)}
+		root := &consensustest.TestEvent{}
+		root.SetEpoch(1)
+		root.SetFrame(frame)
+		root.SetCreator(1)
+		root.SetID([24]byte{byte(frame), 1})
+		if err := store.AddRoot(root); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.BeginTransition(frame, atropos); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetEventConfirmedOn(atropos, frame); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddBlock(&BlockRecord{Epoch: 1, Frame: frame, Atropos: atropos}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CommitTransition(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot := &bytes.Buffer{}
+	exported, err := store.ExportSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Epoch != 1 || exported.LastDecidedFrame != 3 {
+		t.Fatalf("unexpected snapshot: %+v", exported)
+	}
+	data := bytes.Clone(snapshot.Bytes())
+
+	mainDB, getDB := testSnapshotTarget()
+	imported, err := ImportSnapshot(snapshot, mainDB, getDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *imported != *exported {
+		t.Fatalf("expected snapshot: %+v, got: %+v", exported, imported)
+	}
+
+	restored := NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if err := restored.OpenEpochDB(restored.GetEpoch()); err != nil {
+		t.Fatal(err)
+	}
+	if frame := restored.GetLastDecidedFrame(); frame != 3 {
+		t.Fatalf("unexpected last decided frame: %d", frame)
+	}
+	if frame, err := restored.GetEventConfirmedOn(consensus.EventHash{2}); err != nil || frame != 2 {
+		t.Fatalf("unexpected confirmation: %d, %v", frame, err)
+	}
+	if roots, err := restored.GetFrameRoots(3); err != nil || len(roots) != 1 {
+		t.Fatalf("unexpected roots: %v, %v", roots, err)
+	}
+	// the export of the restored state is identical
+	again := &bytes.Buffer{}
+	if _, err := restored.ExportSnapshot(again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again.Bytes()) {
+		t.Fatal("snapshot of the restored state differs")
+	}
+
+	// the target DBs must be empty
+	if _, err := ImportSnapshot(bytes.NewReader(data), mainDB, getDB); !errors.Is(err, ErrSnapshotTargetNotEmpty) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSnapshot_InvalidIsNotImported(t *testing.T) {
+	store, _, _ := testTransitionStore(t)
+	snapshot := &bytes.Buffer{}
+	if _, err := store.ExportSnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	data := snapshot.Bytes()
+
+	for name, invalid := range map[string][]byte{
+		"truncated": data[:len(data)-10],
+		"trailing":  append(bytes.Clone(data), 0x80),
+		"tampered": func() []byte {
+			tampered := bytes.Clone(data)
+			tampered[len(tampered)-40] ^= 1
+			return tampered
+		}(),
+	} {
+		mainDB, getDB := testSnapshotTarget()
+		if _, err := ImportSnapshot(bytes.NewReader(invalid), mainDB, getDB); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		for _, db := range []kvdb.Store{mainDB, getDB(1)} {
+			if empty, err := isEmptyDB(db); err != nil || !empty {
+				t.Fatalf("%s: records of invalid snapshot are left", name)
+			}
+		}
+	}
+
+	// a pending transition must be recovered before the export
+	if err := store.BeginTransition(1, consensus.EventHash{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ExportSnapshot(&bytes.Buffer{}); !errors.Is(err, ErrTransitionPending) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -12,11 +12,14 @@ package consensusstore
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/rlp"
 
 	"github.com/0xsoniclabs/cacheutils/simplewlru"
 	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 	"github.com/0xsoniclabs/kvdb"
 	"github.com/0xsoniclabs/kvdb/memorydb"
 	"github.com/0xsoniclabs/kvdb/table"
@@ -27,35 +30,98 @@ type Store struct {
 	GetEpochDB EpochDBProducer
 	cfg        StoreConfig
 	crit       func(error)
+	// openErr is the failure of NewStore to open the main DB
+	openErr error
 
 	MainDB kvdb.Store
-	table  struct {
-		LastDecidedState kvdb.Store `table:"c"`
-		EpochState       kvdb.Store `table:"e"`
-	}
+	table  mainTables
 
 	cache struct {
 		LastDecidedState *LastDecidedState
 		EpochState       *EpochState
+		LastBlock        *consensus.BlockID
+		PrunedFrame      *consensus.Frame
+		Transition       *Transition
 		FrameRoots       *simplewlru.Cache `cache:"-"` // store by pointer
 	}
 
-	EpochDB    kvdb.Store
-	EpochTable struct {
+	metrics struct {
+		frameRootsHits   metrics.Counter
+		frameRootsMisses metrics.Counter
+		rootsAdded       metrics.Counter
+		errors           metrics.Counter
+	}
+
+	EpochDB      kvdb.Store
+	epochDBEpoch consensus.Epoch
+	// persistIndex writes the DAG index of the current epoch through to the epoch DB, see SetIndexPersister
+	persistIndex func() error
+	EpochTable   struct {
 		Roots          kvdb.Store `table:"r"`
 		VectorIndex    kvdb.Store `table:"v"`
 		ConfirmedEvent kvdb.Store `table:"C"`
+		ElectionState  kvdb.Store `table:"E"`
+		PruningState   kvdb.Store `table:"P"`
+		Schema         kvdb.Store `table:"M"`
 	}
 }
 
+type mainTables struct {
+	LastDecidedState kvdb.Store `table:"c"`
+	EpochState       kvdb.Store `table:"e"`
+	Blocks           kvdb.Store `table:"b"`
+	AtroposBlocks    kvdb.Store `table:"a"`
+	EpochBlocks      kvdb.Store `table:"p"`
+	Journal          kvdb.Store `table:"j"`
+	ArchivedEpochs   kvdb.Store `table:"A"`
+	Schema           kvdb.Store `table:"M"`
+}
+
 var (
 	ErrNoGenesis = errors.New("genesis not applied")
 )
 
 type EpochDBProducer func(epoch consensus.Epoch) kvdb.Store
 
-// NewStore creates store over key-value db.
+// NewStore creates store over key-value db, migrating the main DB to the latest schema version,
+// and reopens the epochs retained by StoreConfig.Archive, as OpenStore does.
+// If it fails, e.g. the main DB is written by a newer version, the error is reported to crit
+// and returned by GetEpochState, GetLastDecidedState, ApplyGenesis and OpenEpochDB, so the store can't be used.
 func NewStore(mainDB kvdb.Store, getDB EpochDBProducer, crit func(error), cfg StoreConfig) *Store {
+	s := newStore(mainDB, getDB, crit, cfg)
+	if err := s.open(); err != nil {
+		if !errors.Is(err, consensus.ErrStorageIO) {
+			// the storage failures are already reported
+			s.critical(err)
+		}
+		s.openErr = err
+	}
+	return s
+}
+
+// OpenStore creates store over key-value db, migrating the main DB to the latest schema version,
+// and reopens the epochs retained by StoreConfig.Archive.
+// Returns ErrUnsupportedSchema if the main DB is written by a newer version.
+// crit is an optional hook, notified of storage and consistency failures before they are returned.
+func OpenStore(mainDB kvdb.Store, getDB EpochDBProducer, crit func(error), cfg StoreConfig) (*Store, error) {
+	s := newStore(mainDB, getDB, crit, cfg)
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) open() error {
+	if err := s.migrate("main", s.MainDB, s.table.Schema, mainMigrations); err != nil {
+		return err
+	}
+	if s.cfg.Archive != nil {
+		return s.restoreArchive()
+	}
+	return nil
+}
+
+func newStore(mainDB kvdb.Store, getDB EpochDBProducer, crit func(error), cfg StoreConfig) *Store {
 	s := &Store{
 		GetEpochDB: getDB,
 		cfg:        cfg,
@@ -66,10 +132,18 @@ func NewStore(mainDB kvdb.Store, getDB EpochDBProducer, crit func(error), cfg St
 	table.MigrateTables(&s.table, s.MainDB)
 
 	s.initCache()
-
+	s.initMetrics()
 	return s
 }
 
+func (s *Store) initMetrics() {
+	registry := metrics.OrNoop(s.cfg.Metrics)
+	s.metrics.frameRootsHits = registry.Counter("consensusstore/frame_roots_cache/hits")
+	s.metrics.frameRootsMisses = registry.Counter("consensusstore/frame_roots_cache/misses")
+	s.metrics.rootsAdded = registry.Counter("consensusstore/roots_added")
+	s.metrics.errors = registry.Counter("consensusstore/errors")
+}
+
 func (s *Store) initCache() {
 	s.cache.FrameRoots = s.makeCache(s.cfg.Cache.RootsNum, s.cfg.Cache.RootsFrames)
 }
@@ -81,10 +155,7 @@ func NewMemStore() *Store {
 		return memorydb.New()
 	}
 	cfg := LiteStoreConfig()
-	crit := func(err error) {
-		panic(err)
-	}
-	return NewStore(memorydb.New(), getDb, crit, cfg)
+	return NewStore(memorydb.New(), getDb, nil, cfg)
 }
 
 // Close leaves underlying database.
@@ -107,30 +178,71 @@ func (s *Store) Close() error {
 			return err
 		}
 	}
+	if s.cfg.Archive != nil {
+		return s.cfg.Archive.Close()
+	}
 	return nil
 }
 
-// DropEpochDB drops existing epoch DB
+// SetIndexPersister sets the function, which writes the DAG index of the current epoch through to the epoch DB,
+// e.g. dagindexer.Index.Persist. It's called before the epoch DB is archived or exported,
+// as the index may hold the vectors in memory.
+func (s *Store) SetIndexPersister(persist func() error) {
+	s.persistIndex = persist
+}
+
+// DropEpochDB drops existing epoch DB, or hands it over to the archive if StoreConfig.Archive is set
 func (s *Store) DropEpochDB() error {
 	prevDb := s.EpochDB
-	if prevDb != nil {
-		err := prevDb.Close()
+	if prevDb != nil && s.cfg.Archive != nil {
+		// the archived epoch is still readable without the vectors, e.g. with dagindexer.MemoryBackend
+		if err := s.writeIndex(); err != nil && !errors.Is(err, dagindexer.ErrNotPersisted) {
+			return err
+		}
+		s.EpochDB = nil
+	}
+	return s.dropEpochDB(prevDb, s.epochDBEpoch)
+}
+
+// writeIndex writes the DAG index of the current epoch through to the epoch DB, see SetIndexPersister.
+func (s *Store) writeIndex() error {
+	if s.persistIndex == nil {
+		return nil
+	}
+	return s.persistIndex()
+}
+
+func (s *Store) dropEpochDB(db kvdb.Store, epoch consensus.Epoch) error {
+	if db != nil && s.cfg.Archive != nil {
+		if err := s.cfg.Archive.Archive(epoch, db); err != nil {
+			return err
+		}
+		return s.saveArchivedEpochs()
+	}
+	if db != nil {
+		err := db.Close()
 		if err != nil {
 			return err
 		}
-		prevDb.Drop()
+		db.Drop()
 	}
 	return nil
 }
 
-// OpenEpochDB makes new epoch DB
+// OpenEpochDB makes new epoch DB, or opens the existing one, migrating it to the latest schema version.
+// Returns ErrUnsupportedSchema if the epoch DB is written by a newer version.
 func (s *Store) OpenEpochDB(n consensus.Epoch) error {
+	if s.openErr != nil {
+		return s.openErr
+	}
 	// Clear full LRU cache.
 	s.cache.FrameRoots.Purge()
+	s.cache.PrunedFrame = nil
 
 	s.EpochDB = s.GetEpochDB(n)
+	s.epochDBEpoch = n
 	table.MigrateTables(&s.EpochTable, s.EpochDB)
-	return nil
+	return s.migrate(fmt.Sprintf("epoch %d", n), s.EpochDB, s.EpochTable.Schema, epochMigrations)
 }
 
 /*
@@ -138,38 +250,56 @@ func (s *Store) OpenEpochDB(n consensus.Epoch) error {
  */
 
 // set RLP value
-func (s *Store) set(table kvdb.Store, key []byte, val interface{}) {
+func (s *Store) set(table kvdb.Store, key []byte, val interface{}) error {
 	buf, err := rlp.EncodeToBytes(val)
 	if err != nil {
-		s.crit(err)
+		return err
 	}
 
 	if err := table.Put(key, buf); err != nil {
-		s.crit(err)
+		return s.ioErr(err)
 	}
+	return nil
 }
 
 // get RLP value
-func (s *Store) get(table kvdb.Store, key []byte, to interface{}) interface{} {
+func (s *Store) get(table kvdb.Store, key []byte, to interface{}) (interface{}, error) {
 	buf, err := table.Get(key)
 	if err != nil {
-		s.crit(err)
+		return nil, s.ioErr(err)
 	}
 	if buf == nil {
-		return nil
+		return nil, nil
 	}
 
 	err = rlp.DecodeBytes(buf, to)
 	if err != nil {
-		s.crit(err)
+		return nil, s.inconsistencyErr("%v", err)
 	}
-	return to
+	return to, nil
 }
 
+// makeCache creates LRU cache. The negative sizes, which are rejected by simplewlru, are treated as 0.
 func (s *Store) makeCache(weight uint, size int) *simplewlru.Cache {
-	cache, err := simplewlru.New(weight, size)
-	if err != nil {
+	cache, _ := simplewlru.New(weight, max(size, 0))
+	return cache
+}
+
+// ioErr wraps a failure of the underlying key-value storage.
+func (s *Store) ioErr(err error) error {
+	return s.critical(fmt.Errorf("%w: %w", consensus.ErrStorageIO, err))
+}
+
+// inconsistencyErr reports a violated invariant of the persisted state.
+func (s *Store) inconsistencyErr(format string, args ...any) error {
+	return s.critical(fmt.Errorf("%w: %s", consensus.ErrInconsistentDB, fmt.Sprintf(format, args...)))
+}
+
+// critical notifies the optional crit hook and returns err unchanged.
+func (s *Store) critical(err error) error {
+	s.metrics.errors.Inc(1)
+	if s.crit != nil {
 		s.crit(err)
 	}
-	return cache
+	return err
 }
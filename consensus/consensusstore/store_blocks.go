@@ -0,0 +1,175 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// BlockRecord describes a decided block. Blocks are numbered sequentially across epochs, starting from 1.
+type BlockRecord struct {
+	Epoch    consensus.Epoch
+	Frame    consensus.Frame
+	Atropos  consensus.EventHash
+	Cheaters consensus.Cheaters
+	// Events is the metric of the events confirmed by the block
+	Events consensus.Metric
+}
+
+// AddBlock records the block with the next number and returns the number.
+// A block with an already recorded atropos isn't recorded again, its number is returned.
+func (s *Store) AddBlock(block *BlockRecord) (consensus.BlockID, error) {
+	if n, err := s.GetBlockNumber(block.Atropos); err != nil || n != 0 {
+		return n, err
+	}
+	last, err := s.GetLastBlockNumber()
+	if err != nil {
+		return 0, err
+	}
+	n := last + 1
+
+	// the first block of an epoch is indexed for the lookups by epoch
+	newEpoch := last == 0
+	if !newEpoch {
+		prev, err := s.GetBlock(last)
+		if err != nil {
+			return 0, err
+		}
+		if prev == nil {
+			return 0, s.inconsistencyErr("blocks table: last block %d is missing", last)
+		}
+		newEpoch = prev.Epoch != block.Epoch
+	}
+	// the block and its indexes are written at once, so a crash doesn't leave a partially indexed block
+	err = s.writeAtomically(func(tables *mainTables) error {
+		if err := s.set(tables.Blocks, n.Bytes(), block); err != nil {
+			return err
+		}
+		if err := tables.AtroposBlocks.Put(block.Atropos.Bytes(), n.Bytes()); err != nil {
+			return s.ioErr(err)
+		}
+		if newEpoch {
+			if err := tables.EpochBlocks.Put(block.Epoch.Bytes(), n.Bytes()); err != nil {
+				return s.ioErr(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.cache.LastBlock = &n
+	return n, nil
+}
+
+// GetBlock returns the recorded block, or nil if it's unknown.
+func (s *Store) GetBlock(n consensus.BlockID) (*BlockRecord, error) {
+	w, err := s.get(s.table.Blocks, n.Bytes(), &BlockRecord{})
+	if w == nil || err != nil {
+		return nil, err
+	}
+	return w.(*BlockRecord), nil
+}
+
+// GetBlockNumber returns the number of the block with the atropos, or 0 if it's unknown.
+func (s *Store) GetBlockNumber(atropos consensus.EventHash) (consensus.BlockID, error) {
+	buf, err := s.table.AtroposBlocks.Get(atropos.Bytes())
+	if err != nil {
+		return 0, s.ioErr(err)
+	}
+	if buf == nil {
+		return 0, nil
+	}
+	return consensus.BytesToBlock(buf), nil
+}
+
+// GetBlockByAtropos returns the number and the block with the atropos, or nil if it's unknown.
+func (s *Store) GetBlockByAtropos(atropos consensus.EventHash) (consensus.BlockID, *BlockRecord, error) {
+	n, err := s.GetBlockNumber(atropos)
+	if n == 0 || err != nil {
+		return 0, nil, err
+	}
+	block, err := s.GetBlock(n)
+	if err != nil {
+		return 0, nil, err
+	}
+	if block == nil {
+		return 0, nil, s.inconsistencyErr("blocks table: block %d of atropos %s is missing", n, atropos.String())
+	}
+	return n, block, nil
+}
+
+// GetLastBlockNumber returns the number of the last recorded block, or 0 if there are none.
+func (s *Store) GetLastBlockNumber() (consensus.BlockID, error) {
+	if s.cache.LastBlock != nil {
+		return *s.cache.LastBlock, nil
+	}
+
+	// the last block is after the first block of the last epoch
+	last := consensus.BlockID(0)
+	it := s.table.EpochBlocks.NewIterator(nil, nil)
+	for it.Next() {
+		last = consensus.BytesToBlock(it.Value())
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return 0, s.ioErr(err)
+	}
+	if last != 0 {
+		it = s.table.Blocks.NewIterator(nil, last.Bytes())
+		defer it.Release()
+		for it.Next() {
+			last = consensus.BytesToBlock(it.Key())
+		}
+		if it.Error() != nil {
+			return 0, s.ioErr(it.Error())
+		}
+	}
+
+	s.cache.LastBlock = &last
+	return last, nil
+}
+
+// ForEachBlock iterates the blocks of epochs [from, to] in the order of numbers, until fn returns false.
+func (s *Store) ForEachBlock(from, to consensus.Epoch, fn func(n consensus.BlockID, block *BlockRecord) bool) error {
+	it := s.table.EpochBlocks.NewIterator(nil, from.Bytes())
+	first := consensus.BlockID(0)
+	if it.Next() {
+		first = consensus.BytesToBlock(it.Value())
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return s.ioErr(err)
+	}
+	if first == 0 {
+		return nil
+	}
+
+	it = s.table.Blocks.NewIterator(nil, first.Bytes())
+	defer it.Release()
+	for it.Next() {
+		block := &BlockRecord{}
+		if err := rlp.DecodeBytes(it.Value(), block); err != nil {
+			return s.inconsistencyErr("blocks table: %v", err)
+		}
+		if block.Epoch > to || !fn(consensus.BytesToBlock(it.Key()), block) {
+			return nil
+		}
+	}
+	if it.Error() != nil {
+		return s.ioErr(it.Error())
+	}
+	return nil
+}
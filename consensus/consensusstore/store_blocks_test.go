@@ -0,0 +1,175 @@
+package consensusstore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestBlocks_ConsistentPersistingAndRetrieval(t *testing.T) {
+	mainDB := memorydb.New()
+	getDB := func(consensus.Epoch) kvdb.Store {
+		return memorydb.New()
+	}
+	store := NewStore(mainDB, getDB, nil, LiteStoreConfig())
+
+	if n, err := store.GetLastBlockNumber(); err != nil || n != 0 {
+		t.Fatalf("unexpected last block of empty store: %d, %v", n, err)
+	}
+
+	// epochs 1, 2 and 4 have 3 blocks each
+	var blocks []*BlockRecord
+	for _, epoch := range []consensus.Epoch{1, 2, 4} {
+		for frame := consensus.Frame(1); frame <= 3; frame++ {
+			block := &BlockRecord{
+				Epoch:    epoch,
+				Frame:    frame,
+				Atropos:  consensus.EventHash{byte(epoch), byte(frame)},
+				Cheaters: consensus.Cheaters{},
+				Events:   consensus.Metric{Num: uint32(frame), Size: uint64(epoch)},
+			}
+			if frame == 2 {
+				block.Cheaters = consensus.Cheaters{consensus.ValidatorID(epoch)}
+			}
+			n, err := store.AddBlock(block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks = append(blocks, block)
+			if want := consensus.BlockID(len(blocks)); n != want {
+				t.Fatalf("unexpected block number, want: %d, got: %d", want, n)
+			}
+		}
+	}
+
+	// re-adding a block is a no-op
+	if n, err := store.AddBlock(blocks[4]); err != nil || n != 5 {
+		t.Fatalf("unexpected re-added block number: %d, %v", n, err)
+	}
+
+	// a new instance restores the last block number
+	store = NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if n, err := store.GetLastBlockNumber(); err != nil || n != consensus.BlockID(len(blocks)) {
+		t.Fatalf("unexpected last block: %d, %v", n, err)
+	}
+
+	for i, want := range blocks {
+		n := consensus.BlockID(i + 1)
+		got, err := store.GetBlock(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("unexpected block %d, want: %v, got: %v", n, want, got)
+		}
+		gotN, got, err := store.GetBlockByAtropos(want.Atropos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotN != n || !reflect.DeepEqual(want, got) {
+			t.Fatalf("unexpected block by atropos, want: %d %v, got: %d %v", n, want, gotN, got)
+		}
+	}
+	if got, err := store.GetBlock(consensus.BlockID(len(blocks) + 1)); err != nil || got != nil {
+		t.Fatalf("unexpected unknown block: %v, %v", got, err)
+	}
+	if n, got, err := store.GetBlockByAtropos(consensus.EventHash{0xff}); err != nil || n != 0 || got != nil {
+		t.Fatalf("unexpected unknown atropos block: %d, %v, %v", n, got, err)
+	}
+
+	for _, test := range []struct {
+		from, to consensus.Epoch
+		want     []consensus.BlockID
+	}{
+		{1, 1, []consensus.BlockID{1, 2, 3}},
+		{1, 2, []consensus.BlockID{1, 2, 3, 4, 5, 6}},
+		{2, 3, []consensus.BlockID{4, 5, 6}},
+		{3, 10, []consensus.BlockID{7, 8, 9}},
+		{5, 10, nil},
+	} {
+		var got []consensus.BlockID
+		err := store.ForEachBlock(test.from, test.to, func(n consensus.BlockID, block *BlockRecord) bool {
+			if !reflect.DeepEqual(blocks[n-1], block) {
+				t.Fatalf("unexpected block %d: %v", n, block)
+			}
+			got = append(got, n)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Fatalf("unexpected blocks of epochs [%d, %d], want: %v, got: %v", test.from, test.to, test.want, got)
+		}
+	}
+
+	// iteration stops
+	count := 0
+	err := store.ForEachBlock(1, 4, func(consensus.BlockID, *BlockRecord) bool {
+		count++
+		return count < 2
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("iteration isn't stopped: %d, %v", count, err)
+	}
+}
+
+// failingBatchDB is a kvdb.Store, which fails the batch writes if fail is set
+type failingBatchDB struct {
+	kvdb.Store
+	fail *bool
+}
+
+type failingBatch struct {
+	kvdb.Batch
+	fail *bool
+}
+
+func (db failingBatchDB) NewBatch() kvdb.Batch {
+	return failingBatch{db.Store.NewBatch(), db.fail}
+}
+
+func (b failingBatch) Write() error {
+	if *b.fail {
+		return errFailingStore
+	}
+	return b.Batch.Write()
+}
+
+func TestBlocks_AddBlockIsAtomic(t *testing.T) {
+	fail := true
+	store := NewStore(failingBatchDB{memorydb.New(), &fail}, func(consensus.Epoch) kvdb.Store {
+		return memorydb.New()
+	}, nil, LiteStoreConfig())
+
+	block := &BlockRecord{Epoch: 1, Frame: 1, Atropos: consensus.EventHash{1}}
+	if _, err := store.AddBlock(block); !errors.Is(err, consensus.ErrStorageIO) {
+		t.Fatalf("expected storage IO error, got: %v", err)
+	}
+	// nothing of the block is written
+	if n, err := store.GetLastBlockNumber(); err != nil || n != 0 {
+		t.Fatalf("unexpected last block: %d, %v", n, err)
+	}
+	if n, err := store.GetBlockNumber(block.Atropos); err != nil || n != 0 {
+		t.Fatalf("unexpected block of atropos: %d, %v", n, err)
+	}
+	if got, err := store.GetBlock(1); err != nil || got != nil {
+		t.Fatalf("unexpected block: %v, %v", got, err)
+	}
+	count := 0
+	if err := store.ForEachBlock(1, 1, func(consensus.BlockID, *BlockRecord) bool {
+		count++
+		return true
+	}); err != nil || count != 0 {
+		t.Fatalf("unexpected blocks of the epoch: %d, %v", count, err)
+	}
+
+	fail = false
+	if n, err := store.AddBlock(block); err != nil || n != 1 {
+		t.Fatalf("unexpected block number: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import "github.com/0xsoniclabs/consensus/consensus"
+
+const (
+	elKey = "s"
+	// electionStatePrefix is the table prefix of EpochTable.ElectionState
+	electionStatePrefix = "E"
+)
+
+// ElectionState is a snapshot of the in-memory election state of the current epoch.
+// It allows to resume the election on restart without replaying all the undecided roots.
+type ElectionState struct {
+	FrameToDeliver consensus.Frame
+	// Roots are the roots of frames since FrameToDeliver, which were voted when the snapshot was taken
+	Roots []consensus.EventHash
+	Votes []ElectionVote
+	// Atropoi are decided, but not yet delivered atropoi
+	Atropoi []ElectionAtropos
+}
+
+// ElectionVote is the vote matrix of a root.
+type ElectionVote struct {
+	Frame                consensus.Frame
+	ValidatorIdx         consensus.ValidatorIndex
+	Root                 consensus.EventHash
+	FrameToDeliverOffset consensus.Frame
+	// VoteMatrix holds int32 values in two's complement, as RLP supports only unsigned integers
+	VoteMatrix []uint32
+}
+
+// ElectionAtropos is a decided atropos.
+type ElectionAtropos struct {
+	Frame   consensus.Frame
+	Atropos consensus.EventHash
+}
+
+// SetElectionState stores the election snapshot in the epoch DB.
+func (s *Store) SetElectionState(v *ElectionState) error {
+	return s.set(s.EpochTable.ElectionState, []byte(elKey), v)
+}
+
+// GetElectionState returns the stored election snapshot, or nil if there's none.
+func (s *Store) GetElectionState() (*ElectionState, error) {
+	w, err := s.get(s.EpochTable.ElectionState, []byte(elKey), &ElectionState{})
+	if err != nil || w == nil {
+		return nil, err
+	}
+	return w.(*ElectionState), nil
+}
@@ -0,0 +1,58 @@
+package consensusstore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+func TestElectionState_NotStored(t *testing.T) {
+	store := NewMemStore()
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetElectionState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Fatalf("unexpected election state retrieved from empty store: %v", got)
+	}
+}
+
+func TestElectionState_ConsistentPersistingAndRetrieval(t *testing.T) {
+	store := NewMemStore()
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ElectionState{
+		FrameToDeliver: 5,
+		Roots:          []consensus.EventHash{{1}, {2}},
+		Votes: []ElectionVote{
+			{Frame: 5, ValidatorIdx: 1, Root: consensus.EventHash{1}, FrameToDeliverOffset: 5, VoteMatrix: []uint32{}},
+			{Frame: 6, ValidatorIdx: 0, Root: consensus.EventHash{2}, FrameToDeliverOffset: 4, VoteMatrix: []uint32{1, uint32(1<<32 - 3), 0, 2}},
+		},
+		Atropoi: []ElectionAtropos{{Frame: 6, Atropos: consensus.EventHash{3}}},
+	}
+	if err := store.SetElectionState(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetElectionState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected election state retrieved, expected: %v, got: %v", want, got)
+	}
+
+	// the snapshot belongs to the epoch
+	if err := store.OpenEpochDB(2); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.GetElectionState(); err != nil || got != nil {
+		t.Fatalf("unexpected election state retrieved from new epoch: %v, %v", got, err)
+	}
+}
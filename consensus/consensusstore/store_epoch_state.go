@@ -30,42 +30,59 @@ func (es EpochState) String() string {
 }
 
 // SetEpochState stores epoch.
-func (s *Store) SetEpochState(e *EpochState) {
+func (s *Store) SetEpochState(e *EpochState) error {
 	s.cache.EpochState = e
-	s.setEpochState([]byte(esKey), e)
+	return s.setEpochState([]byte(esKey), e)
 }
 
 // GetEpochState returns stored epoch.
-func (s *Store) GetEpochState() *EpochState {
+// Returns ErrNoGenesis if genesis isn't applied.
+func (s *Store) GetEpochState() (*EpochState, error) {
+	if s.openErr != nil {
+		return nil, s.openErr
+	}
 	if s.cache.EpochState != nil {
-		return s.cache.EpochState
+		return s.cache.EpochState, nil
+	}
+	e, err := s.getEpochState([]byte(esKey))
+	if err != nil {
+		return nil, err
 	}
-	e := s.getEpochState([]byte(esKey))
 	if e == nil {
-		s.crit(ErrNoGenesis)
+		return nil, s.critical(ErrNoGenesis)
 	}
 	s.cache.EpochState = e
-	return e
+	return e, nil
 }
 
-func (s *Store) setEpochState(key []byte, e *EpochState) {
-	s.set(s.table.EpochState, key, e)
+func (s *Store) setEpochState(key []byte, e *EpochState) error {
+	return s.set(s.table.EpochState, key, e)
 }
 
-func (s *Store) getEpochState(key []byte) *EpochState {
-	w, exists := s.get(s.table.EpochState, key, &EpochState{}).(*EpochState)
-	if !exists {
-		return nil
+func (s *Store) getEpochState(key []byte) (*EpochState, error) {
+	w, err := s.get(s.table.EpochState, key, &EpochState{})
+	if err != nil || w == nil {
+		return nil, err
 	}
-	return w
+	return w.(*EpochState), nil
 }
 
-// GetEpoch returns current epoch
+// GetEpoch returns current epoch, see GetEpochState. It's for the callers, which have read the state before,
+// e.g. the engine does it on Bootstrap and on every event. Otherwise a read failure is only reported to crit, and 0 is returned.
 func (s *Store) GetEpoch() consensus.Epoch {
-	return s.GetEpochState().Epoch
+	e, err := s.GetEpochState()
+	if err != nil {
+		return 0
+	}
+	return e.Epoch
 }
 
-// GetValidators returns current validators
+// GetValidators returns current validators, see GetEpoch. If the epoch state can't be read, the failure is reported to crit,
+// and the empty set is returned.
 func (s *Store) GetValidators() *consensus.Validators {
-	return s.GetEpochState().Validators
+	e, err := s.GetEpochState()
+	if err != nil {
+		return consensus.NewValidatorsBuilder().Build()
+	}
+	return e.Validators
 }
@@ -1,6 +1,7 @@
 package consensusstore
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -9,13 +10,13 @@ import (
 
 func TestStore_ConsistentEpochStatePersistingAndRetrieving(t *testing.T) {
 	store := NewMemStore()
-	epochState := populateWithEpochState(store)
-	if want, got := epochState, store.GetEpochState(); want.Epoch != got.Epoch || want.Validators.TotalWeight() != got.Validators.TotalWeight() {
+	epochState := populateWithEpochState(t, store)
+	if want, got := epochState, mustGetEpochState(t, store); want.Epoch != got.Epoch || want.Validators.TotalWeight() != got.Validators.TotalWeight() {
 		t.Fatalf("incorrect epoch state retrieved. expected: %v, got: %v", want, got)
 	}
 	// force non-cached retrieval
 	store.cache.EpochState = nil
-	if want, got := epochState, store.GetEpochState(); want.Epoch != got.Epoch || want.Validators.TotalWeight() != got.Validators.TotalWeight() {
+	if want, got := epochState, mustGetEpochState(t, store); want.Epoch != got.Epoch || want.Validators.TotalWeight() != got.Validators.TotalWeight() {
 		t.Fatalf("incorrect epoch state retrieved. expected: %v, got: %v", want, got)
 	}
 	if want, got := epochState.Epoch, store.GetEpoch(); want != got {
@@ -28,16 +29,48 @@ func TestStore_ConsistentEpochStatePersistingAndRetrieving(t *testing.T) {
 
 func TestStore_ConsistentEpochStateFormatting(t *testing.T) {
 	store := NewMemStore()
-	epochState := populateWithEpochState(store)
+	epochState := populateWithEpochState(t, store)
 	if want, got := fmt.Sprintf("%d/%s", epochState.Epoch, epochState.Validators.String()), epochState.String(); want != got {
 		t.Fatalf("unexpectedly formatted epochState, expected: %s, got: %s", want, got)
 	}
 }
 
-func populateWithEpochState(store *Store) *EpochState {
+func TestStore_MissingEpochState(t *testing.T) {
+	store := NewMemStore()
+	var reported []error
+	store.crit = func(err error) {
+		reported = append(reported, err)
+	}
+	if _, err := store.GetEpochState(); !errors.Is(err, ErrNoGenesis) {
+		t.Fatalf("expected no genesis error, got: %v", err)
+	}
+	// the accessors don't panic with a crit, which doesn't panic
+	if got := store.GetEpoch(); got != 0 {
+		t.Fatalf("expected zero epoch, got: %d", got)
+	}
+	if got := store.GetValidators().Len(); got != 0 {
+		t.Fatalf("expected no validators, got: %d", got)
+	}
+	if len(reported) == 0 {
+		t.Fatalf("expected the error to be reported to crit")
+	}
+}
+
+func mustGetEpochState(t *testing.T, store *Store) *EpochState {
+	t.Helper()
+	epochState, err := store.GetEpochState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return epochState
+}
+
+func populateWithEpochState(t *testing.T, store *Store) *EpochState {
 	validatorBuilder := consensus.NewValidatorsBuilder()
 	validatorBuilder.Set(1, 10)
 	epochState := &EpochState{Epoch: 3, Validators: validatorBuilder.Build()}
-	store.SetEpochState(epochState)
+	if err := store.SetEpochState(epochState); err != nil {
+		t.Fatal(err)
+	}
 	return epochState
 }
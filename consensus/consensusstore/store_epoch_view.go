@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/dagindexer"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/readonlystore"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// ErrEpochNotArchived is returned when a view is opened for an epoch which isn't retained by the archive.
+var ErrEpochNotArchived = errors.New("epoch isn't archived")
+
+// EpochView is a read-only view of an archived epoch.
+// The vectors are read from the epoch DB, where the DAG index is written through before the epoch is archived,
+// see Store.SetIndexPersister. There are none with dagindexer.MemoryBackend.
+type EpochView struct {
+	store   *Store
+	epoch   consensus.Epoch
+	vectors *dagindexer.VectorsView
+	table   struct {
+		Roots          kvdb.Store `table:"r"`
+		VectorIndex    kvdb.Store `table:"v"`
+		ConfirmedEvent kvdb.Store `table:"C"`
+	}
+}
+
+// OpenEpochView opens a read-only view of the archived epoch, see StoreConfig.Archive.
+func (s *Store) OpenEpochView(epoch consensus.Epoch) (*EpochView, error) {
+	if s.cfg.Archive == nil {
+		return nil, fmt.Errorf("%w: epoch %d, archive is disabled", ErrEpochNotArchived, epoch)
+	}
+	db, err := s.cfg.Archive.Get(epoch)
+	if err != nil {
+		return nil, s.ioErr(err)
+	}
+	if db == nil {
+		return nil, fmt.Errorf("%w: epoch %d", ErrEpochNotArchived, epoch)
+	}
+	v := &EpochView{
+		store: s,
+		epoch: epoch,
+	}
+	table.MigrateTables(&v.table, readonlystore.Wrap(db))
+	v.vectors = dagindexer.NewVectorsView(v.table.VectorIndex)
+	return v, nil
+}
+
+// Epoch returns the epoch of the view.
+func (v *EpochView) Epoch() consensus.Epoch {
+	return v.epoch
+}
+
+// GetFrameRoots returns all the roots in the specified frame.
+func (v *EpochView) GetFrameRoots(frame consensus.Frame) ([]RootDescriptor, error) {
+	return v.store.readFrameRoots(v.table.Roots, frame)
+}
+
+// GetEventConfirmedOn returns the frame which confirmed the event, or 0 if it isn't confirmed.
+func (v *EpochView) GetEventConfirmedOn(e consensus.EventHash) (consensus.Frame, error) {
+	return v.store.readEventConfirmedOn(v.table.ConfirmedEvent, e)
+}
+
+// Vectors returns the vector clocks of the epoch's events.
+func (v *EpochView) Vectors() *dagindexer.VectorsView {
+	return v.vectors
+}
@@ -12,28 +12,34 @@ package consensusstore
 
 import (
 	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
 )
 
 // SetEventConfirmedOn stores confirmed event ctype.
-func (s *Store) SetEventConfirmedOn(e consensus.EventHash, on consensus.Frame) {
+func (s *Store) SetEventConfirmedOn(e consensus.EventHash, on consensus.Frame) error {
 	key := e.Bytes()
 
 	if err := s.EpochTable.ConfirmedEvent.Put(key, on.Bytes()); err != nil {
-		s.crit(err)
+		return s.ioErr(err)
 	}
+	return nil
 }
 
 // GetEventConfirmedOn returns confirmed event ctype.
-func (s *Store) GetEventConfirmedOn(e consensus.EventHash) consensus.Frame {
+func (s *Store) GetEventConfirmedOn(e consensus.EventHash) (consensus.Frame, error) {
+	return s.readEventConfirmedOn(s.EpochTable.ConfirmedEvent, e)
+}
+
+func (s *Store) readEventConfirmedOn(confirmedTable kvdb.Store, e consensus.EventHash) (consensus.Frame, error) {
 	key := e.Bytes()
 
-	buf, err := s.EpochTable.ConfirmedEvent.Get(key)
+	buf, err := confirmedTable.Get(key)
 	if err != nil {
-		s.crit(err)
+		return 0, s.ioErr(err)
 	}
 	if buf == nil {
-		return 0
+		return 0, nil
 	}
 
-	return consensus.BytesToFrame(buf)
+	return consensus.BytesToFrame(buf), nil
 }
@@ -14,7 +14,11 @@ func TestEventConfirmedOn_NonExistingEvent(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if want, got := consensus.Frame(0), store.GetEventConfirmedOn(consensus.EventHash{}); want != got {
+	got, err := store.GetEventConfirmedOn(consensus.EventHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := consensus.Frame(0); want != got {
 		t.Fatalf("unexpected frame retrieved for non-existing event hash, expected: %d, got: %d", want, got)
 	}
 }
@@ -32,12 +36,18 @@ func TestEventConfirmedOn_ConsistentPersistingAndRetrieval(t *testing.T) {
 		eventHash := consensus.EventHash{}
 		binary.LittleEndian.PutUint16(eventHash[:16], uint16(frame))
 		binary.LittleEndian.PutUint16(eventHash[16:], uint16(i))
-		store.SetEventConfirmedOn(eventHash, frame)
+		if err := store.SetEventConfirmedOn(eventHash, frame); err != nil {
+			t.Fatal(err)
+		}
 		expectedFrames[eventHash] = frame
 	}
 
 	for eventHash, want := range expectedFrames {
-		if got := store.GetEventConfirmedOn(eventHash); want != got {
+		got, err := store.GetEventConfirmedOn(eventHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
 			t.Fatalf("unexpected frame retrieved for event: %s, expected: %d, got: %d", eventHash.String(), want, got)
 		}
 	}
@@ -22,28 +22,40 @@ type LastDecidedState struct {
 
 // SetLastDecidedState save LastDecidedState.
 // LastDecidedState is seldom read; so no cache.
-func (s *Store) SetLastDecidedState(v *LastDecidedState) {
+func (s *Store) SetLastDecidedState(v *LastDecidedState) error {
 	s.cache.LastDecidedState = v
 
-	s.set(s.table.LastDecidedState, []byte(dsKey), v)
+	return s.set(s.table.LastDecidedState, []byte(dsKey), v)
 }
 
 // GetLastDecidedState returns stored LastDecidedState.
-// State is seldom read; so no cache.
-func (s *Store) GetLastDecidedState() *LastDecidedState {
+// Returns ErrNoGenesis if genesis isn't applied.
+func (s *Store) GetLastDecidedState() (*LastDecidedState, error) {
+	if s.openErr != nil {
+		return nil, s.openErr
+	}
 	if s.cache.LastDecidedState != nil {
-		return s.cache.LastDecidedState
+		return s.cache.LastDecidedState, nil
 	}
 
-	w, exists := s.get(s.table.LastDecidedState, []byte(dsKey), &LastDecidedState{}).(*LastDecidedState)
-	if !exists {
-		s.crit(ErrNoGenesis)
+	w, err := s.get(s.table.LastDecidedState, []byte(dsKey), &LastDecidedState{})
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, s.critical(ErrNoGenesis)
 	}
 
-	s.cache.LastDecidedState = w
-	return w
+	s.cache.LastDecidedState = w.(*LastDecidedState)
+	return s.cache.LastDecidedState, nil
 }
 
+// GetLastDecidedFrame returns the last decided frame, see GetLastDecidedState. It's for the callers, which have read
+// the state before, see GetEpoch. Otherwise a read failure is only reported to crit, and 0 is returned.
 func (s *Store) GetLastDecidedFrame() consensus.Frame {
-	return s.GetLastDecidedState().LastDecidedFrame
+	ds, err := s.GetLastDecidedState()
+	if err != nil {
+		return 0
+	}
+	return ds.LastDecidedFrame
 }
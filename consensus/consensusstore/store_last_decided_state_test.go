@@ -1,6 +1,7 @@
 package consensusstore
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/0xsoniclabs/consensus/consensus"
@@ -8,13 +9,13 @@ import (
 
 func TestStore_StatesPersisting(t *testing.T) {
 	store := NewMemStore()
-	lastDecidedState := populateWithLastDecidedState(store)
-	if want, got := lastDecidedState, store.GetLastDecidedState(); want.LastDecidedFrame != got.LastDecidedFrame {
+	lastDecidedState := populateWithLastDecidedState(t, store)
+	if want, got := lastDecidedState, mustGetLastDecidedState(t, store); want.LastDecidedFrame != got.LastDecidedFrame {
 		t.Fatalf("incorrect last decided state retrieved. expected: %v, got: %v", want, got)
 	}
 	// force non-cached retrieval
 	store.cache.LastDecidedState = nil
-	if want, got := lastDecidedState, store.GetLastDecidedState(); want.LastDecidedFrame != got.LastDecidedFrame {
+	if want, got := lastDecidedState, mustGetLastDecidedState(t, store); want.LastDecidedFrame != got.LastDecidedFrame {
 		t.Fatalf("incorrect last decided state retrieved. expected: %v, got: %v", want, got)
 	}
 	if want, got := lastDecidedState.LastDecidedFrame, store.GetLastDecidedFrame(); want != got {
@@ -22,10 +23,32 @@ func TestStore_StatesPersisting(t *testing.T) {
 	}
 }
 
-func populateWithLastDecidedState(store *Store) *LastDecidedState {
+func TestStore_MissingLastDecidedState(t *testing.T) {
+	store := NewMemStore()
+	store.crit = func(error) {}
+	if _, err := store.GetLastDecidedState(); !errors.Is(err, ErrNoGenesis) {
+		t.Fatalf("expected no genesis error, got: %v", err)
+	}
+	if got := store.GetLastDecidedFrame(); got != 0 {
+		t.Fatalf("expected zero frame, got: %d", got)
+	}
+}
+
+func mustGetLastDecidedState(t *testing.T, store *Store) *LastDecidedState {
+	t.Helper()
+	lastDecidedState, err := store.GetLastDecidedState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lastDecidedState
+}
+
+func populateWithLastDecidedState(t *testing.T, store *Store) *LastDecidedState {
 	validatorBuilder := consensus.NewValidatorsBuilder()
 	validatorBuilder.Set(1, 10)
 	lastDecidedState := &LastDecidedState{LastDecidedFrame: 5}
-	store.SetLastDecidedState(lastDecidedState)
+	if err := store.SetLastDecidedState(lastDecidedState); err != nil {
+		t.Fatal(err)
+	}
 	return lastDecidedState
 }
@@ -0,0 +1,84 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+const prunedFrameKey = "f"
+
+// GetPrunedFrame returns the last frame of the current epoch, whose roots are pruned, or 0 if none are.
+func (s *Store) GetPrunedFrame() (consensus.Frame, error) {
+	if s.cache.PrunedFrame != nil {
+		return *s.cache.PrunedFrame, nil
+	}
+	frame := consensus.Frame(0)
+	if _, err := s.get(s.EpochTable.PruningState, []byte(prunedFrameKey), &frame); err != nil {
+		return 0, err
+	}
+	s.cache.PrunedFrame = &frame
+	return frame, nil
+}
+
+// PruneRoots deletes the roots of the frames up to the specified one, inclusive.
+// Returns the number of the deleted roots and their size in bytes.
+// Not safe for concurrent use due to the complex mutable cache!
+func (s *Store) PruneRoots(upTo consensus.Frame) (int, uint64, error) {
+	pruned, err := s.GetPrunedFrame()
+	if err != nil {
+		return 0, 0, err
+	}
+	if upTo <= pruned {
+		return 0, 0, nil
+	}
+
+	// the frame is saved first, so the pruned frames are never mistaken for empty ones
+	if err := s.set(s.EpochTable.PruningState, []byte(prunedFrameKey), upTo); err != nil {
+		return 0, 0, err
+	}
+	s.cache.PrunedFrame = &upTo
+	s.cache.FrameRoots.Purge()
+
+	roots, size := 0, uint64(0)
+	batch := s.EpochTable.Roots.NewBatch()
+	defer batch.Reset()
+	it := s.EpochTable.Roots.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != frameSize+validatorIDSize+eventIDSize {
+			return 0, 0, s.inconsistencyErr("roots table: incorrect key len=%d", len(key))
+		}
+		if consensus.BytesToFrame(key[:frameSize]) > upTo {
+			break
+		}
+		if err := batch.Delete(key); err != nil {
+			return 0, 0, s.ioErr(err)
+		}
+		roots++
+		size += uint64(len(key) + len(it.Value()))
+		if batch.ValueSize() >= kvdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, 0, s.ioErr(err)
+			}
+			batch.Reset()
+		}
+	}
+	if it.Error() != nil {
+		return 0, 0, s.ioErr(it.Error())
+	}
+	if err := batch.Write(); err != nil {
+		return 0, 0, s.ioErr(err)
+	}
+	return roots, size, nil
+}
@@ -0,0 +1,65 @@
+package consensusstore
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+func TestStore_PruneRoots(t *testing.T) {
+	const numFrames = 20
+	store := NewMemStore()
+	rootsExpected := populateWithRoots(t, store, numFrames, 5)
+	// fill the cache
+	for frame := consensus.Frame(0); frame < numFrames; frame++ {
+		getFrameRoots(t, store, frame)
+	}
+
+	const upTo = consensus.Frame(9)
+	expectedRoots := 0
+	for frame := consensus.Frame(0); frame <= upTo; frame++ {
+		expectedRoots += len(rootsExpected[frame])
+	}
+	roots, size, err := store.PruneRoots(upTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots != expectedRoots {
+		t.Fatalf("unexpected number of pruned roots, want: %d, got: %d", expectedRoots, roots)
+	}
+	if size != uint64(roots*(frameSize+validatorIDSize+eventIDSize)) {
+		t.Fatalf("unexpected size of pruned roots: %d", size)
+	}
+
+	for frame := consensus.Frame(0); frame < numFrames; frame++ {
+		want := rootsExpected[frame]
+		if frame <= upTo {
+			want = nil
+		}
+		if got := simplifyAndSortRoots(getFrameRoots(t, store, frame)); !slices.Equal(want, got) {
+			t.Fatalf("unexpected roots of frame %d, expected: %v, got: %v", frame, want, got)
+		}
+	}
+
+	// the pruned frame is persisted
+	store.cache.PrunedFrame = nil
+	if pruned, err := store.GetPrunedFrame(); err != nil || pruned != upTo {
+		t.Fatalf("unexpected pruned frame: %d, %v", pruned, err)
+	}
+	// pruning below the pruned frame is a no-op
+	if roots, size, err := store.PruneRoots(upTo - 1); err != nil || roots != 0 || size != 0 {
+		t.Fatalf("unexpected repeated pruning: %d, %d, %v", roots, size, err)
+	}
+	if pruned, err := store.GetPrunedFrame(); err != nil || pruned != upTo {
+		t.Fatalf("unexpected pruned frame: %d, %v", pruned, err)
+	}
+
+	// a new epoch isn't pruned
+	if err := store.OpenEpochDB(2); err != nil {
+		t.Fatal(err)
+	}
+	if pruned, err := store.GetPrunedFrame(); err != nil || pruned != 0 {
+		t.Fatalf("unexpected pruned frame of a new epoch: %d, %v", pruned, err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ErrNotDecided is returned when the consensus is rewound to a frame, which isn't decided yet.
+var ErrNotDecided = errors.New("frame isn't decided")
+
+// Rewind rewinds the current epoch to the decided frame: the later frames become undecided,
+// and their blocks and the confirmations of their events are deleted.
+// The roots are kept, so the same frames are decided again once the election is replayed.
+// The rewound state is journaled atomically with the blocks deletion, the interrupted rewind is completed by RecoverTransition.
+// Returns consensus.ErrPruned if the roots of the following frame are pruned.
+func (s *Store) Rewind(frame consensus.Frame) error {
+	lastDecided := s.GetLastDecidedFrame()
+	if frame > lastDecided {
+		return fmt.Errorf("%w: frame %d, the last decided frame is %d", ErrNotDecided, frame, lastDecided)
+	}
+	if frame == lastDecided {
+		return nil
+	}
+	pruned, err := s.GetPrunedFrame()
+	if err != nil {
+		return err
+	}
+	if frame < pruned {
+		return fmt.Errorf("%w: roots of frame %d", consensus.ErrPruned, frame+1)
+	}
+
+	// the blocks of an epoch are numbered sequentially, so the blocks of the rewound frames are the last ones
+	epoch := s.GetEpoch()
+	var last consensus.BlockID
+	err = s.ForEachBlock(epoch, epoch, func(n consensus.BlockID, block *BlockRecord) bool {
+		if block.Frame > frame {
+			return false
+		}
+		last = n
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if last == 0 {
+		// the epoch may have no blocks of the kept frames
+		if last, err = s.lastBlockBefore(epoch); err != nil {
+			return err
+		}
+	}
+
+	t := &Transition{
+		Epoch:     epoch,
+		Frame:     frame,
+		LastBlock: last,
+		Phase:     TransitionRewinding,
+	}
+	ds := &LastDecidedState{LastDecidedFrame: frame}
+	err = s.writeAtomically(func(tables *mainTables) error {
+		if err := s.set(tables.LastDecidedState, []byte(dsKey), ds); err != nil {
+			return err
+		}
+		if err := s.deleteBlocksAfter(tables, epoch, last); err != nil {
+			return err
+		}
+		return s.set(tables.Journal, []byte(transitionKey), t)
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.LastDecidedState = ds
+	s.cache.LastBlock = nil
+	s.cache.Transition = t
+
+	return s.completeRewind(t)
+}
+
+// completeRewind deletes the confirmations of the rewound frames, and the journal record.
+func (s *Store) completeRewind(t *Transition) error {
+	if err := s.deleteConfirmedAfter(t.Frame); err != nil {
+		return err
+	}
+	return s.endTransition()
+}
+
+// lastBlockBefore returns the number of the last block before the epoch, or 0 if there's none.
+func (s *Store) lastBlockBefore(epoch consensus.Epoch) (consensus.BlockID, error) {
+	first, err := s.table.EpochBlocks.Get(epoch.Bytes())
+	if err != nil {
+		return 0, s.ioErr(err)
+	}
+	if first != nil {
+		return consensus.BytesToBlock(first) - 1, nil
+	}
+	return s.GetLastBlockNumber()
+}
@@ -0,0 +1,82 @@
+package consensusstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+func TestStore_Rewind(t *testing.T) {
+	store, mainDB, getDB := testTransitionStore(t)
+
+	// frames [1, 4] are decided
+	for frame := consensus.Frame(1); frame <= 4; frame++ {
+		atropos := consensus.EventHash{byte(frame)}
+		if err := store.BeginTransition(frame, atropos); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetEventConfirmedOn(atropos, frame); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddBlock(&BlockRecord{Epoch: 1, Frame: frame, Atropos: atropos}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CommitTransition(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Rewind(5); !errors.Is(err, ErrNotDecided) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	checkRewound := func(store *Store) {
+		t.Helper()
+		if frame := store.GetLastDecidedFrame(); frame != 2 {
+			t.Fatalf("unexpected last decided frame: %d", frame)
+		}
+		for frame := consensus.Frame(1); frame <= 4; frame++ {
+			want := frame
+			if frame > 2 {
+				want = 0
+			}
+			if got, err := store.GetEventConfirmedOn(consensus.EventHash{byte(frame)}); err != nil || got != want {
+				t.Fatalf("unexpected confirmation of frame %d: %d, %v", frame, got, err)
+			}
+		}
+		if n, err := store.GetLastBlockNumber(); err != nil || n != 2 {
+			t.Fatalf("unexpected last block: %d, %v", n, err)
+		}
+		if n, err := store.GetBlockNumber(consensus.EventHash{3}); err != nil || n != 0 {
+			t.Fatalf("block isn't rewound: %d, %v", n, err)
+		}
+	}
+	checkRewound(store)
+
+	// an interrupted rewind is completed on restart
+	if err := store.SetEventConfirmedOn(consensus.EventHash{3}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.set(store.table.Journal, []byte(transitionKey), &Transition{Epoch: 1, Frame: 2, LastBlock: 2, Phase: TransitionRewinding}); err != nil {
+		t.Fatal(err)
+	}
+	store = NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if err := store.OpenEpochDB(store.GetEpoch()); err != nil {
+		t.Fatal(err)
+	}
+	if transition, err := store.RecoverTransition(); err != nil || transition == nil || transition.Phase != TransitionRewinding {
+		t.Fatalf("unexpected recovered transition: %+v, %v", transition, err)
+	}
+	checkRewound(store)
+
+	// the roots of the following frame must be kept
+	if _, _, err := store.PruneRoots(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Rewind(1); !errors.Is(err, consensus.ErrPruned) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
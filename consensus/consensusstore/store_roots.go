@@ -12,9 +12,9 @@ package consensusstore
 
 import (
 	"bytes"
-	"fmt"
 
 	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
 )
 
 const (
@@ -39,19 +39,20 @@ func rootRecordKey(frame consensus.Frame, root *RootDescriptor) []byte {
 
 // AddRoot stores the new root
 // Not safe for concurrent use due to the complex mutable cache!
-func (s *Store) AddRoot(root consensus.Event) {
-	s.addRoot(root, root.Frame())
+func (s *Store) AddRoot(root consensus.Event) error {
+	return s.addRoot(root, root.Frame())
 }
 
-func (s *Store) addRoot(root consensus.Event, frame consensus.Frame) {
+func (s *Store) addRoot(root consensus.Event, frame consensus.Frame) error {
 	rootDescriptor := RootDescriptor{
 		ValidatorID: root.Creator(),
 		RootHash:    root.ID(),
 	}
 
 	if err := s.EpochTable.Roots.Put(rootRecordKey(frame, &rootDescriptor), []byte{}); err != nil {
-		s.crit(err)
+		return s.ioErr(err)
 	}
+	s.metrics.rootsAdded.Inc(1)
 
 	// Add to cache.
 	if c, ok := s.cache.FrameRoots.Get(frame); ok {
@@ -59,21 +60,36 @@ func (s *Store) addRoot(root consensus.Event, frame consensus.Frame) {
 		rootDescriptors = append(rootDescriptors, rootDescriptor)
 		s.cache.FrameRoots.Add(frame, rootDescriptors, uint(len(rootDescriptors)))
 	}
+	return nil
 }
 
 // GetFrameRoots returns all the roots in the specified frame
 // Not safe for concurrent use due to the complex mutable cache!
-func (s *Store) GetFrameRoots(frame consensus.Frame) []RootDescriptor {
+func (s *Store) GetFrameRoots(frame consensus.Frame) ([]RootDescriptor, error) {
 	if rr, ok := s.cache.FrameRoots.Get(frame); ok {
-		return rr.([]RootDescriptor)
+		s.metrics.frameRootsHits.Inc(1)
+		return rr.([]RootDescriptor), nil
 	}
+	s.metrics.frameRootsMisses.Inc(1)
+	roots, err := s.readFrameRoots(s.EpochTable.Roots, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.FrameRoots.Add(frame, roots, uint(len(roots)))
+
+	return roots, nil
+}
+
+// readFrameRoots reads the roots of the frame from the roots table, bypassing the cache.
+func (s *Store) readFrameRoots(rootsTable kvdb.Store, frame consensus.Frame) ([]RootDescriptor, error) {
 	roots := make([]RootDescriptor, 0, 100)
-	it := s.EpochTable.Roots.NewIterator(frame.Bytes(), nil)
+	it := rootsTable.NewIterator(frame.Bytes(), nil)
 	defer it.Release()
 	for it.Next() {
 		key := it.Key()
 		if len(key) != frameSize+validatorIDSize+eventIDSize {
-			s.crit(fmt.Errorf("roots table: incorrect key len=%d", len(key)))
+			return nil, s.inconsistencyErr("roots table: incorrect key len=%d", len(key))
 		}
 
 		r := RootDescriptor{
@@ -83,10 +99,7 @@ func (s *Store) GetFrameRoots(frame consensus.Frame) []RootDescriptor {
 		roots = append(roots, r)
 	}
 	if it.Error() != nil {
-		s.crit(it.Error())
+		return nil, s.ioErr(it.Error())
 	}
-
-	s.cache.FrameRoots.Add(frame, roots, uint(len(roots)))
-
-	return roots
+	return roots, nil
 }
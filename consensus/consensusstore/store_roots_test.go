@@ -35,16 +35,16 @@ func testStoreRoots_ConsistentPersistingAndRetrieval(t *testing.T, numFrames int
 	frameRetrievalOrder := rand.Perm(numFrames)
 	for _, f := range frameRetrievalOrder {
 		frame := consensus.Frame(f)
-		rootsRetrieved := simplifyAndSortRoots(store.GetFrameRoots(frame))
+		rootsRetrieved := simplifyAndSortRoots(getFrameRoots(t, store, frame))
 		if !slices.Equal(rootsExpected[frame], rootsRetrieved) {
 			t.Fatalf("unexpected roots retrieved for frame %d, expected: %v, got: %d", frame, rootsExpected[frame], rootsRetrieved)
 		}
 		// occasionally persist a root right after retrieving the frame (triggering on-Add cache)
 		if frame%additionalRootPeriod == 1 {
 			validatorId := consensus.ValidatorID(rootsExpected[frame][len(rootsExpected[frame])-1]) + 1
-			persistRoot(store, frame, validatorId)
+			persistRoot(t, store, frame, validatorId)
 			rootsExpected[frame] = append(rootsExpected[frame], validatorId)
-			rootsRetrieved := simplifyAndSortRoots(store.GetFrameRoots(frame))
+			rootsRetrieved := simplifyAndSortRoots(getFrameRoots(t, store, frame))
 			if !slices.Equal(rootsExpected[frame], rootsRetrieved) {
 				t.Fatalf("unexpected roots retrieved for frame %d, expected: %v, got: %d", frame, rootsExpected[frame], rootsRetrieved)
 			}
@@ -60,7 +60,7 @@ func populateWithRoots(t *testing.T, store *Store, numFrames int, meanRootsPerFr
 	for i := range meanRootsPerFrame * numFrames {
 		// randomize frame insertion order
 		frame, validatorID := consensus.Frame(rand.IntN(numFrames)), consensus.ValidatorID(i)
-		persistRoot(store, frame, validatorID)
+		persistRoot(t, store, frame, validatorID)
 		rootsExpected[frame] = append(rootsExpected[frame], validatorID)
 	}
 	return rootsExpected
@@ -75,11 +75,21 @@ func simplifyAndSortRoots(rootDescriptors []RootDescriptor) []consensus.Validato
 	return roots
 }
 
-func persistRoot(store *Store, frame consensus.Frame, validatorID consensus.ValidatorID) {
+func getFrameRoots(t *testing.T, store *Store, frame consensus.Frame) []RootDescriptor {
+	roots, err := store.GetFrameRoots(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return roots
+}
+
+func persistRoot(t *testing.T, store *Store, frame consensus.Frame, validatorID consensus.ValidatorID) {
 	root := &consensustest.TestEvent{}
 	// randomize frame insertion order
 	root.SetFrame(frame)
 	// identify roots by ValidatorId (convenient as it's part of RootDescriptor)
 	root.SetCreator(validatorID)
-	store.AddRoot(root)
+	if err := store.AddRoot(root); err != nil {
+		t.Fatal(err)
+	}
 }
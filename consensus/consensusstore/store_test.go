@@ -1,13 +1,29 @@
 package consensusstore
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
 )
 
+var errFailingStore = errors.New("failing store")
+
+// failingStore is a kvdb.Store which fails every read and write
+type failingStore struct {
+	kvdb.Store
+}
+
+func (failingStore) Get([]byte) ([]byte, error) { return nil, errFailingStore }
+
+func (failingStore) Put([]byte, []byte) error { return errFailingStore }
+
 func TestStore_Close(t *testing.T) {
 	store := NewMemStore()
-	populateWithEpochState(store)
-	populateWithLastDecidedState(store)
+	populateWithEpochState(t, store)
+	populateWithLastDecidedState(t, store)
 	populateWithRoots(t, store, 10, 10)
 	err := store.Close()
 	if err != nil {
@@ -27,15 +43,63 @@ func TestStore_Close(t *testing.T) {
 func TestStore_Drop(t *testing.T) {
 	store := NewMemStore()
 	rootsExpected := populateWithRoots(t, store, 10, 10)
-	// silence the panic
-	store.crit = func(err error) {}
 	if err := store.DropEpochDB(); err != nil {
 		t.Fatalf("store drop failed unexpectedly")
 	}
 	for frame := range rootsExpected {
-		roots := store.GetFrameRoots(frame)
+		roots, err := store.GetFrameRoots(frame)
+		if err != nil && !errors.Is(err, consensus.ErrStorageIO) {
+			t.Fatalf("unexpected error kind after dropping the epoch DB: %v", err)
+		}
 		if len(roots) > 0 {
 			t.Fatalf("retrieved non-empty frame roots after dropping the epoch DB")
 		}
 	}
 }
+
+func TestStore_StorageFailuresAreReturnedAndReported(t *testing.T) {
+	store := NewMemStore()
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	var reported []error
+	store.crit = func(err error) {
+		reported = append(reported, err)
+	}
+	store.EpochTable.ConfirmedEvent = failingStore{store.EpochTable.ConfirmedEvent}
+
+	if _, err := store.GetEventConfirmedOn(consensus.EventHash{}); !errors.Is(err, consensus.ErrStorageIO) {
+		t.Fatalf("expected storage IO error, got: %v", err)
+	}
+	if err := store.SetEventConfirmedOn(consensus.EventHash{}, 1); !errors.Is(err, consensus.ErrStorageIO) || !errors.Is(err, errFailingStore) {
+		t.Fatalf("expected wrapped storage IO error, got: %v", err)
+	}
+	if want, got := 2, len(reported); want != got {
+		t.Fatalf("expected %d errors reported to crit, got: %d", want, got)
+	}
+}
+
+func TestStore_InconsistentRootsKey(t *testing.T) {
+	store := NewMemStore()
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	frame := consensus.Frame(1)
+	if err := store.EpochTable.Roots.Put(frame.Bytes(), []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetFrameRoots(frame); !errors.Is(err, consensus.ErrInconsistentDB) {
+		t.Fatalf("expected inconsistent DB error, got: %v", err)
+	}
+}
+
+func TestStore_NegativeCacheSize(t *testing.T) {
+	cfg := LiteStoreConfig()
+	cfg.Cache.RootsFrames = -1
+	// no crit, so a panic would crash the test
+	store := NewStore(memorydb.New(), func(consensus.Epoch) kvdb.Store { return memorydb.New() }, nil, cfg)
+	populateWithRoots(t, store, 3, 3)
+	if _, err := store.GetFrameRoots(1); err != nil {
+		t.Fatal(err)
+	}
+}
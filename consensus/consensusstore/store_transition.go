@@ -0,0 +1,331 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensusstore
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+const transitionKey = "t"
+
+// ErrNoTransition is returned when a transition is committed without being begun.
+var ErrNoTransition = errors.New("no transition in progress")
+
+// TransitionPhase is the progress of a journaled transition.
+type TransitionPhase uint8
+
+const (
+	// TransitionApplying is journaled before the atropos is applied, an interrupted transition is rolled back.
+	TransitionApplying TransitionPhase = iota + 1
+	// TransitionSealing is journaled atomically with the state of the new epoch, an interrupted transition is rolled forward.
+	TransitionSealing
+	// TransitionEpochDropped is journaled once the DB of the sealed epoch is dropped.
+	TransitionEpochDropped
+	// TransitionRewinding is journaled atomically with the rewound state, an interrupted rewind is rolled forward.
+	TransitionRewinding
+)
+
+// Transition is the journal record of a frame decision, which spans several writes to the main and the epoch DBs:
+// the confirmed events and the block are written while the atropos is applied, then the decided frame is moved,
+// or the epoch is sealed and its DB is dropped. A rewind of the decided frames is journaled too, see Store.Rewind.
+type Transition struct {
+	Epoch   consensus.Epoch
+	Frame   consensus.Frame
+	Atropos consensus.EventHash
+	// LastBlock is the last block number before the atropos is applied
+	LastBlock consensus.BlockID
+	Phase     TransitionPhase
+	// NewValidators are the validators of the next epoch, if the transition seals the epoch
+	NewValidators *consensus.Validators `rlp:"nil"`
+}
+
+// BeginTransition journals the decision of the frame, it must precede the writes of the atropos application.
+// Until the transition is committed, it's rolled back by RecoverTransition.
+func (s *Store) BeginTransition(frame consensus.Frame, atropos consensus.EventHash) error {
+	lastBlock, err := s.GetLastBlockNumber()
+	if err != nil {
+		return err
+	}
+	t := &Transition{
+		Epoch:     s.GetEpoch(),
+		Frame:     frame,
+		Atropos:   atropos,
+		LastBlock: lastBlock,
+		Phase:     TransitionApplying,
+	}
+	if err := s.set(s.table.Journal, []byte(transitionKey), t); err != nil {
+		return err
+	}
+	s.cache.Transition = t
+	return nil
+}
+
+// CommitTransition completes the transition begun by BeginTransition.
+// If newValidators is nil, the decided frame is moved atomically with the removal of the journal record.
+// Otherwise, the new epoch state is written atomically with the journal record, then the epoch DB is dropped
+// and the DB of the new epoch is opened. RecoverTransition rolls the seal forward if it's interrupted.
+func (s *Store) CommitTransition(newValidators *consensus.Validators) error {
+	t := s.cache.Transition
+	if t == nil {
+		return ErrNoTransition
+	}
+	if newValidators == nil {
+		ds := &LastDecidedState{LastDecidedFrame: t.Frame}
+		err := s.writeAtomically(func(tables *mainTables) error {
+			if err := s.set(tables.LastDecidedState, []byte(dsKey), ds); err != nil {
+				return err
+			}
+			if err := tables.Journal.Delete([]byte(transitionKey)); err != nil {
+				return s.ioErr(err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		s.cache.LastDecidedState = ds
+		s.cache.Transition = nil
+		return nil
+	}
+
+	sealing := *t
+	sealing.Phase = TransitionSealing
+	sealing.NewValidators = newValidators
+	es := &EpochState{Epoch: t.Epoch + 1, Validators: newValidators}
+	ds := &LastDecidedState{LastDecidedFrame: consensus.FirstFrame - 1}
+	err := s.writeAtomically(func(tables *mainTables) error {
+		if err := s.set(tables.EpochState, []byte(esKey), es); err != nil {
+			return err
+		}
+		if err := s.set(tables.LastDecidedState, []byte(dsKey), ds); err != nil {
+			return err
+		}
+		return s.set(tables.Journal, []byte(transitionKey), &sealing)
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.EpochState = es
+	s.cache.LastDecidedState = ds
+	s.cache.Transition = &sealing
+
+	if err := s.DropEpochDB(); err != nil {
+		return err
+	}
+	if err := s.setTransitionPhase(&sealing, TransitionEpochDropped); err != nil {
+		return err
+	}
+	if err := s.OpenEpochDB(es.Epoch); err != nil {
+		return err
+	}
+	return s.endTransition()
+}
+
+// GetTransition returns the journaled transition, or nil if there's none.
+func (s *Store) GetTransition() (*Transition, error) {
+	w, err := s.get(s.table.Journal, []byte(transitionKey), &Transition{})
+	if err != nil || w == nil {
+		return nil, err
+	}
+	return w.(*Transition), nil
+}
+
+// RecoverTransition completes the transition interrupted by a crash, returns it, or nil if there was none.
+// A transition interrupted while the atropos was applied is rolled back: its confirmed events and blocks are deleted,
+// so the frame is decided again. An interrupted seal is rolled forward: the DB of the sealed epoch is dropped,
+// or archived if StoreConfig.Archive is set, unless it has been archived before the crash.
+// An interrupted rewind is rolled forward: the confirmations of the rewound frames are deleted.
+// The DB of the current epoch must be opened.
+func (s *Store) RecoverTransition() (*Transition, error) {
+	t, err := s.GetTransition()
+	if err != nil || t == nil {
+		return nil, err
+	}
+	switch t.Phase {
+	case TransitionApplying:
+		if t.Epoch != s.GetEpoch() {
+			return nil, s.inconsistencyErr("journal: transition of epoch %d is applied in epoch %d", t.Epoch, s.GetEpoch())
+		}
+		if err := s.rollbackTransition(t); err != nil {
+			return nil, err
+		}
+	case TransitionSealing:
+		if t.Epoch+1 != s.GetEpoch() {
+			return nil, s.inconsistencyErr("journal: seal of epoch %d is interrupted in epoch %d", t.Epoch, s.GetEpoch())
+		}
+		// the epoch may be archived before the crash, then it's reopened by the archive already
+		archived, err := s.isArchived(t.Epoch)
+		if err != nil {
+			return nil, err
+		}
+		if !archived {
+			if err := s.dropEpochDB(s.GetEpochDB(t.Epoch), t.Epoch); err != nil {
+				return nil, err
+			}
+		}
+		fallthrough
+	case TransitionEpochDropped:
+		if err := s.endTransition(); err != nil {
+			return nil, err
+		}
+	case TransitionRewinding:
+		if t.Epoch != s.GetEpoch() {
+			return nil, s.inconsistencyErr("journal: rewind of epoch %d is interrupted in epoch %d", t.Epoch, s.GetEpoch())
+		}
+		if err := s.completeRewind(t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, s.inconsistencyErr("journal: unknown transition phase %d", t.Phase)
+	}
+	return t, nil
+}
+
+// rollbackTransition deletes the confirmed events and the blocks written by the transition, and its journal record.
+func (s *Store) rollbackTransition(t *Transition) error {
+	if err := s.deleteConfirmedAfter(t.Frame - 1); err != nil {
+		return err
+	}
+	err := s.writeAtomically(func(tables *mainTables) error {
+		if err := s.deleteBlocksAfter(tables, t.Epoch, t.LastBlock); err != nil {
+			return err
+		}
+		// the block may be written partially
+		if err := tables.AtroposBlocks.Delete(t.Atropos.Bytes()); err != nil {
+			return s.ioErr(err)
+		}
+		if err := tables.Journal.Delete([]byte(transitionKey)); err != nil {
+			return s.ioErr(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.LastBlock = nil
+	s.cache.Transition = nil
+	return nil
+}
+
+// deleteBlocksAfter deletes the blocks of the epoch, which follow the last one.
+// The deletions are written to tables, while the blocks are read from the store.
+func (s *Store) deleteBlocksAfter(tables *mainTables, epoch consensus.Epoch, last consensus.BlockID) error {
+	it := s.table.Blocks.NewIterator(nil, (last + 1).Bytes())
+	defer it.Release()
+	for it.Next() {
+		block := &BlockRecord{}
+		if err := rlp.DecodeBytes(it.Value(), block); err != nil {
+			return s.inconsistencyErr("blocks table: %v", err)
+		}
+		if err := tables.Blocks.Delete(bytes.Clone(it.Key())); err != nil {
+			return s.ioErr(err)
+		}
+		if err := tables.AtroposBlocks.Delete(block.Atropos.Bytes()); err != nil {
+			return s.ioErr(err)
+		}
+	}
+	if it.Error() != nil {
+		return s.ioErr(it.Error())
+	}
+
+	first, err := s.table.EpochBlocks.Get(epoch.Bytes())
+	if err != nil {
+		return s.ioErr(err)
+	}
+	if first != nil && consensus.BytesToBlock(first) > last {
+		if err := tables.EpochBlocks.Delete(epoch.Bytes()); err != nil {
+			return s.ioErr(err)
+		}
+	}
+	return nil
+}
+
+// deleteConfirmedAfter deletes the confirmations of the events, which are confirmed after the frame.
+func (s *Store) deleteConfirmedAfter(frame consensus.Frame) error {
+	batch := s.EpochTable.ConfirmedEvent.NewBatch()
+	defer batch.Reset()
+	it := s.EpochTable.ConfirmedEvent.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if consensus.BytesToFrame(it.Value()) <= frame {
+			continue
+		}
+		if err := batch.Delete(bytes.Clone(it.Key())); err != nil {
+			return s.ioErr(err)
+		}
+		if batch.ValueSize() >= kvdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return s.ioErr(err)
+			}
+			batch.Reset()
+		}
+	}
+	if it.Error() != nil {
+		return s.ioErr(it.Error())
+	}
+	if err := batch.Write(); err != nil {
+		return s.ioErr(err)
+	}
+	return nil
+}
+
+func (s *Store) setTransitionPhase(t *Transition, phase TransitionPhase) error {
+	t.Phase = phase
+	return s.set(s.table.Journal, []byte(transitionKey), t)
+}
+
+func (s *Store) endTransition() error {
+	if err := s.table.Journal.Delete([]byte(transitionKey)); err != nil {
+		return s.ioErr(err)
+	}
+	s.cache.Transition = nil
+	return nil
+}
+
+// writeAtomically runs write over the main DB tables, and commits all its writes in a single batch.
+func (s *Store) writeAtomically(write func(tables *mainTables) error) error {
+	batch := &batchWriter{
+		Store: s.MainDB,
+		batch: s.MainDB.NewBatch(),
+	}
+	defer batch.batch.Reset()
+	tables := &mainTables{}
+	table.MigrateTables(tables, batch)
+	if err := write(tables); err != nil {
+		return err
+	}
+	if err := batch.batch.Write(); err != nil {
+		return s.ioErr(err)
+	}
+	return nil
+}
+
+// batchWriter is a kvdb.Store, which accumulates the writes in the batch.
+// The batch isn't visible to the reads.
+type batchWriter struct {
+	kvdb.Store
+	batch kvdb.Batch
+}
+
+func (b *batchWriter) Put(key []byte, value []byte) error {
+	return b.batch.Put(key, value)
+}
+
+func (b *batchWriter) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
@@ -0,0 +1,257 @@
+package consensusstore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func testTransitionStore(t *testing.T) (*Store, kvdb.Store, EpochDBProducer) {
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{}
+	getDB := func(epoch consensus.Epoch) kvdb.Store {
+		if epochDBs[epoch] == nil {
+			epochDBs[epoch] = memorydb.NewWithDrop(func() {
+				delete(epochDBs, epoch)
+			})
+		}
+		return epochDBs[epoch]
+	}
+	store := NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if err := store.ApplyGenesis(&Genesis{Epoch: 1, Validators: consensus.ArrayToValidators([]consensus.ValidatorID{1}, []consensus.Weight{1})}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	return store, mainDB, getDB
+}
+
+func TestTransition_RollbackOfInterruptedDecision(t *testing.T) {
+	store, mainDB, getDB := testTransitionStore(t)
+
+	// frame 1 is decided, frame 2 is interrupted
+	for frame := consensus.Frame(1); frame <= 2; frame++ {
+		atropos := consensus.EventHash{byte(frame)}
+		if err := store.BeginTransition(frame, atropos); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range []consensus.EventHash{atropos, {byte(frame), 1}} {
+			if err := store.SetEventConfirmedOn(e, frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := store.AddBlock(&BlockRecord{Epoch: 1, Frame: frame, Atropos: atropos}); err != nil {
+			t.Fatal(err)
+		}
+		if frame == 1 {
+			if err := store.CommitTransition(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// restart
+	store = NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if err := store.OpenEpochDB(store.GetEpoch()); err != nil {
+		t.Fatal(err)
+	}
+	transition, err := store.RecoverTransition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transition == nil || transition.Phase != TransitionApplying || transition.Frame != 2 || transition.LastBlock != 1 {
+		t.Fatalf("unexpected recovered transition: %+v", transition)
+	}
+	if frame := store.GetLastDecidedFrame(); frame != 1 {
+		t.Fatalf("unexpected last decided frame: %d", frame)
+	}
+	for _, e := range []consensus.EventHash{{1}, {1, 1}} {
+		if frame, err := store.GetEventConfirmedOn(e); err != nil || frame != 1 {
+			t.Fatalf("unexpected confirmation of the decided frame: %d, %v", frame, err)
+		}
+	}
+	for _, e := range []consensus.EventHash{{2}, {2, 1}} {
+		if frame, err := store.GetEventConfirmedOn(e); err != nil || frame != 0 {
+			t.Fatalf("confirmation isn't rolled back: %d, %v", frame, err)
+		}
+	}
+	if n, err := store.GetLastBlockNumber(); err != nil || n != 1 {
+		t.Fatalf("unexpected last block: %d, %v", n, err)
+	}
+	if n, err := store.GetBlockNumber(consensus.EventHash{2}); err != nil || n != 0 {
+		t.Fatalf("block isn't rolled back: %d, %v", n, err)
+	}
+	if transition, err := store.RecoverTransition(); err != nil || transition != nil {
+		t.Fatalf("transition is recovered twice: %+v, %v", transition, err)
+	}
+}
+
+func TestTransition_RollForwardOfInterruptedSeal(t *testing.T) {
+	store, mainDB, getDB := testTransitionStore(t)
+	validators := consensus.ArrayToValidators([]consensus.ValidatorID{2, 3}, []consensus.Weight{1, 1})
+
+	if err := store.BeginTransition(1, consensus.EventHash{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetEventConfirmedOn(consensus.EventHash{1}, 1); err != nil {
+		t.Fatal(err)
+	}
+	// interrupt the seal once the new epoch state is written
+	store.cfg.Archive = crashingArchive{}
+	func() {
+		defer func() {
+			if r := recover(); r != errCrashingArchive {
+				t.Fatalf("unexpected panic: %v", r)
+			}
+		}()
+		_ = store.CommitTransition(validators)
+	}()
+
+	// restart
+	store = NewStore(mainDB, getDB, nil, LiteStoreConfig())
+	if epoch := store.GetEpoch(); epoch != 2 {
+		t.Fatalf("unexpected epoch: %d", epoch)
+	}
+	if err := store.OpenEpochDB(store.GetEpoch()); err != nil {
+		t.Fatal(err)
+	}
+	transition, err := store.RecoverTransition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transition == nil || transition.Phase != TransitionSealing || transition.NewValidators.String() != validators.String() {
+		t.Fatalf("unexpected recovered transition: %+v", transition)
+	}
+	if frame := store.GetLastDecidedFrame(); frame != 0 {
+		t.Fatalf("unexpected last decided frame: %d", frame)
+	}
+	// the DB of the sealed epoch is dropped
+	it := getDB(1).NewIterator(nil, nil)
+	defer it.Release()
+	if it.Next() {
+		t.Fatal("sealed epoch DB isn't dropped")
+	}
+}
+
+// crashingJournalDB is a kvdb.Store, which crashes on the first journal write after the archived epochs are saved.
+type crashingJournalDB struct {
+	kvdb.Store
+	armed *bool
+}
+
+func (db crashingJournalDB) Put(key []byte, value []byte) error {
+	if *db.armed && bytes.HasPrefix(key, []byte("j")) {
+		panic(errCrashingArchive)
+	}
+	if bytes.HasPrefix(key, []byte(archivedEpochsPrefix)) {
+		*db.armed = true
+	}
+	return db.Store.Put(key, value)
+}
+
+func TestTransition_RollForwardOfArchivedSeal(t *testing.T) {
+	mainDB := memorydb.New()
+	epochDBs := map[consensus.Epoch]kvdb.Store{}
+	getDB := func(epoch consensus.Epoch) kvdb.Store {
+		if epochDBs[epoch] == nil {
+			epochDBs[epoch] = memorydb.NewWithDrop(func() {
+				delete(epochDBs, epoch)
+			})
+		}
+		return epochDBs[epoch]
+	}
+	cfg := LiteStoreConfig()
+	cfg.Archive = KeepAllEpochs()
+	armed := false
+	store := NewStore(crashingJournalDB{mainDB, &armed}, getDB, nil, cfg)
+	// the archived epochs are saved by NewStore when the archive is reopened
+	armed = false
+	if err := store.ApplyGenesis(&Genesis{Epoch: 1, Validators: consensus.ArrayToValidators([]consensus.ValidatorID{1}, []consensus.Weight{1})}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(1); err != nil {
+		t.Fatal(err)
+	}
+	validators := consensus.ArrayToValidators([]consensus.ValidatorID{2, 3}, []consensus.Weight{1, 1})
+
+	if err := store.BeginTransition(1, consensus.EventHash{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetEventConfirmedOn(consensus.EventHash{1}, 1); err != nil {
+		t.Fatal(err)
+	}
+	// interrupt the seal once the sealed epoch is archived
+	func() {
+		defer func() {
+			if r := recover(); r != errCrashingArchive {
+				t.Fatalf("unexpected panic: %v", r)
+			}
+		}()
+		_ = store.CommitTransition(validators)
+	}()
+
+	// restart
+	cfg.Archive = KeepAllEpochs()
+	store, err := OpenStore(mainDB, getDB, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.OpenEpochDB(store.GetEpoch()); err != nil {
+		t.Fatal(err)
+	}
+	transition, err := store.RecoverTransition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transition == nil || transition.Phase != TransitionSealing {
+		t.Fatalf("unexpected recovered transition: %+v", transition)
+	}
+	// the archived epoch isn't archived again, which would drop it
+	view, err := store.OpenEpochView(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame, err := view.GetEventConfirmedOn(consensus.EventHash{1}); err != nil || frame != 1 {
+		t.Fatalf("unexpected confirmation in the archived epoch: %d, %v", frame, err)
+	}
+	if epochs := cfg.Archive.Epochs(); len(epochs) != 1 || epochs[0] != 1 {
+		t.Fatalf("unexpected archived epochs: %v", epochs)
+	}
+}
+
+func TestTransition_CommitWithoutBegin(t *testing.T) {
+	store, _, _ := testTransitionStore(t)
+	if err := store.CommitTransition(nil); !errors.Is(err, ErrNoTransition) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+var errCrashingArchive = errors.New("crash")
+
+// crashingArchive crashes once an epoch DB is handed over to it.
+type crashingArchive struct{}
+
+func (crashingArchive) Archive(consensus.Epoch, kvdb.Store) error {
+	panic(errCrashingArchive)
+}
+
+func (crashingArchive) Get(consensus.Epoch) (kvdb.Store, error) {
+	return nil, nil
+}
+
+func (crashingArchive) Epochs() []consensus.Epoch {
+	return nil
+}
+
+func (crashingArchive) Restore([]consensus.Epoch, EpochDBProducer) error {
+	return nil
+}
+
+func (crashingArchive) Close() error {
+	return nil
+}
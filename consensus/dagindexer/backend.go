@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"errors"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+// ErrNotPersisted is returned by Index.Persist if the vectors aren't kept in the DB, see MemoryBackend.
+var ErrNotPersisted = errors.New("index isn't persisted")
+
+// Backend selects where Index keeps the vectors, see IndexConfig.Backend.
+type Backend uint8
+
+const (
+	// DBBackend keeps the encoded vectors in the DB, which the index is reset with, behind the caches.
+	DBBackend Backend = iota
+	// MemoryBackend keeps the vectors in Go maps, e.g. for simulations and fuzzing.
+	// The DB, which the index is reset with, isn't used, so the vectors are lost on Reset,
+	// and they can't be read by VectorsView or exported with the store.
+	MemoryBackend
+)
+
+// vectorsStore keeps the vectors and the branches of the indexed events.
+// The getters return nil or false if the record isn't found.
+type vectorsStore interface {
+	reset(db kvdb.FlushableKVStore)
+
+	getHighestBeforeSeq(id consensus.EventHash) (*HighestBeforeSeq, error)
+	getHighestBeforeTime(id consensus.EventHash) (*HighestBeforeTime, error)
+	setHighestBefore(id consensus.EventHash, vec *HighestBefore) error
+	// getLowestAfter returns the memo of the stored vector, which may be completed in place, see completeLowestAfter
+	getLowestAfter(id consensus.EventHash) (*lowestAfterMemo, error)
+	setLowestAfter(id consensus.EventHash, vec *LowestAfterSeq) error
+	// updateLowestAfter stores the vector of the memo along with the Seqs, up to which it's completed
+	updateLowestAfter(id consensus.EventHash, memo *lowestAfterMemo) error
+	getHighestBeforeObserved(id consensus.EventHash) (*HighestBeforeObserved, error)
+	setHighestBeforeObserved(id consensus.EventHash, vec *HighestBeforeObserved) error
+	getSinkTips(id consensus.EventHash) ([]sinkTip, error)
+	setSinkTips(id consensus.EventHash, tips []sinkTip) error
+
+	getEventBranch(id consensus.EventHash) (consensus.ValidatorIndex, bool, error)
+	setEventBranch(id consensus.EventHash, branchID consensus.ValidatorIndex) error
+	getBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq) (consensus.EventHash, bool, error)
+	setBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq, id consensus.EventHash) error
+	getBranchesInfo() (*BranchesInfo, error)
+	setBranchesInfo(info *BranchesInfo) error
+
+	getPrunedBefore() (consensus.Lamport, error)
+	setPrunedBefore(before consensus.Lamport) error
+	// checkPrunable returns an error if prune isn't supported
+	checkPrunable() error
+	prune(epoch consensus.Epoch, before consensus.Lamport) (int, uint64, error)
+
+	flush() error
+	// persist writes the flushed records, which are cached by the DB, through to the DB
+	persist() error
+	notFlushed() int
+	dropNotFlushed()
+	// purgeCaches drops the records, which are derived from the stored ones
+	purgeCaches()
+	close() error
+}
+
+func newVectorsStore(vi *Index) vectorsStore {
+	if vi.cfg.Backend == MemoryBackend {
+		return newMemStore()
+	}
+	return newDBStore(vi)
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+
+	"github.com/0xsoniclabs/cacheutils/simplewlru"
+	"github.com/0xsoniclabs/cacheutils/wlru"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// dbStore keeps the vectors in the DB, see DBBackend.
+type dbStore struct {
+	vi *Index
+
+	vecDb kvdb.FlushableKVStore
+	table struct {
+		HighestBeforeTime     kvdb.Store `table:"T"`
+		EventBranch           kvdb.Store `table:"b"`
+		BranchesInfo          kvdb.Store `table:"B"`
+		HighestBeforeSeq      kvdb.Store `table:"S"`
+		LowestAfterSeq        kvdb.Store `table:"s"`
+		PruningState          kvdb.Store `table:"P"`
+		BranchEvent           kvdb.Store `table:"i"`
+		HighestBeforeObserved kvdb.Store `table:"O"`
+		SinkTips              kvdb.Store `table:"t"`
+	}
+
+	cache struct {
+		HighestBeforeTime *wlru.Cache
+		HighestBeforeSeq  *simplewlru.Cache
+		LowestAfterSeq    *simplewlru.Cache
+		EventBranch       *simplewlru.Cache
+	}
+}
+
+func newDBStore(vi *Index) *dbStore {
+	s := &dbStore{vi: vi}
+	s.cache.HighestBeforeTime, _ = wlru.New(vi.cfg.Caches.HighestBeforeTimeSize, int(vi.cfg.Caches.HighestBeforeTimeSize))
+	s.cache.HighestBeforeSeq, _ = simplewlru.New(vi.cfg.Caches.HighestBeforeSeqSize, int(vi.cfg.Caches.HighestBeforeSeqSize))
+	s.cache.LowestAfterSeq, _ = simplewlru.New(vi.cfg.Caches.LowestAfterSeqSize, int(vi.cfg.Caches.HighestBeforeSeqSize))
+	s.cache.EventBranch, _ = simplewlru.New(vi.cfg.Caches.EventBranchSize, int(vi.cfg.Caches.EventBranchSize))
+	return s
+}
+
+func (s *dbStore) reset(db kvdb.FlushableKVStore) {
+	s.vecDb = db
+	table.MigrateTables(&s.table, s.vecDb)
+}
+
+func (s *dbStore) getBytes(table kvdb.Store, id consensus.EventHash) ([]byte, error) {
+	key := id.Bytes()
+	b, err := table.Get(key)
+	if err != nil {
+		return nil, s.vi.ioErr(err)
+	}
+	return b, nil
+}
+
+func (s *dbStore) setBytes(table kvdb.Store, id consensus.EventHash, b []byte) error {
+	key := id.Bytes()
+	err := table.Put(key, b)
+	if err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) setRlp(table kvdb.Store, key []byte, val interface{}) error {
+	buf, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+
+	if err := table.Put(key, buf); err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) getRlp(table kvdb.Store, key []byte, to interface{}) (interface{}, error) {
+	buf, err := table.Get(key)
+	if err != nil {
+		return nil, s.vi.ioErr(err)
+	}
+	if buf == nil {
+		return nil, nil
+	}
+
+	err = rlp.DecodeBytes(buf, to)
+	if err != nil {
+		return nil, s.vi.inconsistencyErr("%v", err)
+	}
+	return to, nil
+}
+
+func (s *dbStore) getHighestBeforeSeq(id consensus.EventHash) (*HighestBeforeSeq, error) {
+	if vSeqVal, ok := s.cache.HighestBeforeSeq.Get(id); ok {
+		return vSeqVal.(*HighestBeforeSeq), nil // Assertion needed because of raw bytes.
+	}
+	b, err := s.getBytes(s.table.HighestBeforeSeq, id)
+	if err != nil {
+		return nil, err
+	}
+	vSeqVal := HighestBeforeSeq(b)
+	if vSeqVal == nil {
+		return nil, nil
+	}
+	s.cache.HighestBeforeSeq.Add(id, &vSeqVal, uint(len(vSeqVal)))
+	return &vSeqVal, nil
+}
+
+func (s *dbStore) getHighestBeforeTime(id consensus.EventHash) (*HighestBeforeTime, error) {
+	if vTimeVal, ok := s.cache.HighestBeforeTime.Get(id); ok {
+		return vTimeVal.(*HighestBeforeTime), nil // Assertion needed because of raw bytes.
+	}
+	b, err := s.getBytes(s.table.HighestBeforeTime, id)
+	if err != nil {
+		return nil, err
+	}
+	vTimeVal := HighestBeforeTime(b)
+	if vTimeVal == nil {
+		return nil, nil
+	}
+	s.cache.HighestBeforeTime.Add(id, &vTimeVal, uint(len(vTimeVal)))
+	return &vTimeVal, nil
+}
+
+func (s *dbStore) setHighestBefore(id consensus.EventHash, vec *HighestBefore) error {
+	if err := s.setBytes(s.table.HighestBeforeTime, id, *vec.VTime); err != nil {
+		return err
+	}
+	s.cache.HighestBeforeTime.Add(id, vec.VTime, uint(len(*vec.VTime)))
+	if err := s.setBytes(s.table.HighestBeforeSeq, id, *vec.VSeq); err != nil {
+		return err
+	}
+	s.cache.HighestBeforeSeq.Add(id, vec.VSeq, uint(len(*vec.VSeq)))
+	return nil
+}
+
+func (s *dbStore) getLowestAfter(id consensus.EventHash) (*lowestAfterMemo, error) {
+	if bVal, okGet := s.cache.LowestAfterSeq.Get(id); okGet {
+		return bVal.(*lowestAfterMemo), nil // Cast needed because simplewlru uses raw interface{}.
+	}
+	raw, err := s.getBytes(s.table.LowestAfterSeq, id)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	memo, err := decodeLowestAfter(raw)
+	if err != nil {
+		return nil, s.vi.critical(err)
+	}
+	s.cache.LowestAfterSeq.Add(id, memo, uint(len(raw)))
+	return memo, nil
+}
+
+func (s *dbStore) setLowestAfter(id consensus.EventHash, vec *LowestAfterSeq) error {
+	if err := s.setBytes(s.table.LowestAfterSeq, id, encodeLowestAfter(&lowestAfterMemo{vec: *vec})); err != nil {
+		return err
+	}
+	s.cache.LowestAfterSeq.Remove(id)
+	return nil
+}
+
+func (s *dbStore) updateLowestAfter(id consensus.EventHash, memo *lowestAfterMemo) error {
+	raw := encodeLowestAfter(memo)
+	if err := s.setBytes(s.table.LowestAfterSeq, id, raw); err != nil {
+		return err
+	}
+	s.cache.LowestAfterSeq.Add(id, memo, uint(len(raw)))
+	return nil
+}
+
+func (s *dbStore) getHighestBeforeObserved(id consensus.EventHash) (*HighestBeforeObserved, error) {
+	raw, err := s.getBytes(s.table.HighestBeforeObserved, id)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	observed := HighestBeforeObserved(raw)
+	return &observed, nil
+}
+
+func (s *dbStore) setHighestBeforeObserved(id consensus.EventHash, vec *HighestBeforeObserved) error {
+	return s.setBytes(s.table.HighestBeforeObserved, id, *vec)
+}
+
+func (s *dbStore) getSinkTips(id consensus.EventHash) ([]sinkTip, error) {
+	var tips []sinkTip
+	if _, err := s.getRlp(s.table.SinkTips, id.Bytes(), &tips); err != nil {
+		return nil, err
+	}
+	return tips, nil
+}
+
+func (s *dbStore) setSinkTips(id consensus.EventHash, tips []sinkTip) error {
+	return s.setRlp(s.table.SinkTips, id.Bytes(), tips)
+}
+
+func (s *dbStore) getEventBranch(id consensus.EventHash) (consensus.ValidatorIndex, bool, error) {
+	if bVal, okGet := s.cache.EventBranch.Get(id); okGet {
+		return bVal.(consensus.ValidatorIndex), true, nil
+	}
+	b, err := s.getBytes(s.table.EventBranch, id)
+	if err != nil || b == nil {
+		return 0, false, err
+	}
+	branchID := consensus.BytesToValidator(b)
+	s.cache.EventBranch.Add(id, branchID, uint(len(b)))
+	return branchID, true, nil
+}
+
+func (s *dbStore) setEventBranch(id consensus.EventHash, branchID consensus.ValidatorIndex) error {
+	if err := s.setBytes(s.table.EventBranch, id, branchID.Bytes()); err != nil {
+		return err
+	}
+	s.cache.EventBranch.Add(id, branchID, 4)
+	return nil
+}
+
+func branchEventKey(branchID consensus.ValidatorIndex, seq consensus.Seq) []byte {
+	return append(branchID.Bytes(), seq.Bytes()...)
+}
+
+func (s *dbStore) getBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq) (consensus.EventHash, bool, error) {
+	b, err := s.table.BranchEvent.Get(branchEventKey(branchID, seq))
+	if err != nil {
+		return consensus.EventHash{}, false, s.vi.ioErr(err)
+	}
+	if b == nil {
+		return consensus.EventHash{}, false, nil
+	}
+	return consensus.BytesToEvent(b), true, nil
+}
+
+func (s *dbStore) setBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq, id consensus.EventHash) error {
+	if err := s.table.BranchEvent.Put(branchEventKey(branchID, seq), id.Bytes()); err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) getBranchesInfo() (*BranchesInfo, error) {
+	key := []byte("c")
+
+	w, err := s.getRlp(s.table.BranchesInfo, key, &BranchesInfo{})
+	if err != nil || w == nil {
+		return nil, err
+	}
+
+	return w.(*BranchesInfo), nil
+}
+
+func (s *dbStore) setBranchesInfo(info *BranchesInfo) error {
+	key := []byte("c")
+
+	return s.setRlp(s.table.BranchesInfo, key, info)
+}
+
+func (s *dbStore) getPrunedBefore() (consensus.Lamport, error) {
+	b, err := s.table.PruningState.Get([]byte(prunedBeforeKey))
+	if err != nil {
+		return 0, s.vi.ioErr(err)
+	}
+	if b == nil {
+		return 0, nil
+	}
+	return consensus.BytesToLamport(b), nil
+}
+
+func (s *dbStore) setPrunedBefore(before consensus.Lamport) error {
+	if err := s.table.PruningState.Put([]byte(prunedBeforeKey), before.Bytes()); err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) checkPrunable() error {
+	if _, ok := s.vecDb.(prunableStore); !ok {
+		return fmt.Errorf("%w: index DB doesn't support pruning", kvdb.ErrUnsupportedOp)
+	}
+	return nil
+}
+
+func (s *dbStore) prune(epoch consensus.Epoch, before consensus.Lamport) (int, uint64, error) {
+	db := s.vecDb.(prunableStore)
+	limit := append(epoch.Bytes(), before.Bytes()...)
+	records, size := 0, uint64(0)
+	for _, prefix := range prunedTables {
+		n, sz, err := db.Prune([]byte(prefix), limit)
+		if err != nil {
+			return 0, 0, s.vi.ioErr(err)
+		}
+		records += n
+		size += sz
+	}
+	return records, size, nil
+}
+
+func (s *dbStore) flush() error {
+	if err := s.vecDb.Flush(); err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) persist() error {
+	db, ok := s.vecDb.(unloadableStore)
+	if !ok {
+		// the store isn't reset yet, or its DB doesn't cache the flushed records
+		return nil
+	}
+	if err := db.Unload(); err != nil {
+		return s.vi.ioErr(err)
+	}
+	return nil
+}
+
+func (s *dbStore) notFlushed() int {
+	return s.vecDb.NotFlushedPairs()
+}
+
+func (s *dbStore) dropNotFlushed() {
+	s.vecDb.DropNotFlushed()
+}
+
+func (s *dbStore) purgeCaches() {
+	s.cache.HighestBeforeSeq.Purge()
+	s.cache.LowestAfterSeq.Purge()
+	s.cache.HighestBeforeTime.Purge()
+	s.cache.EventBranch.Purge()
+}
+
+func (s *dbStore) close() error {
+	return s.vecDb.Close()
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+)
+
+// memTable is a map, which can drop the changes made since the last flush.
+type memTable[K comparable, V any] struct {
+	records map[K]V
+	// journal holds the overwritten records of the not flushed changes
+	journal []memRecord[K, V]
+}
+
+type memRecord[K comparable, V any] struct {
+	key K
+	val V
+	ok  bool
+}
+
+func newMemTable[K comparable, V any]() memTable[K, V] {
+	return memTable[K, V]{records: make(map[K]V)}
+}
+
+func (t *memTable[K, V]) get(key K) (V, bool) {
+	val, ok := t.records[key]
+	return val, ok
+}
+
+func (t *memTable[K, V]) set(key K, val V) {
+	prev, ok := t.records[key]
+	t.journal = append(t.journal, memRecord[K, V]{key: key, val: prev, ok: ok})
+	t.records[key] = val
+}
+
+func (t *memTable[K, V]) flush() {
+	clear(t.journal)
+	t.journal = t.journal[:0]
+}
+
+func (t *memTable[K, V]) dropNotFlushed() {
+	for i := len(t.journal) - 1; i >= 0; i-- {
+		r := t.journal[i]
+		if r.ok {
+			t.records[r.key] = r.val
+		} else {
+			delete(t.records, r.key)
+		}
+	}
+	t.flush()
+}
+
+// pruneEvents deletes the records of the events below the bound, and counts them as if they were stored in the DB tables.
+func pruneEvents[V any](t *memTable[consensus.EventHash, V], epoch consensus.Epoch, before consensus.Lamport, tables int, size func(V) int) (int, uint64) {
+	records, bytes := 0, uint64(0)
+	for id, val := range t.records {
+		if id.Epoch() > epoch || id.Epoch() == epoch && id.Lamport() >= before {
+			continue
+		}
+		delete(t.records, id)
+		records += tables
+		// the table prefix and the key of every table
+		bytes += uint64(tables*(1+len(id)) + size(val))
+	}
+	return records, bytes
+}
+
+type branchSeq struct {
+	branchID consensus.ValidatorIndex
+	seq      consensus.Seq
+}
+
+// memStore keeps the vectors in Go maps, see MemoryBackend.
+// The vectors are kept in the same binary form as in DB, so they're returned without decoding or copying.
+type memStore struct {
+	highestBefore         memTable[consensus.EventHash, HighestBefore]
+	lowestAfter           memTable[consensus.EventHash, lowestAfterMemo]
+	highestBeforeObserved memTable[consensus.EventHash, HighestBeforeObserved]
+	sinkTips              memTable[consensus.EventHash, []sinkTip]
+	eventBranch           memTable[consensus.EventHash, consensus.ValidatorIndex]
+	branchEvent           memTable[branchSeq, consensus.EventHash]
+	// the singleton records have the empty key
+	branchesInfo memTable[struct{}, *BranchesInfo]
+	prunedBefore memTable[struct{}, consensus.Lamport]
+
+	// lowestAfterMemos are the completed LowestAfter vectors, dropped like the caches of dbStore
+	lowestAfterMemos map[consensus.EventHash]*lowestAfterMemo
+}
+
+func newMemStore() *memStore {
+	s := &memStore{}
+	s.reset(nil)
+	return s
+}
+
+// reset drops all the records, as the DB isn't used.
+func (s *memStore) reset(kvdb.FlushableKVStore) {
+	s.highestBefore = newMemTable[consensus.EventHash, HighestBefore]()
+	s.lowestAfter = newMemTable[consensus.EventHash, lowestAfterMemo]()
+	s.highestBeforeObserved = newMemTable[consensus.EventHash, HighestBeforeObserved]()
+	s.sinkTips = newMemTable[consensus.EventHash, []sinkTip]()
+	s.eventBranch = newMemTable[consensus.EventHash, consensus.ValidatorIndex]()
+	s.branchEvent = newMemTable[branchSeq, consensus.EventHash]()
+	s.branchesInfo = newMemTable[struct{}, *BranchesInfo]()
+	s.prunedBefore = newMemTable[struct{}, consensus.Lamport]()
+	s.lowestAfterMemos = make(map[consensus.EventHash]*lowestAfterMemo)
+}
+
+func (s *memStore) getHighestBeforeSeq(id consensus.EventHash) (*HighestBeforeSeq, error) {
+	vec, ok := s.highestBefore.get(id)
+	if !ok {
+		return nil, nil
+	}
+	return vec.VSeq, nil
+}
+
+func (s *memStore) getHighestBeforeTime(id consensus.EventHash) (*HighestBeforeTime, error) {
+	vec, ok := s.highestBefore.get(id)
+	if !ok {
+		return nil, nil
+	}
+	return vec.VTime, nil
+}
+
+func (s *memStore) setHighestBefore(id consensus.EventHash, vec *HighestBefore) error {
+	s.highestBefore.set(id, *vec)
+	return nil
+}
+
+func (s *memStore) getLowestAfter(id consensus.EventHash) (*lowestAfterMemo, error) {
+	if memo, ok := s.lowestAfterMemos[id]; ok {
+		return memo, nil
+	}
+	stored, ok := s.lowestAfter.get(id)
+	if !ok {
+		return nil, nil
+	}
+	// the memo is completed in place, so it doesn't share the stored vectors
+	memo := &lowestAfterMemo{vec: slices.Clone(stored.vec), checked: slices.Clone(stored.checked)}
+	s.lowestAfterMemos[id] = memo
+	return memo, nil
+}
+
+func (s *memStore) setLowestAfter(id consensus.EventHash, vec *LowestAfterSeq) error {
+	s.lowestAfter.set(id, lowestAfterMemo{vec: slices.Clone(*vec)})
+	delete(s.lowestAfterMemos, id)
+	return nil
+}
+
+func (s *memStore) updateLowestAfter(id consensus.EventHash, memo *lowestAfterMemo) error {
+	s.lowestAfter.set(id, lowestAfterMemo{vec: slices.Clone(memo.vec), checked: slices.Clone(memo.checked)})
+	s.lowestAfterMemos[id] = memo
+	return nil
+}
+
+func (s *memStore) getHighestBeforeObserved(id consensus.EventHash) (*HighestBeforeObserved, error) {
+	vec, ok := s.highestBeforeObserved.get(id)
+	if !ok {
+		return nil, nil
+	}
+	return &vec, nil
+}
+
+func (s *memStore) setHighestBeforeObserved(id consensus.EventHash, vec *HighestBeforeObserved) error {
+	s.highestBeforeObserved.set(id, *vec)
+	return nil
+}
+
+func (s *memStore) getSinkTips(id consensus.EventHash) ([]sinkTip, error) {
+	tips, _ := s.sinkTips.get(id)
+	return tips, nil
+}
+
+func (s *memStore) setSinkTips(id consensus.EventHash, tips []sinkTip) error {
+	s.sinkTips.set(id, slices.Clone(tips))
+	return nil
+}
+
+func (s *memStore) getEventBranch(id consensus.EventHash) (consensus.ValidatorIndex, bool, error) {
+	branchID, ok := s.eventBranch.get(id)
+	return branchID, ok, nil
+}
+
+func (s *memStore) setEventBranch(id consensus.EventHash, branchID consensus.ValidatorIndex) error {
+	s.eventBranch.set(id, branchID)
+	return nil
+}
+
+func (s *memStore) getBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq) (consensus.EventHash, bool, error) {
+	id, ok := s.branchEvent.get(branchSeq{branchID, seq})
+	return id, ok, nil
+}
+
+func (s *memStore) setBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq, id consensus.EventHash) error {
+	s.branchEvent.set(branchSeq{branchID, seq}, id)
+	return nil
+}
+
+// getBranchesInfo returns a copy, as Index modifies BranchesInfo in place until it's flushed.
+func (s *memStore) getBranchesInfo() (*BranchesInfo, error) {
+	info, ok := s.branchesInfo.get(struct{}{})
+	if !ok {
+		return nil, nil
+	}
+	return info.clone(), nil
+}
+
+func (s *memStore) setBranchesInfo(info *BranchesInfo) error {
+	s.branchesInfo.set(struct{}{}, info.clone())
+	return nil
+}
+
+func (s *memStore) getPrunedBefore() (consensus.Lamport, error) {
+	before, _ := s.prunedBefore.get(struct{}{})
+	return before, nil
+}
+
+func (s *memStore) setPrunedBefore(before consensus.Lamport) error {
+	s.prunedBefore.set(struct{}{}, before)
+	return nil
+}
+
+func (s *memStore) checkPrunable() error {
+	return nil
+}
+
+func (s *memStore) prune(epoch consensus.Epoch, before consensus.Lamport) (int, uint64, error) {
+	records, size := 0, uint64(0)
+	count := func(n int, sz uint64) {
+		records += n
+		size += sz
+	}
+	// the Seq and the time vectors are stored in separate tables
+	count(pruneEvents(&s.highestBefore, epoch, before, 2, func(vec HighestBefore) int {
+		return len(*vec.VTime) + len(*vec.VSeq)
+	}))
+	count(pruneEvents(&s.eventBranch, epoch, before, 1, func(branchID consensus.ValidatorIndex) int {
+		return len(branchID.Bytes())
+	}))
+	count(pruneEvents(&s.lowestAfter, epoch, before, 1, func(memo lowestAfterMemo) int {
+		return len(encodeLowestAfter(&memo))
+	}))
+	count(pruneEvents(&s.highestBeforeObserved, epoch, before, 1, func(vec HighestBeforeObserved) int {
+		return len(vec)
+	}))
+	count(pruneEvents(&s.sinkTips, epoch, before, 1, func(tips []sinkTip) int {
+		b, _ := rlp.EncodeToBytes(tips)
+		return len(b)
+	}))
+	return records, size, nil
+}
+
+func (s *memStore) flush() error {
+	s.highestBefore.flush()
+	s.lowestAfter.flush()
+	s.highestBeforeObserved.flush()
+	s.sinkTips.flush()
+	s.eventBranch.flush()
+	s.branchEvent.flush()
+	s.branchesInfo.flush()
+	s.prunedBefore.flush()
+	return nil
+}
+
+func (s *memStore) persist() error {
+	return fmt.Errorf("%w: vectors are kept in memory", ErrNotPersisted)
+}
+
+func (s *memStore) notFlushed() int {
+	return len(s.highestBefore.journal) + len(s.lowestAfter.journal) + len(s.highestBeforeObserved.journal) + len(s.sinkTips.journal) +
+		len(s.eventBranch.journal) + len(s.branchEvent.journal) + len(s.branchesInfo.journal) + len(s.prunedBefore.journal)
+}
+
+func (s *memStore) dropNotFlushed() {
+	s.highestBefore.dropNotFlushed()
+	s.lowestAfter.dropNotFlushed()
+	s.highestBeforeObserved.dropNotFlushed()
+	s.sinkTips.dropNotFlushed()
+	s.eventBranch.dropNotFlushed()
+	s.branchEvent.dropNotFlushed()
+	s.branchesInfo.dropNotFlushed()
+	s.prunedBefore.dropNotFlushed()
+}
+
+func (s *memStore) purgeCaches() {
+	clear(s.lowestAfterMemos)
+}
+
+func (s *memStore) close() error {
+	return nil
+}
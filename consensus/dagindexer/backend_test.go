@@ -0,0 +1,207 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// TestIndex_MemoryBackend compares the results of MemoryBackend and DBBackend on the same DAGs.
+func TestIndex_MemoryBackend(t *testing.T) {
+	for i, test := range []struct {
+		cheatersNum int
+		maxBranches int
+	}{
+		{},
+		{cheatersNum: 3},
+		{cheatersNum: 3, maxBranches: 2},
+	} {
+		t.Run(fmt.Sprintf("Test #%d", i), func(t *testing.T) {
+			testMemoryBackend(t, test.cheatersNum, test.maxBranches)
+		})
+	}
+}
+
+func testMemoryBackend(t *testing.T, cheatersNum, maxBranches int) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(7)
+	validators := consensus.ArrayToValidators(nodes, []consensus.Weight{1, 2, 3, 4, 5, 6, 7})
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+	newIndex := func(backend Backend) *Index {
+		cfg := LiteConfig()
+		cfg.Backend = backend
+		cfg.MaxBranches = maxBranches
+		vi := NewIndex(tCrit, cfg)
+		vi.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+		return vi
+	}
+	db := newIndex(DBBackend)
+	mem := newIndex(MemoryBackend)
+
+	r := rand.New(rand.NewSource(int64(cheatersNum + maxBranches))) // nolint:gosec
+	var ordered, notFlushed consensus.Events
+	consensustest.ForEachRandFork(nodes, nodes[:cheatersNum], 30, 3, 5, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+			notFlushed = append(notFlushed, e)
+			assertar.NoError(db.Add(e))
+			assertar.NoError(mem.Add(e))
+
+			switch r.Intn(10) {
+			case 0:
+				// the not flushed events are dropped by both, and added again
+				db.DropNotFlushed()
+				mem.DropNotFlushed()
+				for _, dropped := range notFlushed {
+					vec, err := mem.GetHighestBefore(dropped.ID())
+					assertar.NoError(err)
+					assertar.Nil(vec, "dropped event=%s", dropped.ID())
+				}
+				for _, dropped := range notFlushed {
+					assertar.NoError(db.Add(dropped))
+					assertar.NoError(mem.Add(dropped))
+				}
+			case 1, 2, 3:
+				assertar.NoError(db.Flush())
+				assertar.NoError(mem.Flush())
+				notFlushed = nil
+			}
+			// the results of the new event are the same
+			for j := 0; j < 3; j++ {
+				b := ordered[r.Intn(len(ordered))].ID()
+				dbRes, err := db.ForklessCause(e.ID(), b)
+				assertar.NoError(err)
+				memRes, err := mem.ForklessCause(e.ID(), b)
+				assertar.NoError(err)
+				assertar.Equal(dbRes, memRes, "%s forkless caused by %s", e.ID(), b)
+			}
+		},
+	})
+	assertar.NoError(db.Flush())
+	assertar.NoError(mem.Flush())
+	assertar.Equal(db.BranchesInfo(), mem.BranchesInfo())
+
+	for _, a := range ordered {
+		for _, get := range []func(vi *Index) (any, error){
+			func(vi *Index) (any, error) { return vi.GetHighestBefore(a.ID()) },
+			func(vi *Index) (any, error) { return vi.GetLowestAfter(a.ID()) },
+			func(vi *Index) (any, error) { return vi.GetEventBranchID(a.ID()) },
+			func(vi *Index) (any, error) { return vi.MedianTime(a.ID(), 0) },
+		} {
+			dbRes, err := get(db)
+			assertar.NoError(err)
+			memRes, err := get(mem)
+			assertar.NoError(err)
+			assertar.Equal(dbRes, memRes, "event=%s", a.ID())
+		}
+		for _, b := range ordered {
+			dbRes, err := db.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			memRes, err := mem.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(dbRes, memRes, "%s forkless caused by %s", a.ID(), b.ID())
+			dbRes, err = db.Observes(a.ID(), b.ID())
+			assertar.NoError(err)
+			memRes, err = mem.Observes(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(dbRes, memRes, "%s observes %s", a.ID(), b.ID())
+		}
+		if t.Failed() {
+			return
+		}
+	}
+
+	// the same records are pruned
+	head := ordered[len(ordered)-1].ID()
+	before := head.Lamport() / 2
+	dbRecords, dbSize, err := db.Prune(head.Epoch(), before)
+	assertar.NoError(err)
+	memRecords, memSize, err := mem.Prune(head.Epoch(), before)
+	assertar.NoError(err)
+	assertar.NotZero(memRecords)
+	assertar.Equal(dbRecords, memRecords)
+	assertar.Equal(dbSize, memSize)
+	for _, e := range ordered {
+		_, dbErr := db.GetEventBranchID(e.ID())
+		_, memErr := mem.GetEventBranchID(e.ID())
+		assertar.Equal(errors.Is(dbErr, consensus.ErrPruned), errors.Is(memErr, consensus.ErrPruned), "event=%s", e.ID())
+		assertar.Equal(dbErr == nil, memErr == nil, "event=%s", e.ID())
+	}
+}
+
+func BenchmarkIndex_Simulation_DBBackend(b *testing.B) {
+	benchSimulation(b, DBBackend)
+}
+
+func BenchmarkIndex_Simulation_MemoryBackend(b *testing.B) {
+	benchSimulation(b, MemoryBackend)
+}
+
+// benchSimulation indexes a DAG, and evaluates every new event against the recent events of every validator
+func benchSimulation(b *testing.B, backend Backend) {
+	b.Helper()
+	nodes := consensustest.GenNodes(30)
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	events := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return events[id]
+	}
+	var ordered consensus.Events
+	consensustest.ForEachRandEvent(nodes, 30, 5, nil, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events[e.ID()] = e
+			ordered = append(ordered, e)
+		},
+	})
+	cfg := LiteConfig()
+	cfg.Backend = backend
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vi := NewIndex(tCrit, cfg)
+		vi.Reset(validators, vecflushable.Wrap(memorydb.New(), 10000000), getEvent)
+		last := make(map[consensus.ValidatorID]consensus.EventHash)
+		for _, e := range ordered {
+			if err := vi.Add(e); err != nil {
+				b.Fatal(err)
+			}
+			if err := vi.Flush(); err != nil {
+				b.Fatal(err)
+			}
+			for _, root := range last {
+				if _, err := vi.ForklessCause(e.ID(), root); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if e.Seq()%5 == 0 {
+				last[e.Creator()] = e.ID()
+			}
+		}
+	}
+}
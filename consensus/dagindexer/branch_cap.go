@@ -0,0 +1,204 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+/*
+ * Under BranchCapSink, the events beyond the branch cap are merged into a single sink branch of the creator. Which events land
+ * in the sink depends on the order of arrival, so the sink is indexed to give the same results as if every fork
+ * had its own branch: the sink events observed by an event must form a chain, otherwise the fork is detected,
+ * and the highest of them (the sink tip) votes for the creator in ForklessCause.
+ *
+ * The chain is checked by walking the self-parents from the highest sink tip observed by the parents down to the lowest one,
+ * so an event pays up to the Seq distance between the tips per sink branch. In the worst case, e.g. a parent observing
+ * a stale tip, it's the number of the cheater's events in the epoch. Once the fork is detected, the sink isn't walked.
+ */
+
+// ErrBranchCapReached is returned for an event, which would exceed the branch cap of its creator under BranchCapReject.
+var ErrBranchCapReached = errors.New("branch cap reached")
+
+// BranchCapPolicy defines how the events beyond the branch cap are indexed, see IndexConfig.MaxBranches.
+type BranchCapPolicy uint8
+
+const (
+	// BranchCapSink merges the events beyond the cap into a single sink branch of the creator,
+	// which is indexed to give the same results as without the cap, whichever forks arrive first.
+	BranchCapSink BranchCapPolicy = iota
+	// BranchCapSinkForkDetected merges the events beyond the cap into a single sink branch of the creator,
+	// which is always treated as fork-detected by the events observing it.
+	// An event observing a single chain of the sink is fork-detected too, so the results depend on which forks arrive first.
+	BranchCapSinkForkDetected
+	// BranchCapReject rejects the events, which would create a branch beyond the cap, with ErrBranchCapReached.
+	// Which forks are rejected depends on the order of arrival.
+	BranchCapReject
+)
+
+// BranchCapHit describes an event, which exceeded the branch cap of its creator.
+type BranchCapHit struct {
+	Creator consensus.ValidatorID
+	Event   consensus.EventHash
+	// SelfParent of the event, nil if the event has no self-parent
+	SelfParent *consensus.EventHash
+	// Branches is the number of the creator's branches, including the sink branch
+	Branches int
+	// Rejected is true if the event is rejected, false if it's merged into the sink branch
+	Rejected bool
+}
+
+// sinkTip is the highest event of a sink branch, observed by an event.
+type sinkTip struct {
+	BranchID consensus.ValidatorIndex
+	Tip      consensus.EventHash
+}
+
+// onBranchCap merges an event, which would create a branch beyond the cap, into the sink branch of its creator.
+// The hit is reported once the event is flushed, or at once if the event is rejected.
+func (vi *Index) onBranchCap(e consensus.Event, meIdx consensus.ValidatorIndex) (consensus.ValidatorIndex, error) {
+	hit := BranchCapHit{
+		Creator:    e.Creator(),
+		Event:      e.ID(),
+		SelfParent: e.SelfParent(),
+		Branches:   len(vi.branchesInfo.BranchIDByCreators[meIdx]),
+	}
+	if vi.cfg.BranchCapPolicy == BranchCapReject {
+		hit.Rejected = true
+		vi.reportBranchCapHit(hit)
+		return 0, fmt.Errorf("%w: validator %d, event %s", ErrBranchCapReached, e.Creator(), e.ID().String())
+	}
+
+	sinkBranchID, ok := vi.branchesInfo.sinkOf(meIdx)
+	if ok {
+		vi.branchesInfo.BranchIDLastSeq[sinkBranchID] = max(vi.branchesInfo.BranchIDLastSeq[sinkBranchID], e.Seq())
+	} else {
+		sinkBranchID = vi.newBranch(e, meIdx)
+		vi.branchesInfo.SinkBranchIDs = append(vi.branchesInfo.SinkBranchIDs, sinkBranchID)
+	}
+	hit.Branches = len(vi.branchesInfo.BranchIDByCreators[meIdx])
+	vi.pendingBranchCapHits = append(vi.pendingBranchCapHits, hit)
+	return sinkBranchID, nil
+}
+
+// reportBranchCapHits reports the hits of the flushed events.
+func (vi *Index) reportBranchCapHits() {
+	for _, hit := range vi.pendingBranchCapHits {
+		vi.reportBranchCapHit(hit)
+	}
+	vi.pendingBranchCapHits = nil
+}
+
+func (vi *Index) reportBranchCapHit(hit BranchCapHit) {
+	vi.metrics.branchCapHits.Inc(1)
+	if vi.cfg.OnBranchCapHit != nil {
+		vi.cfg.OnBranchCapHit(hit)
+	}
+}
+
+// markSinksForkDetected treats the observed sink branches as fork-detected, see BranchCapSinkForkDetected.
+func (vi *Index) markSinksForkDetected(before *HighestBefore) {
+	for _, sinkBranchID := range vi.branchesInfo.SinkBranchIDs {
+		if !before.IsEmpty(sinkBranchID) {
+			vi.setForkDetected(before, sinkBranchID)
+		}
+	}
+}
+
+// fillSinkTips finds the sink tips of the event, and detects the forks of the sink branches,
+// whose observed events don't form a chain.
+func (vi *Index) fillSinkTips(e consensus.Event, meBranchID consensus.ValidatorIndex, before *HighestBefore, parentsVecs []*HighestBefore) error {
+	var tips []sinkTip
+	for _, sinkBranchID := range vi.branchesInfo.SinkBranchIDs {
+		if before.IsEmpty(sinkBranchID) || before.IsForkDetected(sinkBranchID) {
+			continue
+		}
+		candidates := make([]consensus.Event, 0, len(e.Parents())+1)
+		if meBranchID == sinkBranchID {
+			candidates = append(candidates, e)
+		}
+		for i, p := range e.Parents() {
+			if parentsVecs[i].IsEmpty(sinkBranchID) {
+				continue
+			}
+			tip, ok, err := vi.getSinkTip(p, sinkBranchID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return vi.inconsistencyErr("no sink tip of event=%s, branch=%d", p.String(), sinkBranchID)
+			}
+			tipEvent := vi.getEvent(tip)
+			if tipEvent == nil {
+				return fmt.Errorf("%w: sink tip %s", consensus.ErrEventNotFound, tip.String())
+			}
+			candidates = append(candidates, tipEvent)
+		}
+		chain, err := vi.isSelfParentChain(candidates)
+		if err != nil {
+			return err
+		}
+		if !chain {
+			vi.setForkDetected(before, sinkBranchID)
+			continue
+		}
+		tips = append(tips, sinkTip{BranchID: sinkBranchID, Tip: candidates[0].ID()})
+	}
+	if len(tips) == 0 {
+		return nil
+	}
+	return vi.store.setSinkTips(e.ID(), tips)
+}
+
+// isSelfParentChain returns true if the events of one creator lie on the self-parent chain of the highest of them.
+// The events are sorted by Seq in the descending order.
+func (vi *Index) isSelfParentChain(events []consensus.Event) (bool, error) {
+	slices.SortFunc(events, func(a, b consensus.Event) int {
+		return cmp.Compare(b.Seq(), a.Seq())
+	})
+	walk := events[0]
+	for _, e := range events[1:] {
+		for walk.Seq() > e.Seq() {
+			if walk.SelfParent() == nil {
+				return false, nil
+			}
+			selfParent := *walk.SelfParent()
+			walk = vi.getEvent(selfParent)
+			if walk == nil {
+				return false, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, selfParent.String())
+			}
+		}
+		if walk.ID() != e.ID() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getSinkTip returns the highest event of the sink branch, observed by the event.
+// Returns false if the event doesn't observe the branch or observes its fork.
+func (vi *Index) getSinkTip(id consensus.EventHash, sinkBranchID consensus.ValidatorIndex) (consensus.EventHash, bool, error) {
+	tips, err := vi.store.getSinkTips(id)
+	if err != nil {
+		return consensus.EventHash{}, false, err
+	}
+	for _, tip := range tips {
+		if tip.BranchID == sinkBranchID {
+			return tip.Tip, true, nil
+		}
+	}
+	return consensus.EventHash{}, false, nil
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// TestIndex_BranchCapSink checks the capped index gives the same results as the unlimited one,
+// whichever forks are merged into the sink branch due to the order of arrival.
+func TestIndex_BranchCapSink(t *testing.T) {
+	assertar := assert.New(t)
+
+	const maxBranches = 2
+	nodes := consensustest.GenNodes(5)
+	cheaters := nodes[:2]
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+
+	unlimited := NewIndex(tCrit, LiteConfig())
+	unlimited.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	r := consensustest.NewIntSeededRandGenerator(0)
+	var ordered consensus.Events
+	consensustest.ForEachRandFork(nodes, cheaters, 30, 3, 10, r, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+			assertar.NoError(unlimited.Add(e))
+		},
+	})
+	assertar.NoError(unlimited.Flush())
+
+	ancestors := map[consensus.EventHash]consensus.EventHashSet{}
+	for _, e := range ordered {
+		set := consensus.EventHashSet{}
+		set.Add(e.ID())
+		for _, p := range e.Parents() {
+			for id := range ancestors[p] {
+				set.Add(id)
+			}
+		}
+		ancestors[e.ID()] = set
+	}
+
+	for try := 0; try < 3; try++ {
+		events := ordered
+		if try != 0 {
+			unordered := make(consensus.Events, len(ordered))
+			for i, j := range r.Perm(len(ordered)) {
+				unordered[i] = ordered[j]
+			}
+			events = consensustest.ByParents(unordered)
+		}
+		t.Run(fmt.Sprintf("Order #%d", try), func(t *testing.T) {
+			testBranchCapSink(t, validators, getEvent, events, unlimited, ancestors, maxBranches)
+		})
+	}
+}
+
+func testBranchCapSink(t *testing.T, validators *consensus.Validators, getEvent func(consensus.EventHash) consensus.Event,
+	events consensus.Events, unlimited *Index, ancestors map[consensus.EventHash]consensus.EventHashSet, maxBranches int) {
+	assertar := assert.New(t)
+
+	registry := metrics.NewMemoryRegistry()
+	var hits []BranchCapHit
+	cfg := LiteConfig()
+	cfg.Metrics = registry
+	cfg.MaxBranches = maxBranches
+	cfg.OnBranchCapHit = func(hit BranchCapHit) {
+		hits = append(hits, hit)
+	}
+	capped := NewIndex(tCrit, cfg)
+	capped.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	// the hits of the dropped events aren't reported
+	for _, e := range events {
+		assertar.NoError(capped.Add(e))
+	}
+	capped.DropNotFlushed()
+	assertar.Empty(hits)
+	assertar.Zero(registry.CounterValue("dagindexer/branch_cap/hits"))
+
+	for _, e := range events {
+		assertar.NoError(capped.Add(e))
+	}
+	assertar.Empty(hits)
+	assertar.NoError(capped.Flush())
+
+	// the cap is hit, and the branches are bounded by the cap and the sink branch
+	assertar.NotEmpty(hits)
+	assertar.Equal(int64(len(hits)), registry.CounterValue("dagindexer/branch_cap/hits"))
+	hitEvents := consensus.EventHashSet{}
+	for _, hit := range hits {
+		assertar.False(hitEvents.Contains(hit.Event), "reported twice %s", hit.Event)
+		hitEvents.Add(hit.Event)
+		assertar.Equal(getEvent(hit.Event).Creator(), hit.Creator)
+		assertar.LessOrEqual(hit.Branches, maxBranches+1)
+	}
+	assertar.NotEmpty(capped.BranchesInfo().SinkBranchIDs)
+	for _, branches := range capped.BranchesInfo().BranchIDByCreators {
+		assertar.LessOrEqual(len(branches), maxBranches+1)
+	}
+
+	for _, a := range events {
+		// the forks are detected exactly as without the cap
+		expectedCheaters, err := testForksDetected(capped, a)
+		assertar.NoError(err)
+		cappedSeqs, err := capped.GetObservedSeqs(a.ID())
+		assertar.NoError(err)
+		unlimitedSeqs, err := unlimited.GetObservedSeqs(a.ID())
+		assertar.NoError(err)
+		assertar.Equal(unlimitedSeqs, cappedSeqs, "event=%s", a.ID())
+		for i, observed := range cappedSeqs {
+			assertar.Equal(expectedCheaters[validators.GetID(consensus.ValidatorIndex(i))], observed.ForkDetected, "event=%s", a.ID())
+		}
+
+		ids := make(consensus.EventHashes, 0, len(events))
+		for _, b := range events {
+			ids = append(ids, b.ID())
+		}
+		capped.cache.ForklessCause.Purge()
+		roots, err := capped.ForklessCauseRoots(a.ID(), ids)
+		assertar.NoError(err)
+		for i, b := range events {
+			expected, err := unlimited.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(expected, roots.Observed[i], "%s forkless caused by %s", a.ID(), b.ID())
+			res, err := capped.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(expected, res, "%s forkless caused by %s", a.ID(), b.ID())
+
+			ok, err := capped.Observes(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(ancestors[a.ID()].Contains(b.ID()), ok, "%s observes %s", a.ID(), b.ID())
+		}
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+func TestIndex_BranchCapSinkForkDetected(t *testing.T) {
+	assertar := assert.New(t)
+
+	const maxBranches = 2
+	nodes := consensustest.GenNodes(5)
+	cheaters := nodes[:2]
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+
+	var hits []BranchCapHit
+	cfg := LiteConfig()
+	cfg.MaxBranches = maxBranches
+	cfg.BranchCapPolicy = BranchCapSinkForkDetected
+	cfg.OnBranchCapHit = func(hit BranchCapHit) {
+		hits = append(hits, hit)
+	}
+	capped := NewIndex(tCrit, cfg)
+	capped.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+	unlimited := NewIndex(tCrit, LiteConfig())
+	unlimited.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	var ordered consensus.Events
+	consensustest.ForEachRandFork(nodes, cheaters, 30, 3, 10, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+			assertar.NoError(capped.Add(e))
+			assertar.NoError(unlimited.Add(e))
+		},
+	})
+	assertar.NoError(capped.Flush())
+	assertar.NoError(unlimited.Flush())
+
+	assertar.NotEmpty(hits)
+	for _, hit := range hits {
+		assertar.Contains(cheaters, hit.Creator)
+		assertar.False(hit.Rejected)
+	}
+	assertar.NotEmpty(capped.BranchesInfo().SinkBranchIDs)
+
+	// the sink is fork-detected by every event observing it, and the rest is indexed as without the cap
+	for _, e := range ordered {
+		before, err := capped.GetHighestBefore(e.ID())
+		assertar.NoError(err)
+		cappedSeqs, err := capped.GetObservedSeqs(e.ID())
+		assertar.NoError(err)
+		unlimitedSeqs, err := unlimited.GetObservedSeqs(e.ID())
+		assertar.NoError(err)
+		for _, sinkBranchID := range capped.BranchesInfo().SinkBranchIDs {
+			creatorIdx := capped.BranchesInfo().BranchIDCreatorIdxs[sinkBranchID]
+			if !before.IsEmpty(sinkBranchID) {
+				assertar.True(cappedSeqs[creatorIdx].ForkDetected, "event=%s", e.ID())
+			}
+		}
+		for i := range cappedSeqs {
+			if unlimitedSeqs[i].ForkDetected {
+				assertar.True(cappedSeqs[i].ForkDetected, "event=%s", e.ID())
+			}
+			if !cappedSeqs[i].ForkDetected {
+				assertar.Equal(unlimitedSeqs[i], cappedSeqs[i], "event=%s", e.ID())
+			}
+		}
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+func TestIndex_BranchCapReject(t *testing.T) {
+	assertar := assert.New(t)
+
+	const maxBranches = 2
+	nodes := consensustest.GenNodes(5)
+	cheaters := nodes[:2]
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+
+	registry := metrics.NewMemoryRegistry()
+	var hits []BranchCapHit
+	cfg := LiteConfig()
+	cfg.Metrics = registry
+	cfg.MaxBranches = maxBranches
+	cfg.BranchCapPolicy = BranchCapReject
+	cfg.OnBranchCapHit = func(hit BranchCapHit) {
+		hits = append(hits, hit)
+	}
+	vi := NewIndex(tCrit, cfg)
+	vi.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	rejected := consensus.EventHashSet{}
+	consensustest.ForEachRandFork(nodes, cheaters, 30, 3, 10, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			for _, p := range e.Parents() {
+				if rejected.Contains(p) {
+					rejected.Add(e.ID())
+					return
+				}
+			}
+			err := vi.Add(e)
+			if err != nil {
+				assertar.ErrorIs(err, ErrBranchCapReached)
+				assertar.Contains(cheaters, e.Creator())
+				rejected.Add(e.ID())
+				vi.DropNotFlushed()
+				return
+			}
+			assertar.NoError(vi.Flush())
+		},
+	})
+
+	assertar.NotEmpty(hits)
+	assertar.Equal(int64(len(hits)), registry.CounterValue("dagindexer/branch_cap/hits"))
+	for _, hit := range hits {
+		assertar.True(hit.Rejected)
+		assertar.True(rejected.Contains(hit.Event))
+		assertar.Equal(maxBranches, hit.Branches)
+	}
+	assertar.NoError(vi.InitBranchesInfo())
+	assertar.Empty(vi.BranchesInfo().SinkBranchIDs)
+	for _, branches := range vi.BranchesInfo().BranchIDByCreators {
+		assertar.LessOrEqual(len(branches), maxBranches)
+	}
+}
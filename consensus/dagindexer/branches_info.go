@@ -11,6 +11,8 @@
 package dagindexer
 
 import (
+	"slices"
+
 	"github.com/0xsoniclabs/consensus/consensus"
 )
 
@@ -19,18 +21,25 @@ type BranchesInfo struct {
 	BranchIDLastSeq     []consensus.Seq              // branchID -> highest e.Seq in the branch
 	BranchIDCreatorIdxs []consensus.ValidatorIndex   // branchID -> validator idx
 	BranchIDByCreators  [][]consensus.ValidatorIndex // validator idx -> list of branch IDs
+	// SinkBranchIDs are the branches, which merge the events beyond the branch cap, see IndexConfig.MaxBranches
+	SinkBranchIDs []consensus.ValidatorIndex `rlp:"optional"`
 }
 
 // InitBranchesInfo loads BranchesInfo from store
-func (vi *Index) InitBranchesInfo() {
+func (vi *Index) InitBranchesInfo() error {
 	if vi.branchesInfo == nil {
 		// if not cached
-		vi.branchesInfo = vi.getBranchesInfo()
+		info, err := vi.getBranchesInfo()
+		if err != nil {
+			return err
+		}
+		vi.branchesInfo = info
 		if vi.branchesInfo == nil {
 			// first run
 			vi.branchesInfo = newInitialBranchesInfo(vi.validators)
 		}
 	}
+	return nil
 }
 
 func newInitialBranchesInfo(validators *consensus.Validators) *BranchesInfo {
@@ -53,6 +62,35 @@ func newInitialBranchesInfo(validators *consensus.Validators) *BranchesInfo {
 	}
 }
 
+// isSink returns true if the branch merges the events beyond the branch cap.
+func (info *BranchesInfo) isSink(branchID consensus.ValidatorIndex) bool {
+	return slices.Contains(info.SinkBranchIDs, branchID)
+}
+
+// sinkOf returns the sink branch of the validator, if it's allocated.
+func (info *BranchesInfo) sinkOf(creatorIdx consensus.ValidatorIndex) (consensus.ValidatorIndex, bool) {
+	for _, branchID := range info.SinkBranchIDs {
+		if info.BranchIDCreatorIdxs[branchID] == creatorIdx {
+			return branchID, true
+		}
+	}
+	return 0, false
+}
+
+// clone returns a deep copy of the info.
+func (info *BranchesInfo) clone() *BranchesInfo {
+	byCreators := make([][]consensus.ValidatorIndex, len(info.BranchIDByCreators))
+	for i, branchIDs := range info.BranchIDByCreators {
+		byCreators[i] = slices.Clone(branchIDs)
+	}
+	return &BranchesInfo{
+		BranchIDLastSeq:     slices.Clone(info.BranchIDLastSeq),
+		BranchIDCreatorIdxs: slices.Clone(info.BranchIDCreatorIdxs),
+		BranchIDByCreators:  byCreators,
+		SinkBranchIDs:       slices.Clone(info.SinkBranchIDs),
+	}
+}
+
 func (vi *Index) AtLeastOneFork() bool {
 	return consensus.ValidatorIndex(len(vi.branchesInfo.BranchIDCreatorIdxs)) > vi.validators.Len()
 }
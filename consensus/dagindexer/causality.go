@@ -0,0 +1,139 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ObservedSeq is the highest event of a validator, observed by an event.
+type ObservedSeq struct {
+	// Seq of the highest observed event, 0 if none is observed or if the fork is detected
+	Seq consensus.Seq
+	// ForkDetected is true if the validator's fork is observed, then the observed events aren't ordered by Seq
+	ForkDetected bool
+}
+
+// Observes returns true if a observes b, i.e. if b is a or an ancestor of a.
+// The lowest events of a's branch, which observe b, are compared with a, so forks don't affect the answer.
+func (vi *Index) Observes(a, b consensus.EventHash) (bool, error) {
+	if a == b {
+		return true, nil
+	}
+	if a.Lamport() <= b.Lamport() {
+		return false, nil
+	}
+	aBranchID, err := vi.GetEventBranchID(a)
+	if err != nil {
+		return false, err
+	}
+	if err := vi.InitBranchesInfo(); err != nil {
+		return false, err
+	}
+	bAfter, err := vi.getLowestAfter(b)
+	if err != nil {
+		return false, err
+	}
+	if vi.branchesInfo.isSink(aBranchID) {
+		bBranchID, err := vi.GetEventBranchID(b)
+		if err != nil {
+			return false, err
+		}
+		if vi.branchesInfo.isSink(bBranchID) {
+			return vi.observesByTraversal(a, b)
+		}
+		// the events of b's branch form a chain, so a observes b if it observes an event of the branch as high as b
+		observed, err := vi.getHighestObservedSeq(a, bBranchID)
+		return observed >= bAfter.Get(bBranchID), err
+	}
+	aAfter, err := vi.getLowestAfter(a)
+	if err != nil {
+		return false, err
+	}
+	// the event is observed by itself first
+	aSeq := aAfter.Get(aBranchID)
+	lowest := bAfter.Get(aBranchID)
+	return lowest != 0 && lowest <= aSeq, nil
+}
+
+// GetObservedSeq returns the highest event of the validator observed by the event, merged over the validator's branches.
+func (vi *Index) GetObservedSeq(id consensus.EventHash, validator consensus.ValidatorID) (ObservedSeq, error) {
+	validatorIdx, ok := vi.validatorIdxs[validator]
+	if !ok {
+		return ObservedSeq{}, fmt.Errorf("%d isn't a validator", validator)
+	}
+	observed, err := vi.GetObservedSeqs(id)
+	if err != nil {
+		return ObservedSeq{}, err
+	}
+	return observed[validatorIdx], nil
+}
+
+// GetObservedSeqs returns the highest events of all the validators observed by the event, ordered by validator index.
+func (vi *Index) GetObservedSeqs(id consensus.EventHash) ([]ObservedSeq, error) {
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
+	}
+	before, err := vi.getHighestBefore(id)
+	if err != nil {
+		return nil, err
+	}
+	observed := make([]ObservedSeq, vi.validators.Len())
+	for creatorIdx, branches := range vi.branchesInfo.BranchIDByCreators {
+		observed[creatorIdx] = mergeObservedSeq(before.VSeq, branches)
+	}
+	return observed, nil
+}
+
+// GetCommonFrontier returns the highest events of all the validators observed by both a and b,
+// ordered by validator index. ForkDetected is set if either of the events observes the validator's fork.
+func (vi *Index) GetCommonFrontier(a, b consensus.EventHash) ([]ObservedSeq, error) {
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
+	}
+	aBefore, err := vi.getHighestBefore(a)
+	if err != nil {
+		return nil, err
+	}
+	bBefore, err := vi.getHighestBefore(b)
+	if err != nil {
+		return nil, err
+	}
+	frontier := make([]ObservedSeq, vi.validators.Len())
+	for creatorIdx, branches := range vi.branchesInfo.BranchIDByCreators {
+		common := ObservedSeq{}
+		for _, branchID := range branches {
+			aSeq, bSeq := aBefore.VSeq.Get(branchID), bBefore.VSeq.Get(branchID)
+			if aSeq.IsForkDetected() || bSeq.IsForkDetected() {
+				common = ObservedSeq{ForkDetected: true}
+				break
+			}
+			// the events of a branch form a chain, so both observe its events up to the lower seq
+			common.Seq = max(common.Seq, min(aSeq.Seq, bSeq.Seq))
+		}
+		frontier[creatorIdx] = common
+	}
+	return frontier, nil
+}
+
+func mergeObservedSeq(vec *HighestBeforeSeq, branches []consensus.ValidatorIndex) ObservedSeq {
+	merged := ObservedSeq{}
+	for _, branchID := range branches {
+		seq := vec.Get(branchID)
+		if seq.IsForkDetected() {
+			return ObservedSeq{ForkDetected: true}
+		}
+		merged.Seq = max(merged.Seq, seq.Seq)
+	}
+	return merged
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestIndex_Causality(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(5)
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+	vi := NewIndex(tCrit, LiteConfig())
+	vi.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	var ordered consensus.Events
+	consensustest.ForEachRandFork(nodes, nodes[:2], 20, 3, 3, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+			assertar.NoError(vi.Add(e))
+		},
+	})
+	assertar.NoError(vi.Flush())
+	assertar.True(vi.AtLeastOneFork())
+
+	// naive implementation by the DAG traversal
+	ancestors := map[consensus.EventHash]consensus.EventHashSet{}
+	for _, e := range ordered {
+		set := consensus.EventHashSet{}
+		set.Add(e.ID())
+		for _, p := range e.Parents() {
+			for id := range ancestors[p] {
+				set.Add(id)
+			}
+		}
+		ancestors[e.ID()] = set
+	}
+	observedSeqs := func(set consensus.EventHashSet) []ObservedSeq {
+		observed := make([]ObservedSeq, validators.Len())
+		seen := map[eventSlot]bool{}
+		for id := range set {
+			e := processed[id]
+			idx := validators.GetIdx(e.Creator())
+			slot := eventSlot{seq: e.Seq(), creator: e.Creator()}
+			if seen[slot] {
+				observed[idx].ForkDetected = true
+			}
+			seen[slot] = true
+			observed[idx].Seq = max(observed[idx].Seq, e.Seq())
+		}
+		for i := range observed {
+			if observed[i].ForkDetected {
+				observed[i].Seq = 0
+			}
+		}
+		return observed
+	}
+
+	forksObserved := 0
+	for _, a := range ordered {
+		expected := observedSeqs(ancestors[a.ID()])
+		for _, seq := range expected {
+			if seq.ForkDetected {
+				forksObserved++
+			}
+		}
+		observed, err := vi.GetObservedSeqs(a.ID())
+		assertar.NoError(err)
+		assertar.Equal(expected, observed, a.ID().String())
+		for i, node := range validators.SortedIDs() {
+			seq, err := vi.GetObservedSeq(a.ID(), node)
+			assertar.NoError(err)
+			assertar.Equal(expected[i], seq)
+		}
+
+		for _, b := range ordered {
+			ok, err := vi.Observes(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(ancestors[a.ID()].Contains(b.ID()), ok, "%s observes %s", a.ID(), b.ID())
+
+			common := consensus.EventHashSet{}
+			for id := range ancestors[a.ID()] {
+				if ancestors[b.ID()].Contains(id) {
+					common.Add(id)
+				}
+			}
+			expectedFrontier := observedSeqs(common)
+			aObserved, bObserved := expected, observedSeqs(ancestors[b.ID()])
+			for i := range expectedFrontier {
+				if aObserved[i].ForkDetected || bObserved[i].ForkDetected {
+					expectedFrontier[i] = ObservedSeq{ForkDetected: true}
+				}
+			}
+			frontier, err := vi.GetCommonFrontier(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(expectedFrontier, frontier, "frontier of %s and %s", a.ID(), b.ID())
+		}
+		if t.Failed() {
+			return
+		}
+	}
+	assertar.NotZero(forksObserved)
+}
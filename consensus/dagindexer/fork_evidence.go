@@ -0,0 +1,174 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ErrNoForkEvidence is returned when the fork of a validator isn't observed, or its events aren't found.
+var ErrNoForkEvidence = errors.New("no fork evidence")
+
+// ForkEvent is an event of a forking validator.
+type ForkEvent struct {
+	ID       consensus.EventHash
+	Seq      consensus.Seq
+	BranchID consensus.ValidatorIndex
+}
+
+// ForkEvidence proves that a validator has created conflicting events (a double-sign).
+type ForkEvidence struct {
+	Creator consensus.ValidatorID
+	// Events are the distinct events of the creator with the same Seq, ordered by branch ID.
+	// They are on different branches, unless they are merged into the sink branch, see IndexConfig.MaxBranches
+	Events []ForkEvent
+	// Observer is the earliest event, which observes the fork
+	Observer consensus.EventHash
+}
+
+// GetForkEvidence returns the evidence of the creator's fork, observed by the observer, e.g. by the atropos
+// of a block which reports the creator as a cheater. The conflicting events are the ones with the lowest Seq,
+// found among the observer's ancestors, which observe the creator's events.
+// Returns ErrNoForkEvidence if the observer doesn't observe the fork, or if the conflicting events are pruned.
+func (vi *Index) GetForkEvidence(creator consensus.ValidatorID, observer consensus.EventHash) (*ForkEvidence, error) {
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
+	}
+	creatorIdx, ok := vi.validatorIdxs[creator]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d isn't a validator", ErrNoForkEvidence, creator)
+	}
+	branches := vi.branchesInfo.BranchIDByCreators[creatorIdx]
+	if len(branches) <= 1 {
+		return nil, fmt.Errorf("%w: %d has no branches", ErrNoForkEvidence, creator)
+	}
+	observerBefore, err := vi.GetHighestBefore(observer)
+	if err != nil {
+		return nil, err
+	}
+	if observerBefore == nil {
+		return nil, fmt.Errorf("%w: observer %s", consensus.ErrEventNotFound, observer.String())
+	}
+	// every branch is marked if the fork is observed
+	if !observerBefore.IsForkDetected(creatorIdx) {
+		return nil, fmt.Errorf("%w: %s doesn't observe the fork of %d", ErrNoForkEvidence, observer.String(), creator)
+	}
+
+	evidence := &ForkEvidence{
+		Creator:  creator,
+		Observer: observer,
+	}
+	bySeq := make(map[consensus.Seq][]ForkEvent)
+	visited := make(consensus.EventHashSet)
+	stack := consensus.EventHashStack{observer}
+	pruned := false
+	for next := stack.Pop(); next != nil; next = stack.Pop() {
+		id := *next
+		if visited.Contains(id) {
+			continue
+		}
+		visited.Add(id)
+
+		before, err := vi.GetHighestBefore(id)
+		if err != nil {
+			return nil, err
+		}
+		if before == nil {
+			if err := vi.checkPruned(id); err != nil {
+				pruned = true
+				continue
+			}
+			return nil, vi.inconsistencyErr("event=%s isn't indexed", id.String())
+		}
+		// only the events, which observe the creator's events, lead to them
+		if !slices.ContainsFunc(branches, func(branchID consensus.ValidatorIndex) bool {
+			return !before.IsEmpty(branchID)
+		}) {
+			continue
+		}
+		e := vi.getEvent(id)
+		if e == nil {
+			return nil, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, id.String())
+		}
+		if before.IsForkDetected(creatorIdx) && earlierEvent(id, evidence.Observer) {
+			evidence.Observer = id
+		}
+		if e.Creator() == creator {
+			branchID, err := vi.GetEventBranchID(id)
+			if err != nil {
+				return nil, err
+			}
+			bySeq[e.Seq()] = append(bySeq[e.Seq()], ForkEvent{ID: id, Seq: e.Seq(), BranchID: branchID})
+		}
+		stack.PushAll(e.Parents())
+	}
+
+	for seq, events := range bySeq {
+		if len(events) > 1 && (len(evidence.Events) == 0 || seq < evidence.Events[0].Seq) {
+			evidence.Events = events
+		}
+	}
+	if len(evidence.Events) == 0 {
+		if pruned {
+			return nil, fmt.Errorf("%w: conflicting events of %d are pruned: %w", ErrNoForkEvidence, creator, consensus.ErrPruned)
+		}
+		return nil, vi.inconsistencyErr("conflicting events of %d aren't found", creator)
+	}
+	slices.SortFunc(evidence.Events, func(a, b ForkEvent) int {
+		if a.BranchID != b.BranchID {
+			return cmp.Compare(a.BranchID, b.BranchID)
+		}
+		return bytes.Compare(a.ID.Bytes(), b.ID.Bytes())
+	})
+	return evidence, nil
+}
+
+// earlierEvent orders the events by Lamport time, then by ID.
+func earlierEvent(a, b consensus.EventHash) bool {
+	if a.Lamport() != b.Lamport() {
+		return a.Lamport() < b.Lamport()
+	}
+	return bytes.Compare(a.Bytes(), b.Bytes()) < 0
+}
+
+// Verify checks the evidence against the events, independently of the index: the events must be distinct events
+// of the creator, of the same epoch and with the same Seq.
+// The signatures of the events aren't checked, it's up to the caller.
+func (ev *ForkEvidence) Verify(getEvent func(consensus.EventHash) consensus.Event) error {
+	if len(ev.Events) < 2 {
+		return fmt.Errorf("%w: %d conflicting events", ErrNoForkEvidence, len(ev.Events))
+	}
+	var first consensus.Event
+	seen := make(consensus.EventHashSet, len(ev.Events))
+	for _, fe := range ev.Events {
+		e := getEvent(fe.ID)
+		if e == nil {
+			return fmt.Errorf("%w: %s", consensus.ErrEventNotFound, fe.ID.String())
+		}
+		if seen.Contains(e.ID()) {
+			return fmt.Errorf("%w: duplicate event %s", ErrNoForkEvidence, e.ID().String())
+		}
+		seen.Add(e.ID())
+		if first == nil {
+			first = e
+		}
+		if e.Creator() != ev.Creator || e.Epoch() != first.Epoch() || e.Seq() != fe.Seq || e.Seq() != first.Seq() {
+			return fmt.Errorf("%w: event %s doesn't conflict with %s", ErrNoForkEvidence, e.ID().String(), first.ID().String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestIndex_GetForkEvidence(t *testing.T) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(6)
+	cheaters := nodes[:2]
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+	vi := NewIndex(tCrit, LiteConfig())
+	vi.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	events := consensustest.ForEachRandFork(nodes, cheaters, 100, 3, 10, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			assertar.NoError(vi.Add(e))
+		},
+	})
+	assertar.NoError(vi.Flush())
+
+	for _, node := range nodes[len(cheaters):] {
+		head := events[node][len(events[node])-1].ID()
+		for _, cheater := range cheaters {
+			evidence, err := vi.GetForkEvidence(cheater, head)
+			if !assertar.NoError(err) {
+				continue
+			}
+			assertar.NoError(evidence.Verify(getEvent))
+			assertar.Equal(cheater, evidence.Creator)
+			branches := map[consensus.ValidatorIndex]bool{}
+			for _, e := range evidence.Events {
+				assertar.Equal(vi.validatorIdxs[cheater], vi.BranchesInfo().BranchIDCreatorIdxs[e.BranchID])
+				assertar.False(branches[e.BranchID], "events of the same branch")
+				branches[e.BranchID] = true
+			}
+
+			// the observer detects the fork, but its parents don't
+			observerBefore, err := vi.GetHighestBefore(evidence.Observer)
+			assertar.NoError(err)
+			assertar.True(observerBefore.IsForkDetected(vi.validatorIdxs[cheater]))
+			for _, p := range getEvent(evidence.Observer).Parents() {
+				parentBefore, err := vi.GetHighestBefore(p)
+				assertar.NoError(err)
+				assertar.False(parentBefore.IsForkDetected(vi.validatorIdxs[cheater]))
+			}
+		}
+		// honest validators have no fork evidence
+		_, err := vi.GetForkEvidence(node, head)
+		assertar.ErrorIs(err, ErrNoForkEvidence)
+	}
+
+	// the evidence is verified against the events
+	cheater := cheaters[0]
+	evidence, err := vi.GetForkEvidence(cheater, events[nodes[5]][len(events[nodes[5]])-1].ID())
+	if !assertar.NoError(err) {
+		return
+	}
+	forged := *evidence
+	forged.Events = []ForkEvent{evidence.Events[0], evidence.Events[0]}
+	assertar.ErrorIs(forged.Verify(getEvent), ErrNoForkEvidence)
+	forged.Events = []ForkEvent{evidence.Events[0], {ID: events[nodes[5]][0].ID(), Seq: evidence.Events[0].Seq}}
+	assertar.ErrorIs(forged.Verify(getEvent), ErrNoForkEvidence)
+	forged.Creator = nodes[5]
+	assertar.ErrorIs(forged.Verify(getEvent), ErrNoForkEvidence)
+}
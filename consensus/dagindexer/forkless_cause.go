@@ -20,6 +20,12 @@ type kv struct {
 	a, b consensus.EventHash
 }
 
+// forklessCauseRes is cached by the pair of events, creatorIdx is the creator of B, if B is observed
+type forklessCauseRes struct {
+	observed   bool
+	creatorIdx consensus.ValidatorIndex
+}
+
 // ForklessCause calculates "sufficient coherence" between the events.
 // The A.HighestBefore array remembers the sequence number of the last
 // event by each validator that is an ancestor of A. The array for
@@ -34,39 +40,57 @@ type kv struct {
 // unless more than 1/3W are Byzantine.
 // This great property is the reason why this function exists,
 // providing the base for the BFT algorithm.
-func (vi *Index) ForklessCause(aID, bID consensus.EventHash) bool {
+func (vi *Index) ForklessCause(aID, bID consensus.EventHash) (bool, error) {
 	if res, ok := vi.cache.ForklessCause.Get(kv{aID, bID}); ok {
-		return res.(bool)
+		vi.metrics.forklessCauseHits.Inc(1)
+		return res.(forklessCauseRes).observed, nil
 	}
+	vi.metrics.forklessCauseMisses.Inc(1)
 
-	vi.InitBranchesInfo()
-	res := vi.forklessCause(aID, bID)
+	if err := vi.InitBranchesInfo(); err != nil {
+		return false, err
+	}
+	res, err := vi.forklessCause(aID, bID)
+	if err != nil {
+		return false, err
+	}
 
-	vi.cache.ForklessCause.Add(kv{aID, bID}, res, 1)
-	return res
+	cached := forklessCauseRes{observed: res}
+	if res {
+		// the creator is counted by ForklessCauseRoots
+		bBranchID, err := vi.GetEventBranchID(bID)
+		if err != nil {
+			return false, err
+		}
+		cached.creatorIdx = vi.branchesInfo.BranchIDCreatorIdxs[bBranchID]
+	}
+	vi.cache.ForklessCause.Add(kv{aID, bID}, cached, 1)
+	return res, nil
 }
 
-func (vi *Index) forklessCause(aID, bID consensus.EventHash) bool {
+func (vi *Index) forklessCause(aID, bID consensus.EventHash) (bool, error) {
 	// Get events by hash
-	a := vi.GetHighestBefore(aID).VSeq
-	if a == nil {
-		vi.crit(fmt.Errorf("event A=%s not found", aID.String()))
-		return false
+	aHB, err := vi.getHighestBefore(aID)
+	if err != nil {
+		return false, err
 	}
+	a := aHB.VSeq
 
 	// check A doesn't observe any forks from B
 	if vi.AtLeastOneFork() {
-		bBranchID := vi.GetEventBranchID(bID)
+		bBranchID, err := vi.GetEventBranchID(bID)
+		if err != nil {
+			return false, err
+		}
 		if a.Get(bBranchID).IsForkDetected() { // B is observed as cheater by A
-			return false
+			return false, nil
 		}
 	}
 
 	// check A observes that {QUORUM} non-cheater-validators observe B
-	b := vi.GetLowestAfter(bID)
-	if b == nil {
-		vi.crit(fmt.Errorf("event B=%s not found", bID.String()))
-		return false
+	b, err := vi.getLowestAfter(bID)
+	if err != nil {
+		return false, err
 	}
 
 	yes := vi.validators.NewCounter()
@@ -74,6 +98,17 @@ func (vi *Index) forklessCause(aID, bID consensus.EventHash) bool {
 	branchIDs := vi.BranchesInfo().BranchIDCreatorIdxs
 	for branchIDint, creatorIdx := range branchIDs {
 		branchID := consensus.ValidatorIndex(branchIDint)
+		if vi.branchesInfo.isSink(branchID) {
+			// the events of a sink branch don't form a chain, so the highest observed one is asked
+			observed, err := vi.sinkObserves(aID, branchID, aHB.VSeq.Get(branchID), bID)
+			if err != nil {
+				return false, err
+			}
+			if observed {
+				yes.CountVoteByIndex(creatorIdx)
+			}
+			continue
+		}
 
 		// bLowestAfter := vi.GetLowestAfterSeq_(bID, branchID)   // lowest event from creator on branchID, which observes B
 		bLowestAfter := b.Get(branchID)   // lowest event from creator on branchID, which observes B
@@ -87,10 +122,25 @@ func (vi *Index) forklessCause(aID, bID consensus.EventHash) bool {
 			yes.CountVoteByIndex(creatorIdx)
 		}
 	}
-	return yes.HasQuorum()
+	return yes.HasQuorum(), nil
+}
+
+// sinkObserves returns true if the highest event of the sink branch, observed by A, observes B.
+func (vi *Index) sinkObserves(aID consensus.EventHash, sinkBranchID consensus.ValidatorIndex, aSeq BranchSeq, bID consensus.EventHash) (bool, error) {
+	if aSeq.Seq == 0 || aSeq.IsForkDetected() {
+		return false, nil
+	}
+	tip, ok, err := vi.getSinkTip(aID, sinkBranchID)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, vi.inconsistencyErr("no sink tip of event=%s, branch=%d", aID.String(), sinkBranchID)
+	}
+	return vi.Observes(tip, bID)
 }
 
-func (vi *Index) ForklessCauseProgress(aID, bID consensus.EventHash, candidateParents, chosenParents consensus.EventHashes) (*consensus.WeightCounter, []*consensus.WeightCounter) {
+func (vi *Index) ForklessCauseProgress(aID, bID consensus.EventHash, candidateParents, chosenParents consensus.EventHashes) (*consensus.WeightCounter, []*consensus.WeightCounter, error) {
 	// This function is used to determine progress of event bID in forkless causing aID.
 	// It may be used to determine progress toward the forkless cause condition for an event not in vi, but whose parents are in vi.
 	// To do so, aID should be the self-parent while chosenParents should be the parents of the not-yet-created event.
@@ -109,63 +159,63 @@ func (vi *Index) ForklessCauseProgress(aID, bID consensus.EventHash, candidatePa
 	}
 	chosenParentsFCProgress := vi.validators.NewCounter() // initialise the counter for chosen parents only
 
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, nil, err
+	}
+
 	// Get events by hash
-	aHB := vi.GetHighestBefore(aID).VSeq
-	if aHB == nil {
-		vi.crit(fmt.Errorf("event A=%s not found", aID.String()))
-		return chosenParentsFCProgress, candidateParentsFCProgress
+	aVecs, err := vi.getHighestBefore(aID)
+	if err != nil {
+		return nil, nil, err
 	}
+	aHB := aVecs.VSeq
 
 	candidateParentsHB := make([]*HighestBeforeSeq, len(candidateParents))
 	for i := range candidateParents {
-		candidateParentsHB[i] = vi.GetHighestBefore(candidateParents[i]).VSeq
-		if candidateParentsHB[i] == nil {
-			vi.crit(fmt.Errorf("candidate parent=%s not found", candidateParents[i].String()))
-			return chosenParentsFCProgress, candidateParentsFCProgress
+		vecs, err := vi.getHighestBefore(candidateParents[i])
+		if err != nil {
+			return nil, nil, err
 		}
+		candidateParentsHB[i] = vecs.VSeq
 	}
 
 	chosenParentsHB := make([]*HighestBeforeSeq, len(chosenParents))
 	for i := range chosenParents {
-		chosenParentsHB[i] = vi.GetHighestBefore(chosenParents[i]).VSeq
-		if chosenParentsHB[i] == nil {
-			vi.crit(fmt.Errorf("chosen parent=%s not found", chosenParents[i].String()))
-			return chosenParentsFCProgress, candidateParentsFCProgress
+		vecs, err := vi.getHighestBefore(chosenParents[i])
+		if err != nil {
+			return nil, nil, err
 		}
+		chosenParentsHB[i] = vecs.VSeq
 	}
 
-	// check A doesn't observe any forks from B
 	if vi.AtLeastOneFork() {
-		bBranchID := vi.GetEventBranchID(bID)
+		bBranchID, err := vi.GetEventBranchID(bID)
+		if err != nil {
+			return nil, nil, err
+		}
+		// check A doesn't observe any forks from B
 		if aHB.Get(bBranchID).IsForkDetected() { // B is observed as cheater by A
-			return chosenParentsFCProgress, candidateParentsFCProgress
+			return chosenParentsFCProgress, candidateParentsFCProgress, nil
 		}
-	}
 
-	// check chosenParents don't observe any forks from B
-	for i := 0; i < len(chosenParentsHB); i++ {
-		if vi.AtLeastOneFork() {
-			bBranchID := vi.GetEventBranchID(bID)
+		// check chosenParents don't observe any forks from B
+		for i := 0; i < len(chosenParentsHB); i++ {
 			if chosenParentsHB[i].Get(bBranchID).IsForkDetected() { // B is observed as cheater by a chosen parent
-				return chosenParentsFCProgress, candidateParentsFCProgress
+				return chosenParentsFCProgress, candidateParentsFCProgress, nil
 			}
 		}
-	}
 
-	// check candidateParents don't observe any forks from B
-	for i := 0; i < len(candidateParentsHB); i++ {
-		if vi.AtLeastOneFork() {
-			bBranchID := vi.GetEventBranchID(bID)
+		// check candidateParents don't observe any forks from B
+		for i := 0; i < len(candidateParentsHB); i++ {
 			if candidateParentsHB[i].Get(bBranchID).IsForkDetected() { // B is observed as cheater by a candidate parent
-				return chosenParentsFCProgress, candidateParentsFCProgress
+				return chosenParentsFCProgress, candidateParentsFCProgress, nil
 			}
 		}
 	}
 
-	bLA := vi.GetLowestAfter(bID)
-	if bLA == nil {
-		vi.crit(fmt.Errorf("event B=%s not found", bID.String()))
-		return chosenParentsFCProgress, candidateParentsFCProgress
+	bLA, err := vi.getLowestAfter(bID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// calculate forkless causing using the indexes
@@ -208,13 +258,16 @@ func (vi *Index) ForklessCauseProgress(aID, bID consensus.EventHash, candidatePa
 	// aID may not contribute to forkless cause without the heads,
 	// but may contribute with the heads. HighestBefore and LowestAfter used above do not incorporate
 	// these potential new events, so ensure the contribution of aID's creator is checked and made here
-	aCreatorID := vi.getEvent(aID).Creator()
+	aEvent := vi.getEvent(aID)
+	if aEvent == nil {
+		return nil, nil, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, aID.String())
+	}
 	for _, FC := range candidateParentsFCProgress {
 		if FC.Sum() > 0 { // if anything in candidate event's subgraph observes bID, then the candidate must too
-			FC.CountVoteByID(aCreatorID)
+			FC.CountVoteByID(aEvent.Creator())
 		}
 	}
-	return chosenParentsFCProgress, candidateParentsFCProgress
+	return chosenParentsFCProgress, candidateParentsFCProgress, nil
 }
 
 func maxEvent(a consensus.Seq, b consensus.Seq) consensus.Seq {
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/kelindar/simd"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ObservedRoots is the result of ForklessCauseRoots.
+type ObservedRoots struct {
+	// Observed[i] is true if the i-th root forkless causes the event
+	Observed []bool
+	// Weight counts the creators of the observed roots
+	Weight *consensus.WeightCounter
+}
+
+// ForklessCauseRoots evaluates ForklessCause of the event by every root, e.g. by all the roots of a frame, in one pass.
+// The HighestBefore vector of the event is decoded once, and the per-branch comparisons are vectorized.
+// The cached pairs are answered without reading the vectors or the branches of the roots.
+// Seqs are assumed to be below math.MaxInt32, as forkDetectedSeq does.
+func (vi *Index) ForklessCauseRoots(aID consensus.EventHash, roots consensus.EventHashes) (*ObservedRoots, error) {
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
+	}
+	res := &ObservedRoots{
+		Observed: make([]bool, len(roots)),
+		Weight:   vi.validators.NewCounter(),
+	}
+	// the vectors of the event are decoded for the first root, which isn't cached
+	var forklessCause func(bID consensus.EventHash, bBranchID consensus.ValidatorIndex) (bool, error)
+	for i, bID := range roots {
+		var pair forklessCauseRes
+		if cached, ok := vi.cache.ForklessCause.Get(kv{aID, bID}); ok {
+			vi.metrics.forklessCauseHits.Inc(1)
+			pair = cached.(forklessCauseRes)
+		} else {
+			vi.metrics.forklessCauseMisses.Inc(1)
+			bBranchID, err := vi.GetEventBranchID(bID)
+			if err != nil {
+				return nil, err
+			}
+			if forklessCause == nil {
+				if forklessCause, err = vi.rootsForklessCause(aID); err != nil {
+					return nil, err
+				}
+			}
+			observed, err := forklessCause(bID, bBranchID)
+			if err != nil {
+				return nil, err
+			}
+			pair = forklessCauseRes{observed: observed, creatorIdx: vi.branchesInfo.BranchIDCreatorIdxs[bBranchID]}
+			vi.cache.ForklessCause.Add(kv{aID, bID}, pair, 1)
+		}
+		if pair.observed {
+			res.Observed[i] = true
+			res.Weight.CountVoteByIndex(pair.creatorIdx)
+		}
+	}
+	return res, nil
+}
+
+// rootsForklessCause returns forklessCause of the event, which reuses the decoded vectors of the event for every root.
+func (vi *Index) rootsForklessCause(aID consensus.EventHash) (func(bID consensus.EventHash, bBranchID consensus.ValidatorIndex) (bool, error), error) {
+	aHB, err := vi.getHighestBefore(aID)
+	if err != nil {
+		return nil, err
+	}
+
+	branchCreators := vi.branchesInfo.BranchIDCreatorIdxs
+	branches := len(branchCreators)
+	// the fork-detected branches have Seq 0, so they never observe a root
+	aSeqs := make([]int32, branches)
+	for branchID := range aSeqs {
+		aSeqs[branchID] = int32(aHB.VSeq.Get(consensus.ValidatorIndex(branchID)).Seq)
+	}
+	weights := make([]int32, branches)
+	for branchID, creatorIdx := range branchCreators {
+		weights[branchID] = int32(vi.validators.GetWeightByIdx(creatorIdx))
+	}
+	bSeqs := make([]int32, branches)
+	votes := make([]int32, branches)
+	quorum := int64(vi.validators.Quorum())
+
+	return func(bID consensus.EventHash, bBranchID consensus.ValidatorIndex) (bool, error) {
+		// check A doesn't observe any forks from B
+		if aHB.VSeq.Get(bBranchID).IsForkDetected() {
+			return false, nil
+		}
+		b, err := vi.getLowestAfter(bID)
+		if err != nil {
+			return false, err
+		}
+		// the vector may be shorter than the branches, if the branches are added after B
+		known := min(int(b.Size()), branches)
+		for branchID := 0; branchID < known; branchID++ {
+			bSeqs[branchID] = int32(binary.LittleEndian.Uint32((*b)[branchID*4:]))
+		}
+		for branchID := known; branchID < branches; branchID++ {
+			bSeqs[branchID] = 0
+		}
+		for branchID, seq := range bSeqs {
+			if seq == 0 {
+				// no event of the branch observes B
+				bSeqs[branchID] = math.MaxInt32
+			}
+		}
+		// the branch votes if A observes the lowest event of the branch, which observes B
+		simd.SubInt32s(votes, aSeqs, bSeqs)
+		for branchID, diff := range votes {
+			votes[branchID] = diff>>31 + 1
+		}
+		// the events of a sink branch don't form a chain, so the highest observed one is asked
+		for _, sinkBranchID := range vi.branchesInfo.SinkBranchIDs {
+			observed, err := vi.sinkObserves(aID, sinkBranchID, aHB.VSeq.Get(sinkBranchID), bID)
+			if err != nil {
+				return false, err
+			}
+			votes[sinkBranchID] = 0
+			if observed {
+				votes[sinkBranchID] = 1
+			}
+		}
+		if vi.AtLeastOneFork() {
+			// a creator is counted once, even if it votes on several branches
+			for creatorIdx, creatorBranches := range vi.branchesInfo.BranchIDByCreators {
+				for _, branchID := range creatorBranches[1:] {
+					votes[creatorIdx] |= votes[branchID]
+					votes[branchID] = 0
+				}
+			}
+		}
+		simd.MulInt32s(votes, votes, weights)
+		return int64(simd.SumInt32s(votes)) >= quorum, nil
+	}, nil
+}
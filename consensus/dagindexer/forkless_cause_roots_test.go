@@ -0,0 +1,143 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+func TestIndex_ForklessCauseRoots(t *testing.T) {
+	for _, cheaters := range []int{0, 2} {
+		assertar := assert.New(t)
+
+		nodes := consensustest.GenNodes(7)
+		validators := consensus.ArrayToValidators(nodes, []consensus.Weight{1, 2, 3, 4, 5, 6, 7})
+		processed := make(map[consensus.EventHash]consensus.Event)
+		getEvent := func(id consensus.EventHash) consensus.Event {
+			return processed[id]
+		}
+		batched := NewIndex(tCrit, LiteConfig())
+		batched.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+		single := NewIndex(tCrit, LiteConfig())
+		single.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+		var ordered consensus.Events
+		consensustest.ForEachRandFork(nodes, nodes[:cheaters], 30, 3, 5, consensustest.NewIntSeededRandGenerator(0), consensustest.ForEachEvent{
+			Process: func(e consensus.Event, name string) {
+				if _, ok := processed[e.ID()]; ok {
+					return
+				}
+				processed[e.ID()] = e
+				ordered = append(ordered, e)
+				assertar.NoError(batched.Add(e))
+				assertar.NoError(single.Add(e))
+			},
+		})
+		assertar.NoError(batched.Flush())
+		assertar.NoError(single.Flush())
+
+		r := rand.New(rand.NewSource(0)) // nolint:gosec
+		for _, a := range ordered {
+			roots := consensus.EventHashes{}
+			for _, b := range ordered {
+				if r.Intn(3) == 0 {
+					roots = append(roots, b.ID())
+				}
+			}
+			res, err := batched.ForklessCauseRoots(a.ID(), roots)
+			if !assertar.NoError(err) {
+				return
+			}
+			expected := validators.NewCounter()
+			for i, b := range roots {
+				observed, err := single.ForklessCause(a.ID(), b)
+				assertar.NoError(err)
+				assertar.Equal(observed, res.Observed[i], "%s forkless caused by %s", a.ID(), b)
+				if observed {
+					expected.CountVoteByID(processed[b].Creator())
+				}
+			}
+			assertar.Equal(expected.Sum(), res.Weight.Sum())
+			assertar.Equal(expected.HasQuorum(), res.Weight.HasQuorum())
+
+			// the results are cached
+			cached, err := batched.ForklessCauseRoots(a.ID(), roots)
+			assertar.NoError(err)
+			assertar.Equal(res, cached)
+		}
+	}
+}
+
+func BenchmarkIndex_ForklessCauseRoots(b *testing.B) {
+	benchForklessCauseRoots(b, func(vi *Index, a consensus.EventHash, roots consensus.EventHashes) {
+		if _, err := vi.ForklessCauseRoots(a, roots); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkIndex_ForklessCauseRoots_PerRoot(b *testing.B) {
+	benchForklessCauseRoots(b, func(vi *Index, a consensus.EventHash, roots consensus.EventHashes) {
+		for _, root := range roots {
+			if _, err := vi.ForklessCause(a, root); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// benchForklessCauseRoots evaluates the heads against the events of the first validator's level, as against the roots of a frame
+func benchForklessCauseRoots(b *testing.B, forklessCauseRoots func(vi *Index, a consensus.EventHash, roots consensus.EventHashes)) {
+	b.Helper()
+	nodes := consensustest.GenNodes(100)
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	events := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return events[id]
+	}
+	vi := NewIndex(tCrit, LiteConfig())
+	vi.Reset(validators, vecflushable.Wrap(memorydb.New(), 10000000), getEvent)
+
+	byCreator := make(map[consensus.ValidatorID]consensus.Events)
+	consensustest.ForEachRandEvent(nodes, 20, 5, nil, consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			events[e.ID()] = e
+			byCreator[e.Creator()] = append(byCreator[e.Creator()], e)
+			if err := vi.Add(e); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	if err := vi.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	roots := consensus.EventHashes{}
+	heads := consensus.EventHashes{}
+	for _, node := range nodes {
+		created := byCreator[node]
+		roots = append(roots, created[len(created)/2].ID())
+		heads = append(heads, created[len(created)-1].ID())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vi.cache.ForklessCause.Purge()
+		forklessCauseRoots(vi, heads[i%len(heads)], roots)
+	}
+}
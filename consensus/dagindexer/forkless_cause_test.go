@@ -11,6 +11,7 @@
 package dagindexer
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -29,6 +30,15 @@ import (
 
 func tCrit(err error) { panic(err) }
 
+func mustForklessCause(t *testing.T, vi *Index, a, b consensus.EventHash) bool {
+	t.Helper()
+	res, err := vi.ForklessCause(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
 func BenchmarkIndex_ForklessCause(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
@@ -83,7 +93,9 @@ func benchForklessCauseProcess(b *testing.B, idx *int, inmem bool) {
 			if err != nil {
 				panic(err)
 			}
-			vi.Flush()
+			if err := vi.Flush(); err != nil {
+				panic(err)
+			}
 		},
 	})
 
@@ -93,7 +105,9 @@ func benchForklessCauseProcess(b *testing.B, idx *int, inmem bool) {
 		for _, by := range events {
 			who := by.ID()
 			whom := ev.ID()
-			vi.ForklessCause(who, whom)
+			if _, err := vi.ForklessCause(who, whom); err != nil {
+				b.Fatal(err)
+			}
 			if *idx > b.N {
 				b.StopTimer()
 				return
@@ -174,7 +188,9 @@ func testForklessCaused(t *testing.T, dagAscii string) {
 			if err != nil {
 				panic(err)
 			}
-			vi.Flush()
+			if err := vi.Flush(); err != nil {
+				panic(err)
+			}
 		},
 	})
 
@@ -188,7 +204,7 @@ func testForklessCaused(t *testing.T, dagAscii string) {
 			whom := ev.ID()
 			if !assertar.Equal(
 				bylevel > 0 && bylevel <= level,
-				vi.ForklessCause(who, whom),
+				mustForklessCause(t, vi, who, whom),
 				fmt.Sprintf("%s forkless sees %s", who.String(), whom.String()),
 			) {
 				return
@@ -517,7 +533,9 @@ func testForklessCausedRandom(t *testing.T, dbProducer func() kvdb.FlushableKVSt
 		if err != nil {
 			panic(err)
 		}
-		vi.Flush()
+		if err := vi.Flush(); err != nil {
+			panic(err)
+		}
 	}
 
 	// check
@@ -526,7 +544,7 @@ func testForklessCausedRandom(t *testing.T, dbProducer func() kvdb.FlushableKVSt
 			_, expect := relations[e1name][e2name]
 			if !assertar.Equal(
 				expect,
-				vi.ForklessCause(e1.ID(), e2.ID()),
+				mustForklessCause(t, vi, e1.ID(), e2.ID()),
 				fmt.Sprintf("%s forkless sees %s", e1.ID(), e2.ID()),
 			) {
 				return
@@ -545,10 +563,10 @@ func testForksDetected(vi *Index, head consensus.Event) (cheaters map[consensus.
 	cheaters = map[consensus.ValidatorID]bool{}
 	visited := consensus.EventHashSet{}
 	detected := map[eventSlot]int{}
-	onWalk := func(id consensus.EventHash) (godeeper bool) {
+	onWalk := func(id consensus.EventHash) (godeeper bool, err error) {
 		// ensure visited once
 		if visited.Contains(id) {
-			return false
+			return false, nil
 		}
 		visited.Add(id)
 
@@ -558,9 +576,9 @@ func testForksDetected(vi *Index, head consensus.Event) (cheaters map[consensus.
 			creator: e.Creator(),
 		}
 		detected[slot]++
-		return true
+		return true, nil
 	}
-	onWalk(head.ID())
+	_, _ = onWalk(head.ID())
 	err = vi.DfsSubgraph(head, onWalk)
 	for s, count := range detected {
 		if count > 1 {
@@ -606,7 +624,9 @@ func TestRandomForksSanity(t *testing.T) {
 		},
 	})
 
-	vi.Flush()
+	if err := vi.Flush(); err != nil {
+		t.Fatal(err)
+	}
 	vi.DropNotFlushed() // doesn't drop anything, because everything is flushed
 
 	// quick sanity check. all the nodes should see that cheaters have a fork, and honest nodes don't have forks
@@ -614,7 +634,8 @@ func TestRandomForksSanity(t *testing.T) {
 	idxs := validatorsBuilder.Build().Idxs()
 	for _, node := range nodes {
 		ee := events[node]
-		highestBefore := vi.GetMergedHighestBefore(ee[len(ee)-1].ID())
+		highestBefore, err := vi.GetMergedHighestBefore(ee[len(ee)-1].ID())
+		assertar.NoError(err)
 		for n, cheater := range nodes {
 			branchSeq := highestBefore.VSeq.Get(idxs[cheater])
 			isCheater := n < len(cheaters)
@@ -737,7 +758,8 @@ func TestRandomForks(t *testing.T) {
 			idxs := validators.Idxs()
 			// check that fork observing is identical to naive version
 			for _, e := range processed {
-				highestBefore := vi.GetHighestBefore(e.ID())
+				highestBefore, err := vi.GetHighestBefore(e.ID())
+				assertar.NoError(err)
 				expectedCheaters, err := testForksDetected(vi, e)
 				assertar.NoError(err)
 
@@ -759,14 +781,18 @@ func TestRandomForks(t *testing.T) {
 						a: a.ID(),
 						b: b.ID(),
 					}
-					forklessCauseMap[pair] = vi.ForklessCause(a.ID(), b.ID())
+					forklessCauseMap[pair] = mustForklessCause(t, vi, a.ID(), b.ID())
 				}
 			}
 
 			vi.DropNotFlushed() // drops everything, because wasn't flushed
 			for _, e := range processed {
-				assertar.Nil(vi.GetHighestBefore(e.ID()))
-				assertar.Nil(vi.GetLowestAfter(e.ID()))
+				highestBefore, err := vi.GetHighestBefore(e.ID())
+				assertar.NoError(err)
+				assertar.Nil(highestBefore)
+				lowestAfter, err := vi.GetLowestAfter(e.ID())
+				assertar.NoError(err)
+				assertar.Nil(lowestAfter)
 			}
 
 			// check that events re-order doesn't change forklessCause result
@@ -789,7 +815,7 @@ func TestRandomForks(t *testing.T) {
 							a: a.ID(),
 							b: b.ID(),
 						}
-						res := vi.ForklessCause(a.ID(), b.ID())
+						res := mustForklessCause(t, vi, a.ID(), b.ID())
 						assertar.Equal(forklessCauseMap[pair], res, "%s %s %d", a.ID().String(), b.ID().String(), reorderTry)
 					}
 				}
@@ -858,3 +884,16 @@ func codegen4ForklessCausedStability() {
 	fmt.Printf("}\n")
 }
 */
+
+func TestForklessCause_UnknownEvent(t *testing.T) {
+	nodes := consensustest.GenNodes(2)
+	vi := NewIndex(nil, LiteConfig())
+	vi.Reset(consensus.EqualWeightValidators(nodes, 1), vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), nil)
+
+	if _, err := vi.ForklessCause(consensus.EventHash{1}, consensus.EventHash{2}); !errors.Is(err, consensus.ErrEventNotFound) {
+		t.Fatalf("expected event not found error, got: %v", err)
+	}
+	if _, err := vi.MedianTime(consensus.EventHash{1}, 0); !errors.Is(err, consensus.ErrEventNotFound) {
+		t.Fatalf("expected event not found error, got: %v", err)
+	}
+}
@@ -11,10 +11,9 @@
 package dagindexer
 
 import (
-	"errors"
 	"fmt"
 
-	"github.com/0xsoniclabs/cacheutils/wlru"
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 
@@ -23,7 +22,6 @@ import (
 	"github.com/0xsoniclabs/consensus/consensus"
 
 	"github.com/0xsoniclabs/kvdb"
-	"github.com/0xsoniclabs/kvdb/table"
 )
 
 // UNIX nanoseconds timestamp
@@ -36,11 +34,26 @@ type IndexCacheConfig struct {
 	ForklessCausePairs    int
 	HighestBeforeSeqSize  uint
 	LowestAfterSeqSize    uint
+	EventBranchSize       uint
 }
 
 // IndexConfig - Engine config (cache sizes)
 type IndexConfig struct {
 	Caches IndexCacheConfig
+	// Metrics receives the index metrics, optional
+	Metrics metrics.Registry
+	// TimeAggregator aggregates the times in MedianTime, the weighted median if nil
+	TimeAggregator TimeAggregator
+	// MaxBranches caps the number of branches per validator, unlimited if 0.
+	// A cheater's fork creates a new branch, which grows the vectors of all the events.
+	MaxBranches int
+	// BranchCapPolicy defines how the events beyond MaxBranches are indexed, BranchCapSink by default.
+	// Only BranchCapSink doesn't depend on the order of arrival, so under the other policies the nodes may disagree on the blocks.
+	BranchCapPolicy BranchCapPolicy
+	// OnBranchCapHit is notified of every flushed or rejected event beyond MaxBranches, optional
+	OnBranchCapHit func(BranchCapHit)
+	// Backend selects where the vectors are kept, DBBackend by default
+	Backend Backend
 }
 
 // Index is a data to detect forkless-cause condition, calculate median timestamp, detect forks.
@@ -50,26 +63,29 @@ type Index struct {
 	validatorIdxs map[consensus.ValidatorID]consensus.ValidatorIndex
 
 	branchesInfo *BranchesInfo
+	prunedBefore *consensus.Lamport
+	// pendingBranchCapHits are reported once the events are flushed
+	pendingBranchCapHits []BranchCapHit
+	// version changes whenever an event is added or dropped, so the completed LowestAfter memos are known to be up to date
+	version uint64
 
 	getEvent func(consensus.EventHash) consensus.Event
 
-	vecDb kvdb.FlushableKVStore
-	table struct {
-		HighestBeforeTime kvdb.Store `table:"T"`
-		EventBranch       kvdb.Store `table:"b"`
-		BranchesInfo      kvdb.Store `table:"B"`
-		HighestBeforeSeq  kvdb.Store `table:"S"`
-		LowestAfterSeq    kvdb.Store `table:"s"`
-	}
+	store vectorsStore
 
 	cache struct {
-		HighestBeforeTime *wlru.Cache
-		ForklessCause     *simplewlru.Cache
-		HighestBeforeSeq  *simplewlru.Cache
-		LowestAfterSeq    *simplewlru.Cache
+		ForklessCause *simplewlru.Cache
 	}
 
 	cfg IndexConfig
+
+	metrics struct {
+		forklessCauseHits   metrics.Counter
+		forklessCauseMisses metrics.Counter
+		eventsAdded         metrics.Counter
+		branches            metrics.Gauge
+		branchCapHits       metrics.Counter
+	}
 }
 
 // DefaultConfig returns default index config
@@ -81,6 +97,7 @@ func DefaultConfig(scale cachescale.Func) IndexConfig {
 			ForklessCausePairs:    scale.I(20000),
 			HighestBeforeSeqSize:  scale.U(160 * 1024),
 			LowestAfterSeqSize:    scale.U(160 * 1024),
+			EventBranchSize:       scale.U(160 * 1024),
 		},
 	}
 }
@@ -94,11 +111,13 @@ func LiteConfig() IndexConfig {
 			ForklessCausePairs:    scale.I(20000),
 			HighestBeforeSeqSize:  scale.U(160 * 1024),
 			LowestAfterSeqSize:    scale.U(160 * 1024),
+			EventBranchSize:       scale.U(160 * 1024),
 		},
 	}
 }
 
 // NewIndex creates Index instance.
+// crit is an optional hook, notified of storage and consistency failures before they are returned.
 func NewIndex(crit func(error), config IndexConfig) *Index {
 	vi := &Index{
 		cfg:  config,
@@ -106,61 +125,96 @@ func NewIndex(crit func(error), config IndexConfig) *Index {
 	}
 
 	vi.initCaches()
+	vi.initMetrics()
+	vi.store = newVectorsStore(vi)
 
 	return vi
 }
 
 // Add calculates vector clocks for the event and saves into DB.
 func (vi *Index) Add(e consensus.Event) error {
-	vi.InitBranchesInfo()
+	if err := vi.InitBranchesInfo(); err != nil {
+		return err
+	}
+	vi.version++
 	_, err := vi.fillEventVectors(e)
-	return err
+	if err != nil {
+		return err
+	}
+	vi.metrics.eventsAdded.Inc(1)
+	return nil
 }
 
 // Flush writes vector clocks to persistent store.
-func (vi *Index) Flush() {
+func (vi *Index) Flush() error {
 	if vi.branchesInfo != nil {
-		vi.setBranchesInfo(vi.branchesInfo)
+		if err := vi.setBranchesInfo(vi.branchesInfo); err != nil {
+			return err
+		}
+		vi.metrics.branches.Set(int64(len(vi.branchesInfo.BranchIDCreatorIdxs)))
 	}
-	if err := vi.vecDb.Flush(); err != nil {
-		vi.crit(err)
+	if err := vi.store.flush(); err != nil {
+		return err
 	}
+	vi.reportBranchCapHits()
+	return nil
+}
+
+// unloadableStore is an index DB, which caches the flushed records in memory, see vecflushable.VecFlushable.
+type unloadableStore interface {
+	Unload() error
+}
+
+// Persist writes the flushed vectors, which may be cached in memory by the DB, through to the DB,
+// e.g. before the DB is archived or exported. Not flushed vectors aren't written.
+// Returns ErrNotPersisted with MemoryBackend, which doesn't write the vectors to the DB.
+func (vi *Index) Persist() error {
+	return vi.store.persist()
+}
+
+func (vi *Index) initMetrics() {
+	registry := metrics.OrNoop(vi.cfg.Metrics)
+	vi.metrics.forklessCauseHits = registry.Counter("dagindexer/forkless_cause/hits")
+	vi.metrics.forklessCauseMisses = registry.Counter("dagindexer/forkless_cause/misses")
+	vi.metrics.eventsAdded = registry.Counter("dagindexer/events_added")
+	vi.metrics.branches = registry.Gauge("dagindexer/branches")
+	vi.metrics.branchCapHits = registry.Counter("dagindexer/branch_cap/hits")
 }
 
 func (vi *Index) initCaches() {
-	vi.cache.HighestBeforeTime, _ = wlru.New(vi.cfg.Caches.HighestBeforeTimeSize, int(vi.cfg.Caches.HighestBeforeTimeSize))
 	vi.cache.ForklessCause, _ = simplewlru.New(uint(vi.cfg.Caches.ForklessCausePairs), vi.cfg.Caches.ForklessCausePairs)
-	vi.cache.HighestBeforeSeq, _ = simplewlru.New(vi.cfg.Caches.HighestBeforeSeqSize, int(vi.cfg.Caches.HighestBeforeSeqSize))
-	vi.cache.LowestAfterSeq, _ = simplewlru.New(vi.cfg.Caches.LowestAfterSeqSize, int(vi.cfg.Caches.HighestBeforeSeqSize))
 }
 
 // DropNotFlushed not connected clocks. Call it if event has failed.
 func (vi *Index) DropNotFlushed() {
 	vi.branchesInfo = nil
-	if vi.vecDb.NotFlushedPairs() != 0 {
-		vi.vecDb.DropNotFlushed()
+	vi.version++
+	vi.pendingBranchCapHits = nil
+	if vi.store.notFlushed() != 0 {
+		vi.store.dropNotFlushed()
 		vi.OnDropNotFlushed()
 	}
 }
 
 func (vi *Index) WrapWithFlushable(db kvdb.Store) kvdb.FlushableKVStore {
-	return vecflushable.Wrap(db, vi.cfg.Caches.DBCache)
+	return vecflushable.WrapWithMetrics(db, vi.cfg.Caches.DBCache, vi.cfg.Metrics)
 }
 
 // Reset resets buffers.
+// The DB isn't used by MemoryBackend, so the vectors are dropped instead of being read from the DB.
 func (vi *Index) Reset(validators *consensus.Validators, db kvdb.FlushableKVStore, getEvent func(consensus.EventHash) consensus.Event) {
-	vi.vecDb = db
+	vi.store.reset(db)
 	vi.getEvent = getEvent
 	vi.validators = validators
 	vi.validatorIdxs = validators.Idxs()
+	vi.prunedBefore = nil
 	vi.DropNotFlushed()
-	table.MigrateTables(&vi.table, vi.vecDb)
 	vi.cache.ForklessCause.Purge()
 	vi.OnDropNotFlushed()
 }
 
 func (vi *Index) Close() error {
-	return vi.vecDb.Close()
+	return vi.store.close()
 }
 
 func (vi *Index) setForkDetected(before *HighestBefore, branchID consensus.ValidatorIndex) {
@@ -173,10 +227,10 @@ func (vi *Index) setForkDetected(before *HighestBefore, branchID consensus.Valid
 func (vi *Index) fillGlobalBranchID(e consensus.Event, meIdx consensus.ValidatorIndex) (consensus.ValidatorIndex, error) {
 	// sanity checks
 	if len(vi.branchesInfo.BranchIDCreatorIdxs) != len(vi.branchesInfo.BranchIDLastSeq) {
-		return 0, errors.New("inconsistent BranchIDCreators len (inconsistent DB)")
+		return 0, vi.inconsistencyErr("inconsistent BranchIDCreators len")
 	}
 	if consensus.ValidatorIndex(len(vi.branchesInfo.BranchIDCreatorIdxs)) < vi.validators.Len() {
-		return 0, errors.New("inconsistent BranchIDCreators len (inconsistent DB)")
+		return 0, vi.inconsistencyErr("inconsistent BranchIDCreators len")
 	}
 
 	if e.SelfParent() == nil {
@@ -187,10 +241,18 @@ func (vi *Index) fillGlobalBranchID(e consensus.Event, meIdx consensus.Validator
 			return meIdx, nil
 		}
 	} else {
-		selfParentBranchID := vi.GetEventBranchID(*e.SelfParent())
+		selfParentBranchID, err := vi.GetEventBranchID(*e.SelfParent())
+		if err != nil {
+			return 0, err
+		}
 		// sanity checks
 		if len(vi.branchesInfo.BranchIDCreatorIdxs) != len(vi.branchesInfo.BranchIDLastSeq) {
-			return 0, errors.New("inconsistent BranchIDCreators len (inconsistent DB)")
+			return 0, vi.inconsistencyErr("inconsistent BranchIDCreators len")
+		}
+		if vi.branchesInfo.isSink(selfParentBranchID) {
+			// the descendants of a sink event stay in the sink, so the regular branches don't follow it
+			vi.branchesInfo.BranchIDLastSeq[selfParentBranchID] = max(vi.branchesInfo.BranchIDLastSeq[selfParentBranchID], e.Seq())
+			return selfParentBranchID, nil
 		}
 
 		if vi.branchesInfo.BranchIDLastSeq[selfParentBranchID]+1 == e.Seq() {
@@ -201,14 +263,21 @@ func (vi *Index) fillGlobalBranchID(e consensus.Event, meIdx consensus.Validator
 	}
 
 	// if we're here, then new fork is observed (only globally), create new branchID due to a new fork
+	if vi.cfg.MaxBranches > 0 && len(vi.branchesInfo.BranchIDByCreators[meIdx]) >= vi.cfg.MaxBranches {
+		return vi.onBranchCap(e, meIdx)
+	}
+	return vi.newBranch(e, meIdx), nil
+}
+
+func (vi *Index) newBranch(e consensus.Event, meIdx consensus.ValidatorIndex) consensus.ValidatorIndex {
 	vi.branchesInfo.BranchIDLastSeq = append(vi.branchesInfo.BranchIDLastSeq, e.Seq())
 	vi.branchesInfo.BranchIDCreatorIdxs = append(vi.branchesInfo.BranchIDCreatorIdxs, meIdx)
 	newBranchID := consensus.ValidatorIndex(len(vi.branchesInfo.BranchIDLastSeq) - 1)
 	vi.branchesInfo.BranchIDByCreators[meIdx] = append(vi.branchesInfo.BranchIDByCreators[meIdx], newBranchID)
-	return newBranchID, nil
+	return newBranchID
 }
 
-// fillEventVectors calculates (and stores) event's vectors, and updates LowestAfter of newly-observed events.
+// fillEventVectors calculates (and stores) event's vectors.
 func (vi *Index) fillEventVectors(e consensus.Event) (allVecs, error) {
 	meIdx := vi.validatorIdxs[e.Creator()]
 	myVecs := allVecs{
@@ -216,22 +285,32 @@ func (vi *Index) fillEventVectors(e consensus.Event) (allVecs, error) {
 		after:  NewLowestAfterSeq(consensus.ValidatorIndex(len(vi.branchesInfo.BranchIDCreatorIdxs))),
 	}
 
-	meBranchID, err := vi.fillGlobalBranchID(e, meIdx)
-	if err != nil {
-		return myVecs, err
-	}
-
 	// pre-load parents into RAM for quick access
 	parentsVecs := make([]*HighestBefore, len(e.Parents()))
 	parentsBranchIDs := make([]consensus.ValidatorIndex, len(e.Parents()))
 	for i, p := range e.Parents() {
-		parentsBranchIDs[i] = vi.GetEventBranchID(p)
-		parentsVecs[i] = vi.GetHighestBefore(p)
+		var err error
+		parentsVecs[i], err = vi.GetHighestBefore(p)
+		if err != nil {
+			return myVecs, err
+		}
 		if parentsVecs[i] == nil {
-			return myVecs, fmt.Errorf("processed out of order, parent not found (inconsistent DB), parent=%s", p.String())
+			if err := vi.checkPruned(p); err != nil {
+				return myVecs, err
+			}
+			return myVecs, fmt.Errorf("%w: processed out of order, parent=%s", consensus.ErrEventNotFound, p.String())
+		}
+		parentsBranchIDs[i], err = vi.GetEventBranchID(p)
+		if err != nil {
+			return myVecs, err
 		}
 	}
 
+	meBranchID, err := vi.fillGlobalBranchID(e, meIdx)
+	if err != nil {
+		return myVecs, err
+	}
+
 	// observed by himself
 	myVecs.after.InitWithEvent(meBranchID, e)
 	myVecs.before.InitWithEvent(meBranchID, e)
@@ -278,38 +357,72 @@ func (vi *Index) fillEventVectors(e consensus.Event) (allVecs, error) {
 				}
 			}
 		}
+		// the events of a sink branch don't form a chain, so the forks within the sink are detected by its tips,
+		// unless the sink is always fork-detected
+		if vi.cfg.BranchCapPolicy == BranchCapSinkForkDetected {
+			vi.markSinksForkDetected(myVecs.before)
+		} else if err := vi.fillSinkTips(e, meBranchID, myVecs.before, parentsVecs); err != nil {
+			return myVecs, err
+		}
+	}
+
+	// store calculated vectors
+	if err := vi.SetHighestBefore(e.ID(), myVecs.before); err != nil {
+		return myVecs, err
+	}
+	// the new event isn't observed by the indexed events, see completeLowestAfter
+	checked := NewLowestAfterSeq(0)
+	for branchID, lastSeq := range vi.branchesInfo.BranchIDLastSeq {
+		checked.Set(consensus.ValidatorIndex(branchID), lastSeq)
+	}
+	if err := vi.store.updateLowestAfter(e.ID(), &lowestAfterMemo{vec: *myVecs.after, checked: *checked}); err != nil {
+		return myVecs, err
+	}
+	if err := vi.SetEventBranchID(e.ID(), meBranchID); err != nil {
+		return myVecs, err
+	}
+	if err := vi.fillHighestBeforeObserved(e, meBranchID, myVecs.before); err != nil {
+		return myVecs, err
+	}
+	if !vi.branchesInfo.isSink(meBranchID) {
+		// LowestAfter of the observed events is found by the branch's events, see completeLowestAfter
+		return myVecs, vi.SetBranchEvent(meBranchID, e.Seq(), e.ID())
 	}
 
-	// graph traversal starting from e, but excluding e
-	onWalk := func(walk consensus.EventHash) (godeeper bool) {
-		wLowestAfterSeq := vi.GetLowestAfter(walk)
+	// the events of a sink branch don't form a chain, so LowestAfter of the newly-observed events is updated
+	// by the graph traversal starting from e, but excluding e
+	onWalk := func(walk consensus.EventHash) (godeeper bool, err error) {
+		wLowestAfter, err := vi.getIndexedLowestAfterMemo(walk)
+		if err != nil {
+			return false, err
+		}
 
 		// update LowestAfter vector of the old event, because newly-connected event observes it
-		if wLowestAfterSeq.Visit(meBranchID, e) {
-			vi.SetLowestAfter(walk, wLowestAfterSeq)
-			return true
+		if wLowestAfter.vec.Visit(meBranchID, e) {
+			return true, vi.store.updateLowestAfter(walk, wLowestAfter)
 		}
-		return false
+		return false, nil
 	}
 	err = vi.DfsSubgraph(e, onWalk)
 	if err != nil {
-		vi.crit(err)
+		return myVecs, err
 	}
 
-	// store calculated vectors
-	vi.SetHighestBefore(e.ID(), myVecs.before)
-	vi.SetLowestAfter(e.ID(), myVecs.after)
-	vi.SetEventBranchID(e.ID(), meBranchID)
-
 	return myVecs, nil
 }
 
 // GetMergedHighestBefore returns HighestBefore vector clock without branches, where branches are merged into one
-func (vi *Index) GetMergedHighestBefore(id consensus.EventHash) *HighestBefore {
-	vi.InitBranchesInfo()
+// Returns nil vector if the event isn't indexed.
+func (vi *Index) GetMergedHighestBefore(id consensus.EventHash) (*HighestBefore, error) {
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
+	}
 
 	if vi.AtLeastOneFork() {
-		scatteredBefore := vi.GetHighestBefore(id)
+		scatteredBefore, err := vi.GetHighestBefore(id)
+		if err != nil || scatteredBefore == nil {
+			return nil, err
+		}
 
 		mergedBefore := NewHighestBefore(vi.validators.Len())
 
@@ -317,7 +430,25 @@ func (vi *Index) GetMergedHighestBefore(id consensus.EventHash) *HighestBefore {
 			mergedBefore.GatherFrom(consensus.ValidatorIndex(creatorIdx), scatteredBefore, branches)
 		}
 
-		return mergedBefore
+		return mergedBefore, nil
 	}
 	return vi.GetHighestBefore(id)
 }
+
+// ioErr wraps a failure of the underlying key-value storage.
+func (vi *Index) ioErr(err error) error {
+	return vi.critical(fmt.Errorf("%w: %w", consensus.ErrStorageIO, err))
+}
+
+// inconsistencyErr reports a violated invariant of the persisted index.
+func (vi *Index) inconsistencyErr(format string, args ...any) error {
+	return vi.critical(fmt.Errorf("%w: %s", consensus.ErrInconsistentDB, fmt.Sprintf(format, args...)))
+}
+
+// critical notifies the optional crit hook and returns err unchanged.
+func (vi *Index) critical(err error) error {
+	if vi.crit != nil {
+		vi.crit(err)
+	}
+	return err
+}
@@ -97,7 +97,9 @@ func benchmark_Index_Add(b *testing.B, dbProducer func() kvdb.FlushableKVStore)
 			if err != nil {
 				panic(err)
 			}
-			vecClock.Flush()
+			if err := vecClock.Flush(); err != nil {
+				panic(err)
+			}
 			i++
 			if i >= b.N {
 				return
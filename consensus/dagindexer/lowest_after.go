@@ -0,0 +1,216 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+/*
+ * LowestAfter isn't updated by the descendants. The stored vector holds the event's own branch, the sink branches
+ * and the branches found so far, while the other branches are computed on read: the events of a branch form a chain,
+ * so the events observing an event are a suffix of the chain, which is found by a binary search
+ * over the HighestBefore vectors of the branch's events, see GetBranchEvent and HighestBeforeObserved.
+ * The Seqs, up to which the branches are searched, are stored along with the vector, so the completed part
+ * isn't searched again after the vector is evicted from the cache. A new event isn't observed by the indexed events,
+ * so its vector is complete up to the last events of the branches.
+ */
+
+// lowestAfterMemo is the LowestAfter vector of an event, completed up to the checked Seqs.
+type lowestAfterMemo struct {
+	vec LowestAfterSeq
+	// checked is the highest Seq of every branch, up to which the branch's events are searched, encoded like the vector
+	checked LowestAfterSeq
+	// version is Index.version, at which the memo was completed last time
+	version uint64
+}
+
+// encodeLowestAfter encodes the memo into the stored record: the vector, the checked Seqs, and the size of the vector.
+func encodeLowestAfter(memo *lowestAfterMemo) []byte {
+	b := make([]byte, 0, len(memo.vec)+len(memo.checked)+4)
+	b = append(b, memo.vec...)
+	b = append(b, memo.checked...)
+	return binary.LittleEndian.AppendUint32(b, uint32(len(memo.vec)))
+}
+
+// decodeLowestAfter decodes the stored record, the vectors of the memo don't share the capacity.
+func decodeLowestAfter(b []byte) (*lowestAfterMemo, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%w: LowestAfter record of %d bytes", consensus.ErrInconsistentDB, len(b))
+	}
+	end := len(b) - 4
+	size := int(binary.LittleEndian.Uint32(b[end:]))
+	if size > end {
+		return nil, fmt.Errorf("%w: LowestAfter vector of %d bytes in a record of %d bytes", consensus.ErrInconsistentDB, size, len(b))
+	}
+	return &lowestAfterMemo{
+		vec:     LowestAfterSeq(b[:size:size]),
+		checked: LowestAfterSeq(b[size:end:end]),
+	}, nil
+}
+
+// completeLowestAfter searches the branches' events, which are added since the vector was completed last time.
+func (vi *Index) completeLowestAfter(id consensus.EventHash, memo *lowestAfterMemo) error {
+	if memo.version == vi.version {
+		return nil
+	}
+	if err := vi.InitBranchesInfo(); err != nil {
+		return err
+	}
+	// the branch of the event is read only if a branch is searched
+	var idBranchID consensus.ValidatorIndex
+	var idSeq consensus.Seq
+	searched := false
+	for branchIDint, lastSeq := range vi.branchesInfo.BranchIDLastSeq {
+		branchID := consensus.ValidatorIndex(branchIDint)
+		checked := memo.checked.Get(branchID)
+		if lastSeq <= checked || memo.vec.Get(branchID) != 0 || vi.branchesInfo.isSink(branchID) {
+			continue
+		}
+		if !searched {
+			var err error
+			idBranchID, err = vi.GetEventBranchID(id)
+			if err != nil {
+				return err
+			}
+			// the event is observed by itself first
+			idSeq = memo.vec.Get(idBranchID)
+			searched = true
+		}
+		lowest, err := vi.lowestObserver(branchID, checked, lastSeq, id, idBranchID, idSeq)
+		if err != nil {
+			return err
+		}
+		if lowest != 0 {
+			memo.vec.Set(branchID, lowest)
+		}
+		memo.checked.Set(branchID, lastSeq)
+	}
+	memo.version = vi.version
+	if searched {
+		// the found Seqs are final, so they're stored along with the progress to not search them again after the memo is evicted
+		return vi.store.updateLowestAfter(id, memo)
+	}
+	return nil
+}
+
+// lowestObserver returns the lowest Seq in (from, to] of the branch's events, which observe the event, or 0 if none.
+func (vi *Index) lowestObserver(branchID consensus.ValidatorIndex, from, to consensus.Seq, id consensus.EventHash, idBranchID consensus.ValidatorIndex, idSeq consensus.Seq) (consensus.Seq, error) {
+	if vi.branchesInfo.isSink(idBranchID) {
+		// the events of a sink branch don't form a chain, so the observed Seq doesn't tell it
+		return searchSeq(from, to, func(seq consensus.Seq) (bool, error) {
+			observer, ok, err := vi.GetBranchEvent(branchID, seq)
+			if err != nil || !ok {
+				return false, err
+			}
+			return vi.observesByTraversal(observer, id)
+		})
+	}
+	return searchSeq(from, to, func(seq consensus.Seq) (bool, error) {
+		observer, ok, err := vi.GetBranchEvent(branchID, seq)
+		if err != nil || !ok || observer.Lamport() <= id.Lamport() {
+			return false, err
+		}
+		observed, err := vi.getHighestObservedSeq(observer, idBranchID)
+		return observed >= idSeq, err
+	})
+}
+
+// getHighestObservedSeq returns the Seq of the highest event of the branch observed by the event,
+// regardless of the fork detection. Returns 0 if the event is pruned.
+func (vi *Index) getHighestObservedSeq(id consensus.EventHash, branchID consensus.ValidatorIndex) (consensus.Seq, error) {
+	before, err := vi.getHighestBeforeSeq(id)
+	if err != nil || before == nil {
+		return 0, err
+	}
+	if seq := before.Get(branchID); !seq.IsForkDetected() {
+		return seq.Seq, nil
+	}
+	observed, err := vi.getHighestBeforeObserved(id)
+	if err != nil || observed == nil {
+		return 0, err
+	}
+	return observed.Get(branchID), nil
+}
+
+// getHighestBeforeObserved returns HighestBeforeObserved vector of the event, or nil if the event is pruned.
+func (vi *Index) getHighestBeforeObserved(id consensus.EventHash) (*HighestBeforeObserved, error) {
+	observed, err := vi.store.getHighestBeforeObserved(id)
+	if err != nil || observed != nil {
+		return observed, err
+	}
+	// the event doesn't detect a fork, so the vectors are equal
+	before, err := vi.getHighestBeforeSeq(id)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	observed = NewLowestAfterSeq(consensus.ValidatorIndex(before.Size()))
+	for branchID := consensus.ValidatorIndex(0); branchID < observed.Size(); branchID++ {
+		observed.Set(branchID, before.Get(branchID).Seq)
+	}
+	return observed, nil
+}
+
+// fillHighestBeforeObserved stores HighestBeforeObserved vector of the event, if it detects a fork.
+func (vi *Index) fillHighestBeforeObserved(e consensus.Event, meBranchID consensus.ValidatorIndex, before *HighestBefore) error {
+	branches := consensus.ValidatorIndex(len(vi.branchesInfo.BranchIDCreatorIdxs))
+	detected := false
+	for branchID := consensus.ValidatorIndex(0); branchID < branches && !detected; branchID++ {
+		detected = before.IsForkDetected(branchID)
+	}
+	if !detected {
+		return nil
+	}
+	observed := NewLowestAfterSeq(branches)
+	observed.Set(meBranchID, e.Seq())
+	for _, p := range e.Parents() {
+		pObserved, err := vi.getHighestBeforeObserved(p)
+		if err != nil {
+			return err
+		}
+		if pObserved == nil {
+			return fmt.Errorf("%w: HighestBefore of event=%s", consensus.ErrEventNotFound, p.String())
+		}
+		for branchID := consensus.ValidatorIndex(0); branchID < pObserved.Size(); branchID++ {
+			observed.Set(branchID, max(observed.Get(branchID), pObserved.Get(branchID)))
+		}
+	}
+	return vi.store.setHighestBeforeObserved(e.ID(), observed)
+}
+
+// searchSeq returns the lowest Seq in (from, to], which satisfies the monotone predicate, or 0 if none.
+func searchSeq(from, to consensus.Seq, predicate func(consensus.Seq) (bool, error)) (consensus.Seq, error) {
+	if to <= from {
+		return 0, nil
+	}
+	// most of the times the event isn't observed by the new events yet
+	ok, err := predicate(to)
+	if err != nil || !ok {
+		return 0, err
+	}
+	lo, hi := from+1, to
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := predicate(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// TestIndex_LowestAfter compares the lazily computed LowestAfter with the vectors,
+// which are updated by the DAG traversal for every new event.
+func TestIndex_LowestAfter(t *testing.T) {
+	for i, test := range []struct {
+		nodesNum    int
+		cheatersNum int
+		forksNum    int
+		maxBranches int
+	}{
+		{nodesNum: 5},
+		{nodesNum: 5, cheatersNum: 2, forksNum: 5},
+		{nodesNum: 10, cheatersNum: 10, forksNum: 3},
+		{nodesNum: 5, cheatersNum: 2, forksNum: 10, maxBranches: 2},
+	} {
+		t.Run(fmt.Sprintf("Test #%d", i), func(t *testing.T) {
+			testLowestAfter(t, test.nodesNum, test.cheatersNum, test.forksNum, test.maxBranches)
+		})
+	}
+}
+
+func testLowestAfter(t *testing.T, nodesNum, cheatersNum, forksNum, maxBranches int) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(nodesNum)
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+	cfg := LiteConfig()
+	cfg.MaxBranches = maxBranches
+	vi := NewIndex(tCrit, cfg)
+	vi.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+
+	// the vectors as they were maintained before, by the DAG traversal from every new event
+	expected := map[consensus.EventHash]*LowestAfterSeq{}
+	addExpected := func(e consensus.Event) {
+		branchID, err := vi.GetEventBranchID(e.ID())
+		assertar.NoError(err)
+		vec := NewLowestAfterSeq(0)
+		vec.InitWithEvent(branchID, e)
+		expected[e.ID()] = vec
+		stack := consensus.EventHashStack{}
+		stack.PushAll(e.Parents())
+		for walk := stack.Pop(); walk != nil; walk = stack.Pop() {
+			if expected[*walk].Visit(branchID, e) {
+				stack.PushAll(processed[*walk].Parents())
+			}
+		}
+	}
+	forklessCause := func(a, b consensus.EventHash) bool {
+		aBefore, err := vi.GetHighestBefore(a)
+		assertar.NoError(err)
+		bBranchID, err := vi.GetEventBranchID(b)
+		assertar.NoError(err)
+		if aBefore.IsForkDetected(bBranchID) {
+			return false
+		}
+		yes := validators.NewCounter()
+		for branchID, creatorIdx := range vi.BranchesInfo().BranchIDCreatorIdxs {
+			bLowestAfter := expected[b].Get(consensus.ValidatorIndex(branchID))
+			aHighestBefore := aBefore.VSeq.Get(consensus.ValidatorIndex(branchID))
+			if bLowestAfter <= aHighestBefore.Seq && bLowestAfter != 0 && !aHighestBefore.IsForkDetected() {
+				yes.CountVoteByIndex(creatorIdx)
+			}
+		}
+		return yes.HasQuorum()
+	}
+	checkLowestAfter := func(id consensus.EventHash) {
+		vec, err := vi.GetLowestAfter(id)
+		assertar.NoError(err)
+		for branchID := range vi.BranchesInfo().BranchIDCreatorIdxs {
+			assertar.Equal(expected[id].Get(consensus.ValidatorIndex(branchID)), vec.Get(consensus.ValidatorIndex(branchID)), "LowestAfter of %s at %d", id, branchID)
+		}
+	}
+
+	r := rand.New(rand.NewSource(int64(nodesNum + forksNum))) // nolint:gosec
+	var ordered consensus.Events
+	consensustest.ForEachRandFork(nodes, nodes[:cheatersNum], 30, 3, forksNum, consensustest.NewIntSeededRandGenerator(uint64(nodesNum)), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+			assertar.NoError(vi.Add(e))
+			addExpected(e)
+
+			// the vectors are completed by the events added since the previous read
+			for j := 0; j < 3; j++ {
+				checkLowestAfter(ordered[r.Intn(len(ordered))].ID())
+			}
+		},
+	})
+	assertar.NoError(vi.Flush())
+
+	for _, a := range ordered {
+		checkLowestAfter(a.ID())
+		for _, b := range ordered {
+			observed, err := vi.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(forklessCause(a.ID(), b.ID()), observed, "%s forkless caused by %s", a.ID(), b.ID())
+		}
+		if t.Failed() {
+			return
+		}
+	}
+}
@@ -1,79 +1,61 @@
 package dagindexer
 
 import (
+	"errors"
 	"fmt"
-	"sort"
 
 	"github.com/0xsoniclabs/consensus/consensus"
 )
 
-// medianTimeIndex is a handy index for the MedianTime() func
-type medianTimeIndex struct {
-	weight       consensus.Weight
-	creationTime Timestamp
-}
-
-// MedianTime calculates weighted median of claimed time within highest observed events.
-func (vi *Index) MedianTime(id consensus.EventHash, defaultTime Timestamp) Timestamp {
-	vi.InitBranchesInfo()
+// MedianTime aggregates the claimed times of the highest observed events into the time of the event,
+// with IndexConfig.TimeAggregator, which is the weighted median by default.
+// Cheaters don't influence the time, and defaultTime is used for the validators whose events aren't observed.
+func (vi *Index) MedianTime(id consensus.EventHash, defaultTime Timestamp) (Timestamp, error) {
 	// Get event by hash
-	before := vi.GetMergedHighestBefore(id)
+	before, err := vi.GetMergedHighestBefore(id)
+	if err != nil {
+		return 0, err
+	}
 	if before == nil {
-		vi.crit(fmt.Errorf("event=%s not found", id.String()))
+		return 0, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, id.String())
 	}
 
-	honestTotalWeight := consensus.Weight(0) // isn't equal to validators.TotalWeight(), because doesn't count cheaters
-	highests := make([]medianTimeIndex, 0, len(vi.validatorIdxs))
-	// convert []HighestBefore -> []medianTimeIndex
-	for creatorIdxI := range vi.validators.IDs() {
+	times := make([]ObservedTime, 0, len(vi.validatorIdxs))
+	// convert []HighestBefore -> []ObservedTime
+	for creatorIdxI, creator := range vi.validators.IDs() {
 		creatorIdx := consensus.ValidatorIndex(creatorIdxI)
-		highest := medianTimeIndex{}
-		highest.weight = vi.validators.GetWeightByIdx(creatorIdx)
-		highest.creationTime = before.VTime.Get(creatorIdx)
+		observed := ObservedTime{
+			Validator: creator,
+			Weight:    vi.validators.GetWeightByIdx(creatorIdx),
+			Time:      before.VTime.Get(creatorIdx),
+		}
 		seq := before.VSeq.Get(creatorIdx)
 
 		// edge cases
 		if seq.IsForkDetected() {
 			// cheaters don't influence medianTime
-			highest.weight = 0
+			observed.Weight = 0
+			observed.Cheater = true
 		} else if seq.Seq == 0 {
 			// if no event was observed from this node, then use genesisTime
-			highest.creationTime = defaultTime
+			observed.Time = defaultTime
+		} else {
+			observed.Observed = true
 		}
-
-		highests = append(highests, highest)
-		honestTotalWeight += highest.weight
+		times = append(times, observed)
 	}
-	// it's technically possible honestTotalWeight == 0 (all validators are cheaters)
+	// it's technically possible that the total weight is 0 (all validators are cheaters)
 
-	// sort by claimed time (partial order is enough here, because we need only creationTime)
-	sort.Slice(highests, func(i, j int) bool {
-		a, b := highests[i], highests[j]
-		return a.creationTime < b.creationTime
-	})
-
-	// Calculate weighted median
-	halfWeight := honestTotalWeight / 2
-	var currWeight consensus.Weight
-	var median Timestamp
-	for _, highest := range highests {
-		currWeight += highest.weight
-		if currWeight >= halfWeight {
-			median = highest.creationTime
-			break
-		}
+	aggregator := vi.cfg.TimeAggregator
+	if aggregator == nil {
+		aggregator = WeightedMedian()
 	}
-
-	// sanity check
-	if currWeight < halfWeight || currWeight > honestTotalWeight {
-		vi.crit(fmt.Errorf("median wasn't calculated correctly, median=%d, currWeight=%d, totalWeight=%d, len(highests)=%d, id=%s",
-			median,
-			currWeight,
-			honestTotalWeight,
-			len(highests),
-			id.String(),
-		))
+	time, err := aggregator.AggregateTime(vi, id, times)
+	if err != nil {
+		if errors.Is(err, consensus.ErrInconsistentDB) {
+			return 0, vi.critical(fmt.Errorf("%w, id=%s", err, id.String()))
+		}
+		return 0, err
 	}
-
-	return median
+	return time, nil
 }
@@ -39,8 +39,10 @@ func TestMedianTimeOnIndex(t *testing.T) {
 		before.VSeq.Set(4, BranchSeq{Seq: 1})
 		before.VTime.Set(4, 10)
 
-		vi.SetHighestBefore(e, before)
-		assertar.Equal(Timestamp(1), vi.MedianTime(e, 1))
+		assertar.NoError(vi.SetHighestBefore(e, before))
+		medianTime, err := vi.MedianTime(e, 1)
+		assertar.NoError(err)
+		assertar.Equal(Timestamp(1), medianTime)
 	}
 
 	{ // fork seen = true
@@ -63,8 +65,10 @@ func TestMedianTimeOnIndex(t *testing.T) {
 		before.VSeq.Set(4, BranchSeq{Seq: 1})
 		before.VTime.Set(4, 10)
 
-		vi.SetHighestBefore(e, before)
-		assertar.Equal(Timestamp(10), vi.MedianTime(e, 1))
+		assertar.NoError(vi.SetHighestBefore(e, before))
+		medianTime, err := vi.MedianTime(e, 1)
+		assertar.NoError(err)
+		assertar.Equal(Timestamp(10), medianTime)
 	}
 
 	{ // normal
@@ -87,8 +91,10 @@ func TestMedianTimeOnIndex(t *testing.T) {
 		before.VSeq.Set(4, BranchSeq{Seq: 4})
 		before.VTime.Set(4, 15)
 
-		vi.SetHighestBefore(e, before)
-		assertar.Equal(Timestamp(12), vi.MedianTime(e, 1))
+		assertar.NoError(vi.SetHighestBefore(e, before))
+		medianTime, err := vi.MedianTime(e, 1)
+		assertar.NoError(err)
+		assertar.Equal(Timestamp(12), medianTime)
 	}
 
 }
@@ -172,7 +178,7 @@ func testMedianTime(t *testing.T, dagAscii string, weights []consensus.Weight, c
 	for _, e := range ordered {
 		events[e.ID()] = e
 		assertar.NoError(vi.Add(e))
-		vi.Flush()
+		assertar.NoError(vi.Flush())
 	}
 
 	// check
@@ -181,6 +187,100 @@ func testMedianTime(t *testing.T, dagAscii string, weights []consensus.Weight, c
 		if !ok {
 			continue
 		}
-		assertar.Equal(expected, vi.MedianTime(e.ID(), genesis), name)
+		medianTime, err := vi.MedianTime(e.ID(), genesis)
+		assertar.NoError(err)
+		assertar.Equal(expected, medianTime, name)
+	}
+}
+
+func TestTimeAggregators(t *testing.T) {
+	assertar := assert.New(t)
+
+	times := func() []ObservedTime {
+		return []ObservedTime{
+			{Validator: 1, Weight: 5, Time: 10, Observed: true},
+			{Validator: 2, Weight: 4, Time: 40, Observed: true},
+			{Validator: 3, Weight: 0, Time: 1, Cheater: true},
+			{Validator: 4, Weight: 1, Time: 20, Observed: true},
+			// excluded as stale
+			{Validator: 5, Weight: 0, Time: 2},
+		}
+	}
+	for name, test := range map[string]struct {
+		aggregator TimeAggregator
+		expected   Timestamp
+	}{
+		"median":         {WeightedMedian(), 10},
+		"percentile 0":   {WeightedPercentile(0), 10},
+		"percentile 60":  {WeightedPercentile(60), 20},
+		"percentile 90":  {WeightedPercentile(90), 40},
+		"percentile 100": {WeightedPercentile(100), 40},
+		"mean":           {TrimmedMean(0), 23},
+		"trimmed mean":   {TrimmedMean(10), 22},
+		"trimmed median": {TrimmedMean(50), 10},
+	} {
+		time, err := test.aggregator.AggregateTime(nil, consensus.ZeroEventHash, times())
+		assertar.NoError(err, name)
+		assertar.Equal(test.expected, time, name)
+	}
+}
+
+func TestMedianTime_ExcludeStale(t *testing.T) {
+	assertar := assert.New(t)
+
+	validators := consensus.ArrayToValidators([]consensus.ValidatorID{1, 2, 3}, []consensus.Weight{1, 1, 3})
+	events := make(map[consensus.EventHash]consensus.Event)
+	roots := make(map[consensus.Frame]consensus.EventHashes)
+	newEvent := func(creator consensus.ValidatorID, seq consensus.Seq, frame consensus.Frame, lamport consensus.Lamport, time Timestamp, parents ...consensus.Event) consensus.Event {
+		e := &consensustest.TestEvent{}
+		e.SetEpoch(1)
+		e.SetCreator(creator)
+		e.SetSeq(seq)
+		e.SetFrame(frame)
+		e.SetLamport(lamport)
+		for _, p := range parents {
+			e.AddParent(p.ID())
+		}
+		e.SetID([24]byte{byte(creator), byte(seq)})
+		if len(parents) == 0 || parents[0].Frame() != frame {
+			roots[frame] = append(roots[frame], e.ID())
+		}
+		events[e.ID()] = &eventWithCreationTime{e, time}
+		return events[e.ID()]
+	}
+	frameRoots := func(frame consensus.Frame) (consensus.EventHashes, error) {
+		return roots[frame], nil
+	}
+
+	a1 := newEvent(1, 1, 1, 1, 10)
+	b1 := newEvent(2, 1, 1, 1, 20)
+	c1 := newEvent(3, 1, 1, 1, 1000)
+	a2 := newEvent(1, 2, 2, 2, 30, a1, b1, c1)
+	b2 := newEvent(2, 2, 2, 3, 40, b1, a2)
+	a3 := newEvent(1, 3, 3, 4, 50, a2, b2)
+
+	for _, test := range []struct {
+		aggregator TimeAggregator
+		expected   Timestamp
+	}{
+		{nil, 50},
+		// the last observed event of validator 3 is 2 frames older
+		{ExcludeStale(1, frameRoots, WeightedMedian()), 40},
+		{ExcludeStale(2, frameRoots, WeightedMedian()), 50},
+		{ExcludeStale(1, frameRoots, TrimmedMean(0)), 45},
+	} {
+		cfg := LiteConfig()
+		cfg.TimeAggregator = test.aggregator
+		vi := NewIndex(func(err error) { panic(err) }, cfg)
+		vi.Reset(validators, vi.WrapWithFlushable(memorydb.New()), func(id consensus.EventHash) consensus.Event {
+			return events[id]
+		})
+		for _, e := range []consensus.Event{a1, b1, c1, a2, b2, a3} {
+			assertar.NoError(vi.Add(e))
+			assertar.NoError(vi.Flush())
+		}
+		time, err := vi.MedianTime(a3.ID(), 1)
+		assertar.NoError(err)
+		assertar.Equal(test.expected, time)
 	}
 }
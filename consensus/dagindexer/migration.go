@@ -0,0 +1,322 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// ErrRebuildRequired is returned by MigrateLowestAfter, if the index can't be converted without the events.
+var ErrRebuildRequired = errors.New("DAG index must be rebuilt by reprocessing the epoch")
+
+const (
+	lowestAfterPrefix = "s"
+	migrationPrefix   = "M"
+	// migratedBeforeKey holds the key of the last converted LowestAfter record, or migrationDone
+	migratedBeforeKey = "s"
+	migrationDone     = "done"
+)
+
+// legacyTables are the tables of the index, which stores the complete LowestAfter vectors, see dbStore.table.
+type legacyTables struct {
+	db kvdb.Store
+
+	EventBranch           kvdb.Store `table:"b"`
+	BranchesInfo          kvdb.Store `table:"B"`
+	HighestBeforeSeq      kvdb.Store `table:"S"`
+	LowestAfterSeq        kvdb.Store `table:"s"`
+	BranchEvent           kvdb.Store `table:"i"`
+	HighestBeforeObserved kvdb.Store `table:"O"`
+	// Migration keeps the progress of the conversion of the LowestAfter records, so an interrupted one is resumed
+	Migration kvdb.Store `table:"M"`
+}
+
+// MigrateLowestAfter converts the index, which stores the complete LowestAfter vectors, to the layout completing them on read.
+// The events of the branches and the HighestBeforeObserved vectors are rebuilt from the stored vectors,
+// then the LowestAfter records are rewritten as complete up to the last events of the branches.
+// The index with sink branches is refused with ErrRebuildRequired, as the sinks don't form chains.
+// It's idempotent, so an interrupted migration is repeated.
+func MigrateLowestAfter(db kvdb.Store, progress func(done, total uint64)) error {
+	t := legacyTables{db: db}
+	table.MigrateTables(&t, db)
+
+	raw, err := t.BranchesInfo.Get([]byte("c"))
+	if err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if raw == nil {
+		// no events are indexed
+		return nil
+	}
+	info := &BranchesInfo{}
+	if err := rlp.DecodeBytes(raw, info); err != nil {
+		return fmt.Errorf("%w: %v", consensus.ErrInconsistentDB, err)
+	}
+	if len(info.SinkBranchIDs) != 0 {
+		return fmt.Errorf("%w: %d sink branches", ErrRebuildRequired, len(info.SinkBranchIDs))
+	}
+
+	events, err := countRecords(t.EventBranch)
+	if err != nil {
+		return err
+	}
+	total := 3 * events
+	migratedBefore, err := t.Migration.Get([]byte(migratedBeforeKey))
+	if err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if string(migratedBefore) == migrationDone {
+		return nil
+	}
+	if migratedBefore == nil {
+		// the LowestAfter records aren't rewritten yet, so the complete vectors are read
+		if err := migrateBranchEvents(&t, func(done uint64) { progress(done, total) }); err != nil {
+			return err
+		}
+		if err := migrateHighestBeforeObserved(&t, info, func(done uint64) { progress(events+done, total) }); err != nil {
+			return err
+		}
+	}
+	return migrateLowestAfterRecords(&t, info, migratedBefore, func(done uint64) { progress(2*events+done, total) })
+}
+
+// migrateBranchEvents stores the events by their branches and Seqs, which are held by the own LowestAfter vectors.
+func migrateBranchEvents(t *legacyTables, progress func(done uint64)) error {
+	batch := newMigrationBatch(t.BranchEvent)
+	it := t.EventBranch.NewIterator(nil, nil)
+	defer it.Release()
+	done := uint64(0)
+	for it.Next() {
+		id := consensus.BytesToEvent(it.Key())
+		branchID := consensus.BytesToValidator(it.Value())
+		vec, err := getLegacyLowestAfter(t, id)
+		if err != nil {
+			return err
+		}
+		if err := batch.putFlushing(branchEventKey(branchID, vec.Get(branchID)), id.Bytes()); err != nil {
+			return err
+		}
+		done++
+		progress(done)
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return batch.write()
+}
+
+// migrateHighestBeforeObserved stores HighestBeforeObserved vectors of the events, which detect a fork.
+// The highest observed event of a fork-detected branch is found by a binary search over the branch's events,
+// as the event observes them if it's not lower than their lowest observer on its own branch.
+func migrateHighestBeforeObserved(t *legacyTables, info *BranchesInfo, progress func(done uint64)) error {
+	branches := consensus.ValidatorIndex(len(info.BranchIDCreatorIdxs))
+	batch := newMigrationBatch(t.HighestBeforeObserved)
+	it := t.HighestBeforeSeq.NewIterator(nil, nil)
+	defer it.Release()
+	done := uint64(0)
+	for it.Next() {
+		done++
+		progress(done)
+		before := HighestBeforeSeq(it.Value())
+		detected := false
+		for branchID := consensus.ValidatorIndex(0); branchID < branches && !detected; branchID++ {
+			detected = before.Get(branchID).IsForkDetected()
+		}
+		if !detected {
+			continue
+		}
+		id := consensus.BytesToEvent(it.Key())
+		meBranchID, err := getLegacyEventBranch(t, id)
+		if err != nil {
+			return err
+		}
+		meVec, err := getLegacyLowestAfter(t, id)
+		if err != nil {
+			return err
+		}
+		meSeq := meVec.Get(meBranchID)
+
+		observed := NewLowestAfterSeq(branches)
+		for branchID := consensus.ValidatorIndex(0); branchID < branches; branchID++ {
+			seq := before.Get(branchID)
+			if branchID == meBranchID {
+				observed.Set(branchID, meSeq)
+				continue
+			}
+			if !seq.IsForkDetected() {
+				observed.Set(branchID, seq.Seq)
+				continue
+			}
+			// the pruned events are below the event, so they're treated as observed
+			highest, err := searchSeq(0, info.BranchIDLastSeq[branchID], func(seq consensus.Seq) (bool, error) {
+				observes, err := legacyObserves(t, meBranchID, meSeq, branchID, seq)
+				return !observes, err
+			})
+			if err != nil {
+				return err
+			}
+			if highest == 0 {
+				highest = info.BranchIDLastSeq[branchID] + 1
+			}
+			observed.Set(branchID, highest-1)
+		}
+		if err := batch.putFlushing(id.Bytes(), *observed); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return batch.write()
+}
+
+// legacyObserves returns true if the event with the Seq on the branch is observed by the event with meSeq on meBranchID,
+// or if it's pruned.
+func legacyObserves(t *legacyTables, meBranchID consensus.ValidatorIndex, meSeq consensus.Seq, branchID consensus.ValidatorIndex, seq consensus.Seq) (bool, error) {
+	b, err := t.BranchEvent.Get(branchEventKey(branchID, seq))
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if b == nil {
+		return true, nil
+	}
+	vec, err := getLegacyLowestAfter(t, consensus.BytesToEvent(b))
+	if err != nil {
+		return false, err
+	}
+	lowest := vec.Get(meBranchID)
+	return lowest != 0 && lowest <= meSeq, nil
+}
+
+// migrateLowestAfterRecords rewrites the LowestAfter records, starting after migratedBefore.
+// The progress is written by the batches of the records, so the rewritten records aren't read as the complete vectors.
+func migrateLowestAfterRecords(t *legacyTables, info *BranchesInfo, migratedBefore []byte, progress func(done uint64)) error {
+	checked := NewLowestAfterSeq(consensus.ValidatorIndex(len(info.BranchIDLastSeq)))
+	for branchID, lastSeq := range info.BranchIDLastSeq {
+		checked.Set(consensus.ValidatorIndex(branchID), lastSeq)
+	}
+	// the records of both tables are written by a single batch
+	batch := newMigrationBatch(t.db)
+	migratedBeforeRecord := []byte(migrationPrefix + migratedBeforeKey)
+
+	it := t.LowestAfterSeq.NewIterator(nil, migratedBefore)
+	defer it.Release()
+	done := uint64(0)
+	for it.Next() {
+		done++
+		progress(done)
+		if bytes.Equal(it.Key(), migratedBefore) {
+			continue
+		}
+		memo := &lowestAfterMemo{vec: LowestAfterSeq(it.Value()), checked: *checked}
+		if err := batch.put(append([]byte(lowestAfterPrefix), it.Key()...), encodeLowestAfter(memo)); err != nil {
+			return err
+		}
+		if batch.full() {
+			if err := batch.put(migratedBeforeRecord, it.Key()); err != nil {
+				return err
+			}
+			if err := batch.write(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	// kept until the epoch DB is dropped, as the migration is repeated if the schema version isn't saved
+	if err := batch.put(migratedBeforeRecord, []byte(migrationDone)); err != nil {
+		return err
+	}
+	return batch.write()
+}
+
+func getLegacyLowestAfter(t *legacyTables, id consensus.EventHash) (LowestAfterSeq, error) {
+	b, err := t.LowestAfterSeq.Get(id.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if b == nil {
+		return nil, fmt.Errorf("%w: LowestAfter of event=%s", consensus.ErrInconsistentDB, id.String())
+	}
+	return LowestAfterSeq(b), nil
+}
+
+func getLegacyEventBranch(t *legacyTables, id consensus.EventHash) (consensus.ValidatorIndex, error) {
+	b, err := t.EventBranch.Get(id.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if b == nil {
+		return 0, fmt.Errorf("%w: branch ID of event=%s", consensus.ErrInconsistentDB, id.String())
+	}
+	return consensus.BytesToValidator(b), nil
+}
+
+func countRecords(db kvdb.Store) (uint64, error) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	n := uint64(0)
+	for it.Next() {
+		n++
+	}
+	if err := it.Error(); err != nil {
+		return 0, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return n, nil
+}
+
+// migrationBatch writes the records in batches of kvdb.IdealBatchSize.
+type migrationBatch struct {
+	batch kvdb.Batch
+}
+
+func newMigrationBatch(db kvdb.Store) *migrationBatch {
+	return &migrationBatch{batch: db.NewBatch()}
+}
+
+func (b *migrationBatch) put(key, value []byte) error {
+	if err := b.batch.Put(key, value); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	return nil
+}
+
+// putFlushing puts the record, and writes the batch if it's full.
+func (b *migrationBatch) putFlushing(key, value []byte) error {
+	if err := b.put(key, value); err != nil {
+		return err
+	}
+	if b.full() {
+		return b.write()
+	}
+	return nil
+}
+
+func (b *migrationBatch) full() bool {
+	return b.batch.ValueSize() >= kvdb.IdealBatchSize
+}
+
+func (b *migrationBatch) write() error {
+	if err := b.batch.Write(); err != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	b.batch.Reset()
+	return nil
+}
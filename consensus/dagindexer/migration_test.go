@@ -0,0 +1,171 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/consensus/consensus/consensustest"
+	"github.com/0xsoniclabs/consensus/consensus/vecflushable"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/memorydb"
+)
+
+// TestMigrateLowestAfter converts the index of the first half of the events to the layout, which stores the complete
+// LowestAfter vectors, and compares the migrated index with a fresh one after the second half is added to both.
+func TestMigrateLowestAfter(t *testing.T) {
+	for i, test := range []struct {
+		nodesNum    int
+		cheatersNum int
+		forksNum    int
+	}{
+		{nodesNum: 5},
+		{nodesNum: 5, cheatersNum: 2, forksNum: 5},
+		{nodesNum: 10, cheatersNum: 10, forksNum: 3},
+	} {
+		t.Run(fmt.Sprintf("Test #%d", i), func(t *testing.T) {
+			testMigrateLowestAfter(t, test.nodesNum, test.cheatersNum, test.forksNum)
+		})
+	}
+}
+
+func testMigrateLowestAfter(t *testing.T, nodesNum, cheatersNum, forksNum int) {
+	assertar := assert.New(t)
+
+	nodes := consensustest.GenNodes(nodesNum)
+	validators := consensus.EqualWeightValidators(nodes, 1)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	getEvent := func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	}
+	var ordered consensus.Events
+	consensustest.ForEachRandFork(nodes, nodes[:cheatersNum], 30, 3, forksNum, consensustest.NewIntSeededRandGenerator(uint64(nodesNum)), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			ordered = append(ordered, e)
+		},
+	})
+	half := len(ordered) / 2
+
+	fresh := NewIndex(tCrit, LiteConfig())
+	fresh.Reset(validators, vecflushable.Wrap(memorydb.New(), vecflushable.TestSizeLimit), getEvent)
+	db := memorydb.New()
+	vi := NewIndex(tCrit, LiteConfig())
+	vi.Reset(validators, vecflushable.Wrap(db, vecflushable.TestSizeLimit), getEvent)
+	for _, e := range ordered[:half] {
+		assertar.NoError(fresh.Add(e))
+		assertar.NoError(vi.Add(e))
+	}
+	assertar.NoError(vi.Flush())
+	assertar.NoError(vi.Persist())
+	writeLegacyLayout(t, vi, db, ordered[:half])
+
+	var reported uint64
+	progress := func(done, total uint64) {
+		assertar.LessOrEqual(done, total)
+		reported = done
+	}
+	assertar.NoError(MigrateLowestAfter(db, progress))
+	assertar.Equal(uint64(3*half), reported)
+	// the migration is repeated if the schema version isn't saved
+	assertar.NoError(MigrateLowestAfter(db, progress))
+
+	vi = NewIndex(tCrit, LiteConfig())
+	vi.Reset(validators, vecflushable.Wrap(db, vecflushable.TestSizeLimit), getEvent)
+	for _, e := range ordered[half:] {
+		assertar.NoError(fresh.Add(e))
+		assertar.NoError(vi.Add(e))
+	}
+
+	for _, a := range ordered {
+		expected, err := fresh.GetLowestAfter(a.ID())
+		assertar.NoError(err)
+		vec, err := vi.GetLowestAfter(a.ID())
+		assertar.NoError(err)
+		for branchID := range vi.BranchesInfo().BranchIDCreatorIdxs {
+			assertar.Equal(expected.Get(consensus.ValidatorIndex(branchID)), vec.Get(consensus.ValidatorIndex(branchID)), "LowestAfter of %s at %d", a.ID(), branchID)
+		}
+		for _, b := range ordered {
+			expected, err := fresh.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			observed, err := vi.ForklessCause(a.ID(), b.ID())
+			assertar.NoError(err)
+			assertar.Equal(expected, observed, "%s forkless caused by %s", a.ID(), b.ID())
+		}
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+// writeLegacyLayout rewrites the index to the layout, which stores the complete LowestAfter vectors
+// and doesn't keep the events of the branches and the HighestBeforeObserved vectors.
+func writeLegacyLayout(t *testing.T, vi *Index, db kvdb.Store, events consensus.Events) {
+	t.Helper()
+	for _, e := range events {
+		vec, err := vi.GetLowestAfter(e.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(append([]byte("s"), e.ID().Bytes()...), *vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, prefix := range []string{"i", "O"} {
+		it := db.NewIterator([]byte(prefix), nil)
+		for it.Next() {
+			if err := db.Delete(it.Key()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		it.Release()
+	}
+}
+
+func TestMigrateLowestAfter_RefusesSinks(t *testing.T) {
+	nodes := consensustest.GenNodes(5)
+	processed := make(map[consensus.EventHash]consensus.Event)
+	cfg := LiteConfig()
+	cfg.MaxBranches = 2
+	db := memorydb.New()
+	vi := NewIndex(tCrit, cfg)
+	vi.Reset(consensus.EqualWeightValidators(nodes, 1), vecflushable.Wrap(db, vecflushable.TestSizeLimit), func(id consensus.EventHash) consensus.Event {
+		return processed[id]
+	})
+	consensustest.ForEachRandFork(nodes, nodes[:2], 30, 3, 10, consensustest.NewIntSeededRandGenerator(5), consensustest.ForEachEvent{
+		Process: func(e consensus.Event, name string) {
+			if _, ok := processed[e.ID()]; ok {
+				return
+			}
+			processed[e.ID()] = e
+			if err := vi.Add(e); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+	if err := vi.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vi.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	if len(vi.BranchesInfo().SinkBranchIDs) == 0 {
+		t.Fatal("no sink branches")
+	}
+	assert.ErrorIs(t, MigrateLowestAfter(db, func(done, total uint64) {}), ErrRebuildRequired)
+}
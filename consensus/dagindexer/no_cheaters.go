@@ -1,34 +1,39 @@
 package dagindexer
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/0xsoniclabs/consensus/consensus"
 )
 
 // NoCheaters excludes events which are observed by selfParents as cheaters.
 // Called by emitter to exclude cheater's events from potential parents list.
-func (vi *Index) NoCheaters(selfParent *consensus.EventHash, options consensus.EventHashes) consensus.EventHashes {
+func (vi *Index) NoCheaters(selfParent *consensus.EventHash, options consensus.EventHashes) (consensus.EventHashes, error) {
 	if selfParent == nil {
-		return options
+		return options, nil
+	}
+	if err := vi.InitBranchesInfo(); err != nil {
+		return nil, err
 	}
-	vi.InitBranchesInfo()
 
 	if !vi.AtLeastOneFork() {
-		return options
+		return options, nil
 	}
 
 	// no need to merge, because every branch is marked by IsForkDetected if fork is observed
-	highest := vi.GetHighestBefore(*selfParent)
+	highest, err := vi.getHighestBefore(*selfParent)
+	if err != nil {
+		return nil, err
+	}
 	filtered := make(consensus.EventHashes, 0, len(options))
 	for _, id := range options {
 		e := vi.getEvent(id)
 		if e == nil {
-			vi.crit(errors.New("event not found"))
+			return nil, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, id.String())
 		}
 		if !highest.VSeq.Get(vi.validatorIdxs[e.Creator()]).IsForkDetected() {
 			filtered.Add(id)
 		}
 	}
-	return filtered
+	return filtered, nil
 }
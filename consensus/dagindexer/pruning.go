@@ -0,0 +1,88 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+const prunedBeforeKey = "l"
+
+// prunedTables are the prefixes of the per-event tables, see dbStore.table.
+// The branch events aren't pruned, as they aren't keyed by the events, and the pruned ones are skipped.
+var prunedTables = []string{"T", "b", "S", "s", "O", "t"}
+
+// prunableStore is an index DB, which supports pruning, see vecflushable.VecFlushable.
+type prunableStore interface {
+	Prune(prefix, limit []byte) (int, uint64, error)
+}
+
+// Prune deletes the vectors and the branch IDs of the events of the epoch with Lamport time below the bound.
+// Afterwards, events which refer to a pruned event, directly or through the not yet observed ancestors,
+// are rejected with consensus.ErrPruned. So the bound must be below the parents of any event, which may be added later,
+// see consensusengine.PruningConfig.
+// Returns the number of the deleted records and their size in bytes.
+func (vi *Index) Prune(epoch consensus.Epoch, before consensus.Lamport) (int, uint64, error) {
+	if err := vi.store.checkPrunable(); err != nil {
+		return 0, 0, err
+	}
+	prunedBefore, err := vi.GetPrunedBefore()
+	if err != nil {
+		return 0, 0, err
+	}
+	if before <= prunedBefore {
+		return 0, 0, nil
+	}
+
+	// the bound is saved first, so the pruned events are never mistaken for inconsistency
+	if err := vi.store.setPrunedBefore(before); err != nil {
+		return 0, 0, err
+	}
+	if err := vi.Flush(); err != nil {
+		return 0, 0, err
+	}
+	vi.prunedBefore = &before
+
+	records, size, err := vi.store.prune(epoch, before)
+	if err != nil {
+		return 0, 0, err
+	}
+	vi.OnDropNotFlushed()
+	vi.cache.ForklessCause.Purge()
+	return records, size, nil
+}
+
+// GetPrunedBefore returns the Lamport time, below which the events are pruned, or 0 if the index isn't pruned.
+func (vi *Index) GetPrunedBefore() (consensus.Lamport, error) {
+	if vi.prunedBefore != nil {
+		return *vi.prunedBefore, nil
+	}
+	before, err := vi.store.getPrunedBefore()
+	if err != nil {
+		return 0, err
+	}
+	vi.prunedBefore = &before
+	return before, nil
+}
+
+// checkPruned returns consensus.ErrPruned if the event is pruned.
+func (vi *Index) checkPruned(id consensus.EventHash) error {
+	before, err := vi.GetPrunedBefore()
+	if err != nil {
+		return err
+	}
+	if id.Lamport() < before {
+		return fmt.Errorf("%w: event=%s", consensus.ErrPruned, id.String())
+	}
+	return nil
+}
@@ -11,70 +11,44 @@
 package dagindexer
 
 import (
-	"errors"
-
-	"github.com/ethereum/go-ethereum/rlp"
-
 	"github.com/0xsoniclabs/consensus/consensus"
-	"github.com/0xsoniclabs/kvdb"
 )
 
-func (vi *Index) setRlp(table kvdb.Store, key []byte, val interface{}) {
-	buf, err := rlp.EncodeToBytes(val)
-	if err != nil {
-		vi.crit(err)
-	}
-
-	if err := table.Put(key, buf); err != nil {
-		vi.crit(err)
-	}
+func (vi *Index) setBranchesInfo(info *BranchesInfo) error {
+	return vi.store.setBranchesInfo(info)
 }
 
-func (vi *Index) getRlp(table kvdb.Store, key []byte, to interface{}) interface{} {
-	buf, err := table.Get(key)
-	if err != nil {
-		vi.crit(err)
-	}
-	if buf == nil {
-		return nil
-	}
-
-	err = rlp.DecodeBytes(buf, to)
-	if err != nil {
-		vi.crit(err)
-	}
-	return to
+func (vi *Index) getBranchesInfo() (*BranchesInfo, error) {
+	return vi.store.getBranchesInfo()
 }
 
-func (vi *Index) setBranchesInfo(info *BranchesInfo) {
-	key := []byte("c")
-
-	vi.setRlp(vi.table.BranchesInfo, key, info)
+// SetEventBranchID stores the event's global branch ID
+func (vi *Index) SetEventBranchID(id consensus.EventHash, branchID consensus.ValidatorIndex) error {
+	return vi.store.setEventBranch(id, branchID)
 }
 
-func (vi *Index) getBranchesInfo() *BranchesInfo {
-	key := []byte("c")
-
-	w, exists := vi.getRlp(vi.table.BranchesInfo, key, &BranchesInfo{}).(*BranchesInfo)
-	if !exists {
-		return nil
+// GetEventBranchID reads the event's global branch ID
+func (vi *Index) GetEventBranchID(id consensus.EventHash) (consensus.ValidatorIndex, error) {
+	branchID, ok, err := vi.store.getEventBranch(id)
+	if err != nil {
+		return 0, err
 	}
-
-	return w
+	if !ok {
+		if err := vi.checkPruned(id); err != nil {
+			return 0, err
+		}
+		return 0, vi.inconsistencyErr("failed to read branch ID of event=%s", id.String())
+	}
+	return branchID, nil
 }
 
-// SetEventBranchID stores the event's global branch ID
-func (vi *Index) SetEventBranchID(id consensus.EventHash, branchID consensus.ValidatorIndex) {
-	vi.setBytes(vi.table.EventBranch, id, branchID.Bytes())
+// SetBranchEvent stores the event with the Seq on the global branch
+func (vi *Index) SetBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq, id consensus.EventHash) error {
+	return vi.store.setBranchEvent(branchID, seq, id)
 }
 
-// GetEventBranchID reads the event's global branch ID
-func (vi *Index) GetEventBranchID(id consensus.EventHash) consensus.ValidatorIndex {
-	b := vi.getBytes(vi.table.EventBranch, id)
-	if b == nil {
-		vi.crit(errors.New("failed to read event's branch ID (inconsistent DB)"))
-		return 0
-	}
-	branchID := consensus.BytesToValidator(b)
-	return branchID
+// GetBranchEvent reads the event with the Seq on the global branch.
+// Returns false if there's no such event, e.g. if the branch was created by a fork with a higher Seq.
+func (vi *Index) GetBranchEvent(branchID consensus.ValidatorIndex, seq consensus.Seq) (consensus.EventHash, bool, error) {
+	return vi.store.getBranchEvent(branchID, seq)
 }
@@ -1,91 +1,109 @@
 package dagindexer
 
 import (
+	"fmt"
+
 	"github.com/0xsoniclabs/consensus/consensus"
-	"github.com/0xsoniclabs/kvdb"
 )
 
-func (vi *Index) getBytes(table kvdb.Store, id consensus.EventHash) []byte {
-	key := id.Bytes()
-	b, err := table.Get(key)
-	if err != nil {
-		vi.crit(err)
-	}
-	return b
-}
-
-func (vi *Index) setBytes(table kvdb.Store, id consensus.EventHash, b []byte) {
-	key := id.Bytes()
-	err := table.Put(key, b)
-	if err != nil {
-		vi.crit(err)
-	}
+// getHighestBeforeSeq reads the Seq part of HighestBefore vector from DB
+// Returns nil vector if the event isn't indexed.
+func (vi *Index) getHighestBeforeSeq(id consensus.EventHash) (*HighestBeforeSeq, error) {
+	return vi.store.getHighestBeforeSeq(id)
 }
 
 // GetHighestBefore reads the vector from DB
-func (vi *Index) GetHighestBefore(id consensus.EventHash) *HighestBefore {
-	var vSeq *HighestBeforeSeq = nil
-	if vSeqVal, ok := vi.cache.HighestBeforeSeq.Get(id); ok {
-		vSeq = vSeqVal.(*HighestBeforeSeq) // Assertion needed because of raw bytes.
-	} else {
-		vSeqVal := HighestBeforeSeq(vi.getBytes(vi.table.HighestBeforeSeq, id))
-		if vSeqVal != nil {
-			vSeq = &vSeqVal
-			vi.cache.HighestBeforeSeq.Add(id, vSeq, uint(len(*vSeq)))
-		}
+// Returns nil vector if the event isn't indexed.
+func (vi *Index) GetHighestBefore(id consensus.EventHash) (*HighestBefore, error) {
+	vSeq, err := vi.getHighestBeforeSeq(id)
+	if err != nil {
+		return nil, err
 	}
-
-	var vTime *HighestBeforeTime = nil
-	if vTimeVal, ok := vi.cache.HighestBeforeTime.Get(id); ok {
-		vTime = vTimeVal.(*HighestBeforeTime) // Assertion needed because of raw bytes.
-	} else {
-		vTimeVal := HighestBeforeTime(vi.getBytes(vi.table.HighestBeforeTime, id))
-		if vTimeVal != nil {
-			vTime = &vTimeVal
-			vi.cache.HighestBeforeTime.Add(id, vTime, uint(len(*vTime)))
-		}
+	vTime, err := vi.store.getHighestBeforeTime(id)
+	if err != nil {
+		return nil, err
 	}
 
 	if vSeq != nil && vTime != nil {
 		return &HighestBefore{
 			VSeq:  vSeq,
 			VTime: vTime,
-		}
+		}, nil
 	} else {
-		return nil
+		return nil, nil
 	}
 }
 
-// GetLowestAfter reads the vector from DB
-func (vi *Index) GetLowestAfter(id consensus.EventHash) *LowestAfter {
-	if bVal, okGet := vi.cache.LowestAfterSeq.Get(id); okGet {
-		return bVal.(*LowestAfter) // Cast needed because simplewlru uses raw interface{}.
+// GetLowestAfter reads the vector from DB, and completes it by the events added since it was stored, see completeLowestAfter.
+// The completed vector is written back, so a read may leave records to be written by Flush or dropped by DropNotFlushed.
+// Returns nil vector if the event isn't indexed.
+func (vi *Index) GetLowestAfter(id consensus.EventHash) (*LowestAfter, error) {
+	memo, err := vi.getLowestAfterMemo(id)
+	if err != nil || memo == nil {
+		return nil, err
 	}
+	return &memo.vec, nil
+}
 
-	b := LowestAfter(vi.getBytes(vi.table.LowestAfterSeq, id))
-	if b == nil {
-		return nil
+func (vi *Index) getLowestAfterMemo(id consensus.EventHash) (*lowestAfterMemo, error) {
+	memo, err := vi.store.getLowestAfter(id)
+	if err != nil || memo == nil {
+		return nil, err
+	}
+	if err := vi.completeLowestAfter(id, memo); err != nil {
+		return nil, err
 	}
-	vi.cache.LowestAfterSeq.Add(id, &b, uint(len(b)))
-	return &b
+	return memo, nil
 }
 
 // SetHighestBefore stores the vectors into DB
-func (vi *Index) SetHighestBefore(id consensus.EventHash, vec *HighestBefore) {
-	vi.setBytes(vi.table.HighestBeforeTime, id, *vec.VTime)
-	vi.cache.HighestBeforeTime.Add(id, vec.VTime, uint(len(*vec.VTime)))
-	vi.setBytes(vi.table.HighestBeforeSeq, id, *vec.VSeq)
-	vi.cache.HighestBeforeSeq.Add(id, vec.VSeq, uint(len(*vec.VSeq)))
+func (vi *Index) SetHighestBefore(id consensus.EventHash, vec *HighestBefore) error {
+	return vi.store.setHighestBefore(id, vec)
 }
 
 // SetLowestAfter stores the vector into DB
-func (vi *Index) SetLowestAfter(id consensus.EventHash, seq *LowestAfterSeq) {
-	vi.setBytes(vi.table.LowestAfterSeq, id, *seq)
-	vi.cache.LowestAfterSeq.Add(id, seq, uint(len(*seq)))
+func (vi *Index) SetLowestAfter(id consensus.EventHash, seq *LowestAfterSeq) error {
+	return vi.store.setLowestAfter(id, seq)
 }
 
 func (vi *Index) OnDropNotFlushed() {
-	vi.cache.HighestBeforeSeq.Purge()
-	vi.cache.LowestAfterSeq.Purge()
-	vi.cache.HighestBeforeTime.Purge()
+	vi.store.purgeCaches()
+}
+
+// getHighestBefore is GetHighestBefore for events which must be indexed.
+func (vi *Index) getHighestBefore(id consensus.EventHash) (*HighestBefore, error) {
+	vec, err := vi.GetHighestBefore(id)
+	if err != nil {
+		return nil, err
+	}
+	if vec == nil {
+		if err := vi.checkPruned(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: HighestBefore of event=%s", consensus.ErrEventNotFound, id.String())
+	}
+	return vec, nil
+}
+
+// getLowestAfter is GetLowestAfter for events which must be indexed.
+func (vi *Index) getLowestAfter(id consensus.EventHash) (*LowestAfter, error) {
+	memo, err := vi.getIndexedLowestAfterMemo(id)
+	if err != nil {
+		return nil, err
+	}
+	return &memo.vec, nil
+}
+
+func (vi *Index) getIndexedLowestAfterMemo(id consensus.EventHash) (*lowestAfterMemo, error) {
+	memo, err := vi.getLowestAfterMemo(id)
+	if err != nil {
+		return nil, err
+	}
+	if memo == nil {
+		if err := vi.checkPruned(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: LowestAfter of event=%s", consensus.ErrEventNotFound, id.String())
+	}
+	return memo, nil
 }
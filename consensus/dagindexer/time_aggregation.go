@@ -0,0 +1,203 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ObservedTime is the claimed time of the highest event of a validator, observed by an event.
+type ObservedTime struct {
+	Validator consensus.ValidatorID
+	// Weight is zero for the cheaters, so they don't influence the time
+	Weight consensus.Weight
+	// Time is the default time if no event of the validator is observed
+	Time Timestamp
+	// Observed is true if an event of the validator is observed, and the validator isn't a cheater
+	Observed bool
+	Cheater  bool
+}
+
+// TimeAggregator aggregates the times of the highest observed events into the time of the event, see Index.MedianTime.
+// The times are ordered by validator index, and may be modified.
+type TimeAggregator interface {
+	AggregateTime(vi *Index, id consensus.EventHash, times []ObservedTime) (Timestamp, error)
+}
+
+// FrameRootsFn returns the roots of the frame, e.g. from consensusstore.Store.GetFrameRoots.
+type FrameRootsFn func(frame consensus.Frame) (consensus.EventHashes, error)
+
+type weightedPercentile struct {
+	percent uint64
+}
+
+// WeightedMedian is the default TimeAggregator, the stake-weighted median of the times.
+func WeightedMedian() TimeAggregator {
+	return WeightedPercentile(50)
+}
+
+// WeightedPercentile aggregates the times into the lowest time, which is reached by the percent of the honest weight.
+func WeightedPercentile(percent uint) TimeAggregator {
+	return weightedPercentile{percent: uint64(min(percent, 100))}
+}
+
+func (p weightedPercentile) AggregateTime(_ *Index, _ consensus.EventHash, times []ObservedTime) (Timestamp, error) {
+	honestTotalWeight := totalTimesWeight(times)
+	sortTimes(times)
+
+	threshold := consensus.Weight(uint64(honestTotalWeight) * p.percent / 100)
+	var currWeight consensus.Weight
+	var percentile Timestamp
+	for _, t := range times {
+		if t.Weight == 0 {
+			// the cheaters and the excluded validators don't influence the time
+			continue
+		}
+		currWeight += t.Weight
+		if currWeight >= threshold {
+			percentile = t.Time
+			break
+		}
+	}
+
+	// sanity check
+	if currWeight < threshold || currWeight > honestTotalWeight {
+		return 0, fmt.Errorf("%w: percentile wasn't calculated correctly, percentile=%d, currWeight=%d, totalWeight=%d, len(times)=%d",
+			consensus.ErrInconsistentDB,
+			percentile,
+			currWeight,
+			honestTotalWeight,
+			len(times),
+		)
+	}
+	return percentile, nil
+}
+
+type trimmedMean struct {
+	percent uint64
+}
+
+// TrimmedMean aggregates the times into the stake-weighted mean, excluding the percent of the honest weight
+// with the lowest times and the same percent with the highest times.
+// If nothing is left, e.g. if the percent is 50, the times are aggregated into the weighted median.
+func TrimmedMean(percent uint) TimeAggregator {
+	return trimmedMean{percent: uint64(min(percent, 50))}
+}
+
+func (m trimmedMean) AggregateTime(vi *Index, id consensus.EventHash, times []ObservedTime) (Timestamp, error) {
+	honestTotalWeight := uint64(totalTimesWeight(times))
+	trim := honestTotalWeight * m.percent / 100
+	if 2*trim >= honestTotalWeight {
+		return WeightedMedian().AggregateTime(vi, id, times)
+	}
+	sortTimes(times)
+
+	// the weight of every time is clipped to the range [trim, total-trim) of the accumulated weight
+	sum, kept := new(big.Int), uint64(0)
+	from := uint64(0)
+	for _, t := range times {
+		to := from + uint64(t.Weight)
+		lo, hi := max(from, trim), min(to, honestTotalWeight-trim)
+		if hi > lo {
+			sum.Add(sum, new(big.Int).Mul(new(big.Int).SetUint64(t.Time), new(big.Int).SetUint64(hi-lo)))
+			kept += hi - lo
+		}
+		from = to
+	}
+	return Timestamp(sum.Div(sum, new(big.Int).SetUint64(kept)).Uint64()), nil
+}
+
+type staleExclusion struct {
+	frames     consensus.Frame
+	frameRoots FrameRootsFn
+	inner      TimeAggregator
+}
+
+// ExcludeStale excludes the validators whose last observed event is older than the number of frames,
+// relative to the frame of the event, before the times are aggregated by the inner aggregator.
+// The stale validators are excluded like the cheaters, while the validators whose events aren't observed keep the default time.
+// The frames of the observed events are found by the roots of the validators, so the index must be reset with getEvent.
+func ExcludeStale(frames consensus.Frame, frameRoots FrameRootsFn, inner TimeAggregator) TimeAggregator {
+	return staleExclusion{
+		frames:     frames,
+		frameRoots: frameRoots,
+		inner:      inner,
+	}
+}
+
+func (s staleExclusion) AggregateTime(vi *Index, id consensus.EventHash, times []ObservedTime) (Timestamp, error) {
+	e := vi.getEvent(id)
+	if e == nil {
+		return 0, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, id.String())
+	}
+	if err := vi.InitBranchesInfo(); err != nil {
+		return 0, err
+	}
+
+	// a validator is fresh if a root of its within the frames is observed,
+	// because a validator creates a root in every frame which its events reach
+	fresh := make([]bool, len(times))
+	lowest := consensus.FirstFrame
+	if e.Frame() > s.frames {
+		lowest = e.Frame() - s.frames
+	}
+	for f := e.Frame(); f >= lowest; f-- {
+		roots, err := s.frameRoots(f)
+		if err != nil {
+			return 0, err
+		}
+		for _, root := range roots {
+			branchID, err := vi.GetEventBranchID(root)
+			if err != nil {
+				return 0, err
+			}
+			creatorIdx := vi.branchesInfo.BranchIDCreatorIdxs[branchID]
+			if fresh[creatorIdx] {
+				continue
+			}
+			observed, err := vi.Observes(id, root)
+			if err != nil {
+				return 0, err
+			}
+			fresh[creatorIdx] = observed
+		}
+		if f == consensus.FirstFrame {
+			break
+		}
+	}
+	for i := range times {
+		if times[i].Observed && !fresh[i] {
+			times[i].Weight = 0
+			times[i].Observed = false
+		}
+	}
+	return s.inner.AggregateTime(vi, id, times)
+}
+
+func totalTimesWeight(times []ObservedTime) consensus.Weight {
+	total := consensus.Weight(0)
+	for _, t := range times {
+		total += t.Weight
+	}
+	return total
+}
+
+// sortTimes sorts by claimed time (partial order is enough here, because only the times are aggregated)
+func sortTimes(times []ObservedTime) {
+	slices.SortFunc(times, func(a, b ObservedTime) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+}
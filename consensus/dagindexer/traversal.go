@@ -11,7 +11,7 @@
 package dagindexer
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/0xsoniclabs/consensus/consensus"
 )
@@ -19,7 +19,7 @@ import (
 // DfsSubgraph iterates all the event which are observed by head, and accepted by a filter
 // Excluding head
 // filter MAY BE called twice for the same event.
-func (vi *Index) DfsSubgraph(head consensus.Event, walk func(consensus.EventHash) (godeeper bool)) error {
+func (vi *Index) DfsSubgraph(head consensus.Event, walk func(consensus.EventHash) (godeeper bool, err error)) error {
 	stack := make(consensus.EventHashStack, 0, vi.validators.Len()*5)
 
 	// first element
@@ -29,13 +29,17 @@ func (vi *Index) DfsSubgraph(head consensus.Event, walk func(consensus.EventHash
 		curr := *next
 
 		// filter
-		if !walk(curr) {
+		godeeper, err := walk(curr)
+		if err != nil {
+			return err
+		}
+		if !godeeper {
 			continue
 		}
 
 		event := vi.getEvent(curr)
 		if event == nil {
-			return errors.New("event not found " + curr.String())
+			return fmt.Errorf("%w: %s", consensus.ErrEventNotFound, curr.String())
 		}
 
 		// memorize parents
@@ -44,3 +48,23 @@ func (vi *Index) DfsSubgraph(head consensus.Event, walk func(consensus.EventHash
 
 	return nil
 }
+
+// observesByTraversal returns true if a observes b, by the DAG traversal from a.
+// It's used when the vectors don't tell it, e.g. if a is on a sink branch, whose events don't form a chain.
+func (vi *Index) observesByTraversal(a, b consensus.EventHash) (bool, error) {
+	e := vi.getEvent(a)
+	if e == nil {
+		return false, fmt.Errorf("%w: %s", consensus.ErrEventNotFound, a.String())
+	}
+	found := false
+	visited := consensus.EventHashSet{}
+	err := vi.DfsSubgraph(e, func(walk consensus.EventHash) (bool, error) {
+		if found || visited.Contains(walk) {
+			return false, nil
+		}
+		visited.Add(walk)
+		found = walk == b
+		return !found && walk.Lamport() > b.Lamport(), nil
+	})
+	return found, err
+}
@@ -50,6 +50,11 @@ type (
 	}
 
 	LowestAfter = LowestAfterSeq
+
+	// HighestBeforeObserved is a vector of highest events (their Seq) which are observed by source event,
+	// encoded like LowestAfterSeq. Unlike HighestBeforeSeq, it isn't reset by the fork detection,
+	// so it's stored only for the events which detect a fork.
+	HighestBeforeObserved = LowestAfterSeq
 )
 
 // NewHighestBefore creates new HighestBefore vector.
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package dagindexer
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+	"github.com/0xsoniclabs/kvdb"
+	"github.com/0xsoniclabs/kvdb/table"
+)
+
+// VectorsView reads the vectors of an index DB without the Index, e.g. of an archived epoch.
+// It doesn't cache and doesn't write.
+// LowestAfter vectors aren't read, as the stored ones are incomplete: Index completes them on read
+// by a search over the branches' events, which needs the events for the sink branches, see Index.GetLowestAfter.
+type VectorsView struct {
+	table struct {
+		HighestBeforeTime kvdb.Store `table:"T"`
+		EventBranch       kvdb.Store `table:"b"`
+		BranchesInfo      kvdb.Store `table:"B"`
+		HighestBeforeSeq  kvdb.Store `table:"S"`
+		PruningState      kvdb.Store `table:"P"`
+	}
+}
+
+// NewVectorsView creates VectorsView over the DB, which Index was reset with.
+func NewVectorsView(db kvdb.Store) *VectorsView {
+	v := &VectorsView{}
+	table.MigrateTables(&v.table, db)
+	return v
+}
+
+// GetHighestBefore returns the vector of the event, or nil if the event isn't indexed.
+func (v *VectorsView) GetHighestBefore(id consensus.EventHash) (*HighestBefore, error) {
+	seq, err := v.table.HighestBeforeSeq.Get(id.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	time, err := v.table.HighestBeforeTime.Get(id.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if seq == nil || time == nil {
+		return nil, nil
+	}
+	vSeq, vTime := HighestBeforeSeq(seq), HighestBeforeTime(time)
+	return &HighestBefore{
+		VSeq:  &vSeq,
+		VTime: &vTime,
+	}, nil
+}
+
+// GetBranchesInfo returns the branches of the vectors, or nil if the index is empty.
+func (v *VectorsView) GetBranchesInfo() (*BranchesInfo, error) {
+	buf, err := v.table.BranchesInfo.Get([]byte("c"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if buf == nil {
+		return nil, nil
+	}
+	info := &BranchesInfo{}
+	if err := rlp.DecodeBytes(buf, info); err != nil {
+		return nil, fmt.Errorf("%w: %v", consensus.ErrInconsistentDB, err)
+	}
+	return info, nil
+}
+
+// ForEachEvent calls fn for every indexed event with its global branch ID, in the order of Lamport time,
+// until fn returns false.
+func (v *VectorsView) ForEachEvent(fn func(id consensus.EventHash, branchID consensus.ValidatorIndex) bool) error {
+	it := v.table.EventBranch.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != len(consensus.EventHash{}) || len(it.Value()) != 4 {
+			return fmt.Errorf("%w: malformed branch ID record key=%x", consensus.ErrInconsistentDB, it.Key())
+		}
+		if !fn(consensus.BytesToEvent(it.Key()), consensus.BytesToValidator(it.Value())) {
+			break
+		}
+	}
+	if it.Error() != nil {
+		return fmt.Errorf("%w: %w", consensus.ErrStorageIO, it.Error())
+	}
+	return nil
+}
+
+// GetPrunedBefore returns the Lamport time, below which the events are pruned, see Index.Prune.
+func (v *VectorsView) GetPrunedBefore() (consensus.Lamport, error) {
+	b, err := v.table.PruningState.Get([]byte(prunedBeforeKey))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", consensus.ErrStorageIO, err)
+	}
+	if b == nil {
+		return 0, nil
+	}
+	return consensus.BytesToLamport(b), nil
+}
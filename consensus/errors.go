@@ -0,0 +1,26 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensus
+
+import "errors"
+
+// Error kinds shared by the consensus engine, store and DAG index.
+// Returned errors wrap one of these, so callers can classify them with errors.Is.
+var (
+	// ErrInconsistentDB indicates that persisted consensus state violates an invariant.
+	ErrInconsistentDB = errors.New("inconsistent DB")
+	// ErrStorageIO indicates a failure of the underlying key-value storage.
+	ErrStorageIO = errors.New("storage I/O failure")
+	// ErrEventNotFound indicates that a referenced event is unknown.
+	ErrEventNotFound = errors.New("event not found")
+	// ErrPruned indicates that the referenced state was pruned from the epoch DB.
+	ErrPruned = errors.New("pruned")
+)
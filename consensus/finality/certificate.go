@@ -0,0 +1,233 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+// Package finality verifies atropos finality certificates without the DAG index.
+package finality
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// ErrInvalidCertificate is wrapped by all the errors returned by Verify.
+var ErrInvalidCertificate = errors.New("invalid finality certificate")
+
+// Certificate proves that Atropos is elected as the atropos of Frame.
+// It contains the roots which the Decider root observes, directly or through other roots,
+// down to Frame. The certificate is RLP-encodable.
+//
+// The forkless cause relations (Root.Observes) can't be checked without the DAG, so the certificate
+// carries the signatures of the validators over SigningHash, and Verify requires a quorum of them.
+type Certificate struct {
+	Epoch   consensus.Epoch
+	Frame   consensus.Frame
+	Atropos consensus.EventHash
+	// Decider is the root whose aggregated votes crossed the quorum
+	Decider consensus.EventHash
+	// Roots are ordered by frame, validator and hash
+	Roots []Root
+	// YesWeight is the aggregated vote of Decider for the validator of Atropos
+	YesWeight consensus.Weight
+	// Quorum is the threshold YesWeight has crossed
+	Quorum consensus.Weight
+	// Signatures are attached by the application, as the keys of the validators are application-specific
+	Signatures []Signature
+}
+
+// Signature is the signature of a validator over the SigningHash of the certificate.
+type Signature struct {
+	Validator consensus.ValidatorID
+	Signature []byte
+}
+
+// SignatureVerifier returns true if the signature of the validator over the hash is valid.
+type SignatureVerifier func(validator consensus.ValidatorID, hash consensus.Hash, signature []byte) bool
+
+// SigningHash returns the hash of the epoch, the frame and the atropos, which the validators sign.
+func (cert *Certificate) SigningHash() consensus.Hash {
+	hasher := sha256.New()
+	hasher.Write(cert.Epoch.Bytes())
+	hasher.Write(cert.Frame.Bytes())
+	hasher.Write(cert.Atropos.Bytes())
+	return consensus.BytesToHash(hasher.Sum(nil))
+}
+
+// Root is a root of the certificate.
+type Root struct {
+	Hash      consensus.EventHash
+	Frame     consensus.Frame
+	Validator consensus.ValidatorID
+	// Observes are the roots of the previous frame, which are forkless caused by this root.
+	// Empty for the roots of the certified frame.
+	Observes []consensus.EventHash
+}
+
+// Verify checks that the certificate proves the election of its atropos by the validators.
+// It checks that the signatures of a quorum of the validators attest the atropos,
+// and repeats the votes aggregation of the election over the certificate's roots.
+func Verify(cert *Certificate, validators *consensus.Validators, verifier SignatureVerifier) error {
+	if err := verifySignatures(cert, validators, verifier); err != nil {
+		return err
+	}
+	roots := make(map[consensus.EventHash]*Root, len(cert.Roots))
+	for i := range cert.Roots {
+		root := &cert.Roots[i]
+		if _, ok := roots[root.Hash]; ok {
+			return invalid("duplicate root %s", root.Hash)
+		}
+		if !validators.Exists(root.Validator) {
+			return invalid("root %s of unknown validator %d", root.Hash, root.Validator)
+		}
+		if root.Frame < cert.Frame {
+			return invalid("root %s of frame %d below certified frame", root.Hash, root.Frame)
+		}
+		if root.Frame == cert.Frame && len(root.Observes) != 0 {
+			return invalid("root %s of certified frame observes roots", root.Hash)
+		}
+		roots[root.Hash] = root
+	}
+	for _, root := range roots {
+		for i, observed := range root.Observes {
+			if slices.Contains(root.Observes[:i], observed) {
+				return invalid("root %s observes %s twice", root.Hash, observed)
+			}
+			if r, ok := roots[observed]; !ok || r.Frame+1 != root.Frame {
+				return invalid("root %s observes %s, which isn't a root of the previous frame", root.Hash, observed)
+			}
+		}
+	}
+
+	decider, ok := roots[cert.Decider]
+	if !ok || decider.Frame < cert.Frame+2 {
+		return invalid("decider %s isn't a root above frame %d", cert.Decider, cert.Frame+1)
+	}
+	atropos, ok := roots[cert.Atropos]
+	if !ok || atropos.Frame != cert.Frame {
+		return invalid("atropos %s isn't a root of frame %d", cert.Atropos, cert.Frame)
+	}
+	if !slices.ContainsFunc(cert.Roots, func(root Root) bool {
+		return root.Frame == cert.Frame+1 && slices.Contains(root.Observes, cert.Atropos)
+	}) {
+		return invalid("atropos %s isn't observed by frame %d roots", cert.Atropos, cert.Frame+1)
+	}
+
+	// votes of the roots for every validator's root of the certified frame, multiplied by the voter's weight
+	ordered := slices.Clone(cert.Roots)
+	slices.SortStableFunc(ordered, func(a, b Root) int {
+		return cmp.Compare(a.Frame, b.Frame)
+	})
+	idxs := validators.Idxs()
+	votes := make(map[consensus.EventHash][]int64, len(ordered))
+	for _, root := range ordered {
+		if root.Frame == cert.Frame || root.Frame > decider.Frame {
+			continue
+		}
+		aggregation, observedWeight := aggregate(root, cert.Frame, votes, roots, validators)
+		if root.Hash == cert.Decider {
+			return decide(cert, aggregation, observedWeight, validators, roots)
+		}
+		weight := int64(validators.GetWeightByIdx(idxs[root.Validator]))
+		for i := range aggregation {
+			if aggregation[i] >= 0 {
+				aggregation[i] = weight
+			} else {
+				aggregation[i] = -weight
+			}
+		}
+		votes[root.Hash] = aggregation
+	}
+	return invalid("decider %s isn't reached", cert.Decider)
+}
+
+// verifySignatures checks that the certificate is signed by a quorum of the validators.
+func verifySignatures(cert *Certificate, validators *consensus.Validators, verifier SignatureVerifier) error {
+	if verifier == nil {
+		return invalid("no signature verifier")
+	}
+	hash := cert.SigningHash()
+	signers := validators.NewCounter()
+	for _, sig := range cert.Signatures {
+		if !validators.Exists(sig.Validator) {
+			return invalid("signature of unknown validator %d", sig.Validator)
+		}
+		if !verifier(sig.Validator, hash, sig.Signature) {
+			return invalid("invalid signature of validator %d", sig.Validator)
+		}
+		if !signers.CountVoteByID(sig.Validator) {
+			return invalid("duplicate signature of validator %d", sig.Validator)
+		}
+	}
+	if !signers.HasQuorum() {
+		return invalid("signed by %d of quorum %d", signers.Sum(), validators.Quorum())
+	}
+	return nil
+}
+
+// aggregate returns the votes of the observed roots, or the direct votes if the root is in the frame above the certified one.
+func aggregate(root Root, frame consensus.Frame, votes map[consensus.EventHash][]int64, roots map[consensus.EventHash]*Root, validators *consensus.Validators) ([]int64, int64) {
+	idxs := validators.Idxs()
+	aggregation := make([]int64, validators.Len())
+	if root.Frame == frame+1 {
+		for i := range aggregation {
+			aggregation[i] = -1
+		}
+	}
+	observedWeight := int64(0)
+	for _, observed := range root.Observes {
+		idx := idxs[roots[observed].Validator]
+		observedWeight += int64(validators.GetWeightByIdx(idx))
+		if root.Frame == frame+1 {
+			aggregation[idx] = 1
+			continue
+		}
+		for i, v := range votes[observed] {
+			aggregation[i] += v
+		}
+	}
+	return aggregation, observedWeight
+}
+
+// decide checks that the aggregated votes of the decider elect the validator of the atropos.
+func decide(cert *Certificate, aggregation []int64, observedWeight int64, validators *consensus.Validators, roots map[consensus.EventHash]*Root) error {
+	// Q = ceil((4*TotalValidatorWeight - 3*observedRootsWeight)/3)
+	q := (4*int64(validators.TotalWeight()) - 3*observedWeight + 3 - 1) / 3
+	if q <= 0 {
+		return invalid("observed weight %d exceeds the total weight", observedWeight)
+	}
+	if q != int64(cert.Quorum) {
+		return invalid("quorum %d mismatches the calculated %d", cert.Quorum, q)
+	}
+	idxs := validators.Idxs()
+	for _, candidate := range validators.SortedIDs() {
+		vote := aggregation[idxs[candidate]]
+		if vote >= q {
+			if candidate != roots[cert.Atropos].Validator {
+				return invalid("validator %d is elected instead of %d", candidate, roots[cert.Atropos].Validator)
+			}
+			if vote != int64(cert.YesWeight) {
+				return invalid("yes weight %d mismatches the calculated %d", cert.YesWeight, vote)
+			}
+			return nil
+		}
+		if vote > -q {
+			return invalid("validator %d is undecided", candidate)
+		}
+	}
+	return invalid("all the validators are voted out")
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCertificate, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package finality
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/0xsoniclabs/consensus/consensus"
+)
+
+// testCertificate returns a certificate of frame 1 for 4 validators with equal weights,
+// where every root observes all the roots of the previous frame.
+func testCertificate() (*Certificate, *consensus.Validators) {
+	ids := []consensus.ValidatorID{1, 2, 3, 4}
+	validators := consensus.EqualWeightValidators(ids, 1)
+	cert := &Certificate{
+		Epoch:     1,
+		Frame:     1,
+		YesWeight: 4,
+		Quorum:    2,
+	}
+	var prev []consensus.EventHash
+	for frame := consensus.Frame(1); frame <= 2; frame++ {
+		var hashes []consensus.EventHash
+		for _, id := range ids {
+			hash := consensus.EventHash{byte(frame), byte(id)}
+			cert.Roots = append(cert.Roots, Root{
+				Hash:      hash,
+				Frame:     frame,
+				Validator: id,
+				Observes:  append([]consensus.EventHash{}, prev...),
+			})
+			hashes = append(hashes, hash)
+		}
+		prev = hashes
+	}
+	cert.Decider = consensus.EventHash{3, 1}
+	cert.Roots = append(cert.Roots, Root{
+		Hash:      cert.Decider,
+		Frame:     3,
+		Validator: 1,
+		Observes:  prev,
+	})
+	cert.Atropos = cert.Roots[0].Hash
+	for _, id := range ids {
+		cert.Signatures = append(cert.Signatures, Signature{Validator: id, Signature: testSign(id, cert.SigningHash())})
+	}
+	return cert, validators
+}
+
+// testSign is a fake signature, which is checked by testVerifier.
+func testSign(validator consensus.ValidatorID, hash consensus.Hash) []byte {
+	return append(validator.Bytes(), hash.Bytes()...)
+}
+
+func testVerifier(validator consensus.ValidatorID, hash consensus.Hash, signature []byte) bool {
+	return bytes.Equal(signature, testSign(validator, hash))
+}
+
+func TestVerify(t *testing.T) {
+	cert, validators := testCertificate()
+	if err := Verify(cert, validators, testVerifier); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(cert, validators, nil); !errors.Is(err, ErrInvalidCertificate) {
+		t.Fatalf("expected ErrInvalidCertificate without verifier, got %v", err)
+	}
+
+	for name, tamper := range map[string]func(cert *Certificate){
+		"wrong atropos": func(cert *Certificate) {
+			cert.Atropos = cert.Roots[1].Hash
+		},
+		"unknown atropos": func(cert *Certificate) {
+			cert.Atropos = consensus.EventHash{0xff}
+		},
+		"wrong yes weight": func(cert *Certificate) {
+			cert.YesWeight--
+		},
+		"wrong quorum": func(cert *Certificate) {
+			cert.Quorum++
+		},
+		"decider too low": func(cert *Certificate) {
+			cert.Decider = cert.Roots[4].Hash
+		},
+		"unknown observed root": func(cert *Certificate) {
+			cert.Roots[4].Observes[0] = consensus.EventHash{0xff}
+		},
+		"observed root of wrong frame": func(cert *Certificate) {
+			cert.Roots[8].Observes[0] = cert.Roots[0].Hash
+		},
+		"duplicate observed root": func(cert *Certificate) {
+			cert.Roots[8].Observes[1] = cert.Roots[8].Observes[0]
+		},
+		"duplicate root": func(cert *Certificate) {
+			cert.Roots = append(cert.Roots, cert.Roots[0])
+		},
+		"unknown validator": func(cert *Certificate) {
+			cert.Roots[5].Validator = 5
+		},
+		"no quorum of signatures": func(cert *Certificate) {
+			cert.Signatures = cert.Signatures[:2]
+		},
+		"duplicate signature": func(cert *Certificate) {
+			cert.Signatures[3] = cert.Signatures[0]
+		},
+		"invalid signature": func(cert *Certificate) {
+			cert.Signatures[0].Signature = testSign(cert.Signatures[0].Validator, consensus.Hash{})
+		},
+		"signature of unknown validator": func(cert *Certificate) {
+			cert.Signatures = append(cert.Signatures, Signature{Validator: 5, Signature: testSign(5, cert.SigningHash())})
+		},
+		"signed by other epoch": func(cert *Certificate) {
+			cert.Epoch++
+		},
+		"undecided": func(cert *Certificate) {
+			// only 2 of 4 frame 2 roots observe the atropos
+			for i := 4; i < 6; i++ {
+				cert.Roots[i].Observes = cert.Roots[i].Observes[1:]
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cert, validators := testCertificate()
+			tamper(cert)
+			if err := Verify(cert, validators, testVerifier); !errors.Is(err, ErrInvalidCertificate) {
+				t.Fatalf("expected ErrInvalidCertificate, got %v", err)
+			}
+		})
+	}
+}
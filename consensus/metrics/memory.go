@@ -0,0 +1,194 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package metrics
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryRegistry keeps the metrics in memory.
+// It's intended for tests, and as a source for bridging to an exporter.
+type MemoryRegistry struct {
+	mu         sync.Mutex
+	counters   map[string]*MemoryCounter
+	gauges     map[string]*MemoryGauge
+	histograms map[string]*MemoryHistogram
+}
+
+// MemoryCounter is a Counter of MemoryRegistry.
+type MemoryCounter struct {
+	value atomic.Int64
+}
+
+// MemoryGauge is a Gauge of MemoryRegistry.
+type MemoryGauge struct {
+	value atomic.Int64
+}
+
+// MemoryHistogram is a Histogram of MemoryRegistry, which keeps summary statistics.
+type MemoryHistogram struct {
+	mu      sync.Mutex
+	summary HistogramSummary
+}
+
+// HistogramSummary summarizes the observed values.
+type HistogramSummary struct {
+	Count    uint64
+	Sum      float64
+	Min, Max float64
+}
+
+// Snapshot is a copy of all the values of MemoryRegistry.
+type Snapshot struct {
+	Counters   map[string]int64
+	Gauges     map[string]int64
+	Histograms map[string]HistogramSummary
+}
+
+// NewMemoryRegistry creates an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		counters:   make(map[string]*MemoryCounter),
+		gauges:     make(map[string]*MemoryGauge),
+		histograms: make(map[string]*MemoryHistogram),
+	}
+}
+
+// Counter returns the counter, creating it if needed.
+func (r *MemoryRegistry) Counter(name string) Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.counters[name]
+	if !ok {
+		c = &MemoryCounter{}
+		r.counters[name] = c
+	}
+	return c
+}
+
+// Gauge returns the gauge, creating it if needed.
+func (r *MemoryRegistry) Gauge(name string) Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.gauges[name]
+	if !ok {
+		g = &MemoryGauge{}
+		r.gauges[name] = g
+	}
+	return g
+}
+
+// Histogram returns the histogram, creating it if needed.
+func (r *MemoryRegistry) Histogram(name string) Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[name]
+	if !ok {
+		h = &MemoryHistogram{}
+		r.histograms[name] = h
+	}
+	return h
+}
+
+// CounterValue returns the value of the counter, 0 if it doesn't exist.
+func (r *MemoryRegistry) CounterValue(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.counters[name]; ok {
+		return c.Value()
+	}
+	return 0
+}
+
+// GaugeValue returns the value of the gauge, 0 if it doesn't exist.
+func (r *MemoryRegistry) GaugeValue(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g, ok := r.gauges[name]; ok {
+		return g.Value()
+	}
+	return 0
+}
+
+// HistogramSummary returns the summary of the histogram, zero if it doesn't exist.
+func (r *MemoryRegistry) HistogramSummary(name string) HistogramSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.histograms[name]; ok {
+		return h.Summary()
+	}
+	return HistogramSummary{}
+}
+
+// Snapshot copies all the values.
+func (r *MemoryRegistry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{
+		Counters:   make(map[string]int64, len(r.counters)),
+		Gauges:     make(map[string]int64, len(r.gauges)),
+		Histograms: make(map[string]HistogramSummary, len(r.histograms)),
+	}
+	for name, c := range r.counters {
+		s.Counters[name] = c.Value()
+	}
+	for name, g := range r.gauges {
+		s.Gauges[name] = g.Value()
+	}
+	for name, h := range r.histograms {
+		s.Histograms[name] = h.Summary()
+	}
+	return s
+}
+
+func (c *MemoryCounter) Inc(delta int64) {
+	c.value.Add(delta)
+}
+
+func (c *MemoryCounter) Value() int64 {
+	return c.value.Load()
+}
+
+func (g *MemoryGauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+func (g *MemoryGauge) Value() int64 {
+	return g.value.Load()
+}
+
+func (h *MemoryHistogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.summary.Count == 0 {
+		h.summary.Min, h.summary.Max = value, value
+	}
+	h.summary.Count++
+	h.summary.Sum += value
+	h.summary.Min = math.Min(h.summary.Min, value)
+	h.summary.Max = math.Max(h.summary.Max, value)
+}
+
+func (h *MemoryHistogram) Summary() HistogramSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.summary
+}
+
+// Mean returns the average of the observed values, 0 if there are none.
+func (s HistogramSummary) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
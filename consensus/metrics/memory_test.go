@@ -0,0 +1,85 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMemoryRegistry_Values(t *testing.T) {
+	r := NewMemoryRegistry()
+
+	r.Counter("c").Inc(2)
+	r.Counter("c").Inc(3)
+	r.Gauge("g").Set(7)
+	r.Gauge("g").Set(4)
+	r.Histogram("h").Observe(2)
+	r.Histogram("h").Observe(-1)
+	r.Histogram("h").Observe(5)
+
+	if got := r.CounterValue("c"); got != 5 {
+		t.Errorf("unexpected counter value, expected: 5, got: %d", got)
+	}
+	if got := r.GaugeValue("g"); got != 4 {
+		t.Errorf("unexpected gauge value, expected: 4, got: %d", got)
+	}
+	want := HistogramSummary{Count: 3, Sum: 6, Min: -1, Max: 5}
+	if got := r.HistogramSummary("h"); got != want {
+		t.Errorf("unexpected histogram summary, expected: %v, got: %v", want, got)
+	}
+	if got := r.HistogramSummary("h").Mean(); got != 2 {
+		t.Errorf("unexpected histogram mean, expected: 2, got: %v", got)
+	}
+	if got := r.CounterValue("missing"); got != 0 {
+		t.Errorf("unexpected value of missing counter: %d", got)
+	}
+
+	snapshot := r.Snapshot()
+	if snapshot.Counters["c"] != 5 || snapshot.Gauges["g"] != 4 || snapshot.Histograms["h"] != want {
+		t.Errorf("unexpected snapshot: %v", snapshot)
+	}
+}
+
+func TestMemoryRegistry_ConcurrentUse(t *testing.T) {
+	r := NewMemoryRegistry()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				r.Counter("c").Inc(1)
+				r.Histogram("h").Observe(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := r.CounterValue("c"); got != 8000 {
+		t.Errorf("unexpected counter value, expected: 8000, got: %d", got)
+	}
+	if got := r.HistogramSummary("h").Count; got != 8000 {
+		t.Errorf("unexpected histogram count, expected: 8000, got: %d", got)
+	}
+}
+
+func TestNoop(t *testing.T) {
+	if OrNoop(nil) != Noop {
+		t.Error("nil registry isn't replaced with Noop")
+	}
+	r := NewMemoryRegistry()
+	if OrNoop(r) != r {
+		t.Error("registry is replaced")
+	}
+	Noop.Counter("c").Inc(1)
+	Noop.Gauge("g").Set(1)
+	Noop.Histogram("h").Observe(1)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+// Package metrics is a minimal instrumentation abstraction.
+// Components report to a Registry, which may be bridged to any metrics exporter.
+package metrics
+
+// Counter is a monotonically increasing value.
+type Counter interface {
+	Inc(delta int64)
+}
+
+// Gauge is a value which may go up and down.
+type Gauge interface {
+	Set(value int64)
+}
+
+// Histogram is a distribution of observed values.
+type Histogram interface {
+	Observe(value float64)
+}
+
+// Registry provides metrics by name. The same name always refers to the same metric.
+// Implementations must be safe for concurrent use.
+type Registry interface {
+	Counter(name string) Counter
+	Gauge(name string) Gauge
+	Histogram(name string) Histogram
+}
+
+// Noop is a Registry which discards all the values.
+var Noop Registry = noop{}
+
+type noop struct{}
+
+func (noop) Counter(string) Counter     { return noop{} }
+func (noop) Gauge(string) Gauge         { return noop{} }
+func (noop) Histogram(string) Histogram { return noop{} }
+func (noop) Inc(int64)                  {}
+func (noop) Set(int64)                  {}
+func (noop) Observe(float64)            {}
+
+// OrNoop returns r, or Noop if r is nil.
+func OrNoop(r Registry) Registry {
+	if r == nil {
+		return Noop
+	}
+	return r
+}
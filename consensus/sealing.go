@@ -0,0 +1,272 @@
+// Copyright (c) 2025 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package consensus
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// EpochProgress accumulates the blocks of the current epoch. Sealing policies decide on it.
+type EpochProgress struct {
+	Blocks uint64
+	Events Metric
+	// Cheaters are detected in the epoch, without duplicates
+	Cheaters Cheaters
+	// StartTime and LastTime are the median times of the first and the last atropoi of the epoch,
+	// they're zero if Sealer has no MedianTimeFn
+	StartTime uint64
+	LastTime  uint64
+}
+
+// SealingPolicy decides whether the epoch is sealed after the last block.
+type SealingPolicy interface {
+	// Seal returns the validators of the next epoch, or nil if the epoch must not be sealed.
+	Seal(progress *EpochProgress, validators *Validators) *Validators
+}
+
+// NextValidatorsFn returns the validators of the next epoch.
+type NextValidatorsFn func(progress *EpochProgress, validators *Validators) *Validators
+
+// SameValidators keeps the validators of the current epoch.
+func SameValidators(_ *EpochProgress, validators *Validators) *Validators {
+	return validators.Copy()
+}
+
+// WithoutCheaters removes the cheaters detected in the epoch from the validators.
+// The validators are kept unchanged if all of them are cheaters.
+func WithoutCheaters(progress *EpochProgress, validators *Validators) *Validators {
+	builder := validators.Builder()
+	for _, cheater := range progress.Cheaters {
+		delete(builder, cheater)
+	}
+	if len(builder) == 0 {
+		return validators.Copy()
+	}
+	return builder.Build()
+}
+
+// sealingPolicy is a SealingPolicy made of a condition and NextValidatorsFn.
+type sealingPolicy struct {
+	condition func(progress *EpochProgress) bool
+	next      NextValidatorsFn
+}
+
+func (p sealingPolicy) Seal(progress *EpochProgress, validators *Validators) *Validators {
+	if !p.condition(progress) {
+		return nil
+	}
+	return p.next(progress, validators)
+}
+
+func newSealingPolicy(next NextValidatorsFn, condition func(progress *EpochProgress) bool) SealingPolicy {
+	if next == nil {
+		next = SameValidators
+	}
+	return sealingPolicy{condition, next}
+}
+
+// SealAfterBlocks seals the epoch after n blocks. nil next keeps the same validators.
+func SealAfterBlocks(n uint64, next NextValidatorsFn) SealingPolicy {
+	return newSealingPolicy(next, func(progress *EpochProgress) bool {
+		return progress.Blocks >= n
+	})
+}
+
+// SealAfterEvents seals the epoch after num confirmed events or size bytes of them, whichever comes first.
+// Zero limit is disabled. nil next keeps the same validators.
+func SealAfterEvents(num uint32, size uint64, next NextValidatorsFn) SealingPolicy {
+	return newSealingPolicy(next, func(progress *EpochProgress) bool {
+		return num != 0 && progress.Events.Num >= num || size != 0 && progress.Events.Size >= size
+	})
+}
+
+// SealAfterDuration seals the epoch once the median time advances by duration since the first block.
+// Median times are expected in nanoseconds. nil next keeps the same validators.
+func SealAfterDuration(duration time.Duration, next NextValidatorsFn) SealingPolicy {
+	return newSealingPolicy(next, func(progress *EpochProgress) bool {
+		return progress.LastTime >= progress.StartTime && progress.LastTime-progress.StartTime >= uint64(duration)
+	})
+}
+
+// SealOnCheaters seals the epoch once a cheater is detected. nil next removes the cheaters from the validators.
+func SealOnCheaters(next NextValidatorsFn) SealingPolicy {
+	if next == nil {
+		next = WithoutCheaters
+	}
+	return newSealingPolicy(next, func(progress *EpochProgress) bool {
+		return len(progress.Cheaters) != 0
+	})
+}
+
+// AnyOf seals the epoch if any of the policies does, with the validators of the first such policy.
+func AnyOf(policies ...SealingPolicy) SealingPolicy {
+	return anyOf(policies)
+}
+
+type anyOf []SealingPolicy
+
+func (pp anyOf) Seal(progress *EpochProgress, validators *Validators) *Validators {
+	for _, p := range pp {
+		if next := p.Seal(progress, validators); next != nil {
+			return next
+		}
+	}
+	return nil
+}
+
+// MedianTimeFn returns the median time of the atropos, e.g. with dagindexer.Index.MedianTime.
+type MedianTimeFn func(atropos EventHash) (uint64, error)
+
+// Sealer applies SealingPolicy to the blocks of ConsensusCallbacks.
+// The progress isn't persisted, it's rebuilt from the applied blocks on Bootstrap and Rewind, see ConsensusCallbacks.RestoreEpoch.
+type Sealer struct {
+	policy     SealingPolicy
+	validators *Validators
+	medianTime MedianTimeFn
+	crit       func(error)
+	progress   EpochProgress
+}
+
+// NewSealer creates Sealer instance for the epoch of the validators.
+// medianTime is optional, required only by SealAfterDuration.
+// crit is an optional hook, notified of medianTime failures, the block time isn't updated on a failure.
+func NewSealer(policy SealingPolicy, validators *Validators, medianTime MedianTimeFn, crit func(error)) *Sealer {
+	return &Sealer{
+		policy:     policy,
+		validators: validators,
+		medianTime: medianTime,
+		crit:       crit,
+	}
+}
+
+// Progress returns the progress of the current epoch.
+func (s *Sealer) Progress() EpochProgress {
+	progress := s.progress
+	progress.Cheaters = slices.Clone(progress.Cheaters)
+	return progress
+}
+
+// Restore resets the progress to the applied blocks of the current epoch, e.g. after a restart or a rewind.
+// The median time of a pruned atropos is unknown, so the epoch is timed from the first atropos which isn't pruned.
+func (s *Sealer) Restore(validators *Validators, blocks []AppliedBlock) {
+	s.validators = validators
+	s.progress = EpochProgress{}
+	for _, block := range blocks {
+		s.progress.Blocks++
+		s.addCheaters(block.Cheaters)
+		s.progress.Events.Num += block.Events.Num
+		s.progress.Events.Size += block.Events.Size
+	}
+	if s.medianTime == nil || len(blocks) == 0 {
+		return
+	}
+	for _, block := range blocks {
+		medianTime, err := s.medianTime(block.Atropos)
+		if errors.Is(err, ErrPruned) {
+			continue
+		}
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		s.progress.StartTime = medianTime
+		break
+	}
+	if s.progress.StartTime == 0 {
+		return
+	}
+	medianTime, err := s.medianTime(blocks[len(blocks)-1].Atropos)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	s.progress.LastTime = medianTime
+}
+
+// Wrap returns the callbacks, which seal the epoch according to the policy.
+// If the wrapped EndBlock seals the epoch, its validators take precedence.
+func (s *Sealer) Wrap(callbacks ConsensusCallbacks) ConsensusCallbacks {
+	return ConsensusCallbacks{
+		RestoreEpoch: func(validators *Validators, blocks []AppliedBlock) {
+			if callbacks.RestoreEpoch != nil {
+				callbacks.RestoreEpoch(validators, blocks)
+			}
+			s.Restore(validators, blocks)
+		},
+		BeginBlock: func(block *Block) BlockCallbacks {
+			var wrapped BlockCallbacks
+			if callbacks.BeginBlock != nil {
+				wrapped = callbacks.BeginBlock(block)
+			}
+			s.beginBlock(block)
+			var metric Metric
+			return BlockCallbacks{
+				ApplyEvent: func(e Event) {
+					metric.Num++
+					metric.Size += uint64(e.Size())
+					if wrapped.ApplyEvent != nil {
+						wrapped.ApplyEvent(e)
+					}
+				},
+				EndBlock: func() *Validators {
+					s.progress.Events.Num += metric.Num
+					s.progress.Events.Size += metric.Size
+
+					var sealEpoch *Validators
+					if wrapped.EndBlock != nil {
+						sealEpoch = wrapped.EndBlock()
+					}
+					if sealEpoch == nil {
+						sealEpoch = s.policy.Seal(&s.progress, s.validators)
+					}
+					if sealEpoch != nil {
+						s.validators = sealEpoch
+						s.progress = EpochProgress{}
+					}
+					return sealEpoch
+				},
+			}
+		},
+	}
+}
+
+func (s *Sealer) beginBlock(block *Block) {
+	s.progress.Blocks++
+	s.addCheaters(block.Cheaters)
+	if s.medianTime == nil {
+		return
+	}
+	medianTime, err := s.medianTime(block.Atropos)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if s.progress.StartTime == 0 {
+		s.progress.StartTime = medianTime
+	}
+	s.progress.LastTime = medianTime
+}
+
+func (s *Sealer) addCheaters(cheaters Cheaters) {
+	for _, cheater := range cheaters {
+		if !slices.Contains(s.progress.Cheaters, cheater) {
+			s.progress.Cheaters = append(s.progress.Cheaters, cheater)
+		}
+	}
+}
+
+func (s *Sealer) fail(err error) {
+	if s.crit != nil {
+		s.crit(err)
+	}
+}
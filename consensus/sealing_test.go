@@ -0,0 +1,190 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealingPolicies(t *testing.T) {
+	validators := ArrayToValidators([]ValidatorID{1, 2, 3}, []Weight{1, 2, 3})
+	withoutOne := ArrayToValidators([]ValidatorID{2, 3}, []Weight{2, 3})
+	for name, test := range map[string]struct {
+		policy   SealingPolicy
+		progress EpochProgress
+		expected *Validators
+	}{
+		"blocks below limit":  {SealAfterBlocks(3, nil), EpochProgress{Blocks: 2}, nil},
+		"blocks limit":        {SealAfterBlocks(3, nil), EpochProgress{Blocks: 3}, validators},
+		"events below limits": {SealAfterEvents(10, 1000, nil), EpochProgress{Events: Metric{Num: 9, Size: 999}}, nil},
+		"events num limit":    {SealAfterEvents(10, 1000, nil), EpochProgress{Events: Metric{Num: 10}}, validators},
+		"events size limit":   {SealAfterEvents(10, 1000, nil), EpochProgress{Events: Metric{Size: 1000}}, validators},
+		"events disabled":     {SealAfterEvents(0, 0, nil), EpochProgress{Events: Metric{Num: 100, Size: 100}}, nil},
+		"duration below":      {SealAfterDuration(time.Second, nil), EpochProgress{StartTime: 10, LastTime: 10 + uint64(time.Second) - 1}, nil},
+		"duration":            {SealAfterDuration(time.Second, nil), EpochProgress{StartTime: 10, LastTime: 10 + uint64(time.Second)}, validators},
+		"no cheaters":         {SealOnCheaters(nil), EpochProgress{Blocks: 100}, nil},
+		"cheaters":            {SealOnCheaters(nil), EpochProgress{Cheaters: Cheaters{1}}, withoutOne},
+		"cheaters same":       {SealOnCheaters(SameValidators), EpochProgress{Cheaters: Cheaters{1}}, validators},
+		"blocks without cheaters": {
+			SealAfterBlocks(1, WithoutCheaters), EpochProgress{Blocks: 1, Cheaters: Cheaters{1}}, withoutOne,
+		},
+		"all cheaters": {
+			SealOnCheaters(nil), EpochProgress{Cheaters: Cheaters{1, 2, 3}}, validators,
+		},
+		"any of first": {
+			AnyOf(SealAfterBlocks(1, nil), SealOnCheaters(nil)), EpochProgress{Blocks: 1, Cheaters: Cheaters{1}}, validators,
+		},
+		"any of second": {
+			AnyOf(SealAfterBlocks(2, nil), SealOnCheaters(nil)), EpochProgress{Blocks: 1, Cheaters: Cheaters{1}}, withoutOne,
+		},
+		"any of none": {AnyOf(SealAfterBlocks(2, nil), SealOnCheaters(nil)), EpochProgress{Blocks: 1}, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := test.policy.Seal(&test.progress, validators)
+			if test.expected == nil {
+				if got != nil {
+					t.Fatalf("unexpected sealing, got: %v", got)
+				}
+				return
+			}
+			if got == nil || got.String() != test.expected.String() {
+				t.Fatalf("unexpected validators, want: %v, got: %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSealer_Wrap(t *testing.T) {
+	validators := ArrayToValidators([]ValidatorID{1, 2, 3}, []Weight{1, 2, 3})
+	times := map[EventHash]uint64{}
+	var critical []error
+	sealer := NewSealer(
+		AnyOf(SealAfterEvents(0, 1000, nil), SealOnCheaters(nil), SealAfterDuration(10, nil)),
+		validators,
+		func(atropos EventHash) (uint64, error) {
+			if medianTime, ok := times[atropos]; ok {
+				return medianTime, nil
+			}
+			return 0, errors.New("unknown atropos")
+		},
+		func(err error) {
+			critical = append(critical, err)
+		},
+	)
+
+	var applied, ended int
+	var appSeal *Validators
+	callbacks := sealer.Wrap(ConsensusCallbacks{
+		BeginBlock: func(block *Block) BlockCallbacks {
+			return BlockCallbacks{
+				ApplyEvent: func(Event) {
+					applied++
+				},
+				EndBlock: func() *Validators {
+					ended++
+					return appSeal
+				},
+			}
+		},
+	})
+	block := func(id byte, medianTime uint64, events int, cheaters ...ValidatorID) *Validators {
+		atropos := EventHash{id}
+		times[atropos] = medianTime
+		cb := callbacks.BeginBlock(&Block{Atropos: atropos, Cheaters: cheaters})
+		for range events {
+			cb.ApplyEvent(&MutableBaseEvent{})
+		}
+		return cb.EndBlock()
+	}
+
+	eventSize := uint64((&MutableBaseEvent{}).Size())
+	if sealed := block(1, 100, 10); sealed != nil {
+		t.Fatal("unexpected sealing")
+	}
+	progress := sealer.Progress()
+	if progress.Blocks != 1 || progress.Events.Num != 10 || progress.Events.Size != 10*eventSize || progress.StartTime != 100 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	// cheaters are removed
+	sealed := block(2, 105, 1, 1)
+	if sealed == nil || sealed.Exists(1) || sealed.Len() != 2 {
+		t.Fatalf("unexpected sealing: %v", sealed)
+	}
+	if progress := sealer.Progress(); progress.Blocks != 0 || len(progress.Cheaters) != 0 {
+		t.Fatalf("progress isn't reset: %+v", progress)
+	}
+
+	// duration since the first block of the new epoch
+	if sealed := block(3, 200, 1); sealed != nil {
+		t.Fatal("unexpected sealing")
+	}
+	if sealed := block(4, 210, 1); sealed == nil || sealed.String() != ArrayToValidators([]ValidatorID{2, 3}, []Weight{2, 3}).String() {
+		t.Fatalf("unexpected sealing: %v", sealed)
+	}
+
+	// size limit
+	if sealed := block(5, 300, int(1000/eventSize)+1); sealed == nil {
+		t.Fatal("epoch isn't sealed")
+	}
+
+	// application's sealing takes precedence
+	appSeal = validators
+	if sealed := block(6, 400, 1); sealed != validators {
+		t.Fatalf("unexpected sealing: %v", sealed)
+	}
+	appSeal = nil
+
+	// median time failure
+	callbacks.BeginBlock(&Block{Atropos: EventHash{7}}).EndBlock()
+	if len(critical) != 1 {
+		t.Fatalf("expected a critical error, got: %v", critical)
+	}
+	if applied != 10+1+1+1+int(1000/eventSize)+1+1 || ended != 7 {
+		t.Fatalf("wrapped callbacks aren't called, applied: %d, ended: %d", applied, ended)
+	}
+}
+
+func TestSealer_Restore(t *testing.T) {
+	validators := ArrayToValidators([]ValidatorID{1, 2, 3}, []Weight{1, 2, 3})
+	times := map[EventHash]uint64{{2}: 100, {3}: 150}
+	var critical []error
+	sealer := NewSealer(
+		AnyOf(SealAfterBlocks(4, nil), SealAfterDuration(100, nil)),
+		validators,
+		func(atropos EventHash) (uint64, error) {
+			if medianTime, ok := times[atropos]; ok {
+				return medianTime, nil
+			}
+			return 0, ErrPruned
+		},
+		func(err error) {
+			critical = append(critical, err)
+		},
+	)
+	callbacks := sealer.Wrap(ConsensusCallbacks{})
+
+	// the first atropos is pruned, the epoch is timed from the second one
+	callbacks.RestoreEpoch(validators, []AppliedBlock{
+		{Atropos: EventHash{1}, Cheaters: Cheaters{1}, Events: Metric{Num: 2, Size: 20}},
+		{Atropos: EventHash{2}, Cheaters: Cheaters{1, 2}, Events: Metric{Num: 1, Size: 10}},
+		{Atropos: EventHash{3}},
+	})
+	progress := sealer.Progress()
+	if progress.Blocks != 3 || progress.Events != (Metric{Num: 3, Size: 30}) || len(progress.Cheaters) != 2 ||
+		progress.StartTime != 100 || progress.LastTime != 150 || len(critical) != 0 {
+		t.Fatalf("unexpected progress: %+v, critical: %v", progress, critical)
+	}
+
+	// the epoch is sealed by the restored blocks and the next one
+	times[EventHash{4}] = 160
+	if sealed := callbacks.BeginBlock(&Block{Atropos: EventHash{4}}).EndBlock(); sealed == nil {
+		t.Fatal("epoch isn't sealed")
+	}
+
+	// a rewind drops the progress of the rewound blocks
+	callbacks.RestoreEpoch(validators, nil)
+	if progress := sealer.Progress(); progress.Blocks != 0 || progress.StartTime != 0 || len(progress.Cheaters) != 0 {
+		t.Fatalf("progress isn't reset: %+v", progress)
+	}
+}
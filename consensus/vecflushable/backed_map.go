@@ -11,8 +11,11 @@
 package vecflushable
 
 import (
+	"bytes"
+
 	"github.com/ethereum/go-ethereum/common"
 
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 	"github.com/0xsoniclabs/kvdb"
 )
 
@@ -25,6 +28,7 @@ type backedMap struct {
 	memSize    int
 	maxMemSize int
 	batchSize  int
+	unloads    metrics.Counter
 }
 
 func newBackedMap(backup kvdb.Store, maxMemSize, batchSize int) *backedMap {
@@ -33,6 +37,7 @@ func newBackedMap(backup kvdb.Store, maxMemSize, batchSize int) *backedMap {
 		backup:     backup,
 		maxMemSize: maxMemSize,
 		batchSize:  batchSize,
+		unloads:    metrics.Noop.Counter(""),
 	}
 }
 
@@ -107,6 +112,56 @@ func (w *backedMap) unload(toUnload int) error {
 	if err != nil {
 		return err
 	}
+	w.unloads.Inc(1)
 
 	return nil
 }
+
+// prune deletes the pairs with keys starting with prefix, which are less than prefix+limit,
+// from both the memory and the backup. Returns the number and the size of the deleted pairs.
+func (w *backedMap) prune(prefix, limit []byte) (int, uint64, error) {
+	end := append(common.CopyBytes(prefix), limit...)
+	pruned := make(map[string]struct{})
+	size := uint64(0)
+	for key, val := range w.cache {
+		if !bytes.HasPrefix([]byte(key), prefix) || key >= string(end) {
+			continue
+		}
+		delete(w.cache, key)
+		rmS := mapMemEst(len(key), len(val))
+		if rmS <= w.memSize {
+			w.memSize -= rmS
+		} else {
+			w.memSize = 0
+		}
+		pruned[key] = struct{}{}
+		size += uint64(len(key) + len(val))
+	}
+
+	batch := w.backup.NewBatch()
+	defer batch.Reset()
+	it := w.backup.NewIterator(prefix, nil)
+	defer it.Release()
+	for it.Next() && bytes.Compare(it.Key(), end) < 0 {
+		if err := batch.Delete(it.Key()); err != nil {
+			return 0, 0, err
+		}
+		if _, ok := pruned[string(it.Key())]; !ok {
+			pruned[string(it.Key())] = struct{}{}
+			size += uint64(len(it.Key()) + len(it.Value()))
+		}
+		if batch.ValueSize() >= w.batchSize {
+			if err := batch.Write(); err != nil {
+				return 0, 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if it.Error() != nil {
+		return 0, 0, it.Error()
+	}
+	if err := batch.Write(); err != nil {
+		return 0, 0, err
+	}
+	return len(pruned), size, nil
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 	"github.com/0xsoniclabs/kvdb"
 )
 
@@ -38,20 +39,37 @@ type VecFlushable struct {
 	modified   map[string][]byte
 	underlying backedMap
 	memSize    int
+
+	metrics struct {
+		flushes   metrics.Counter
+		unloads   metrics.Counter
+		cacheSize metrics.Gauge
+	}
 }
 
-func wrap(parent kvdb.Store, sizeLimit, batchSize int) *VecFlushable {
+func wrap(parent kvdb.Store, sizeLimit, batchSize int, registry metrics.Registry) *VecFlushable {
 	if parent == nil {
 		panic("nil parent")
 	}
-	return &VecFlushable{
+	w := &VecFlushable{
 		modified:   make(map[string][]byte),
 		underlying: *newBackedMap(parent, sizeLimit, batchSize),
 	}
+	registry = metrics.OrNoop(registry)
+	w.metrics.flushes = registry.Counter("vecflushable/flushes")
+	w.metrics.unloads = registry.Counter("vecflushable/unloads")
+	w.metrics.cacheSize = registry.Gauge("vecflushable/cache_size")
+	w.underlying.unloads = w.metrics.unloads
+	return w
 }
 
 func Wrap(parent kvdb.Store, sizeLimit int) *VecFlushable {
-	return wrap(parent, sizeLimit, kvdb.IdealBatchSize)
+	return wrap(parent, sizeLimit, kvdb.IdealBatchSize, nil)
+}
+
+// WrapWithMetrics is Wrap, which reports flushes, unloads and the cache size to the registry.
+func WrapWithMetrics(parent kvdb.Store, sizeLimit int, registry metrics.Registry) *VecFlushable {
+	return wrap(parent, sizeLimit, kvdb.IdealBatchSize, registry)
 }
 
 func (w *VecFlushable) clearModified() {
@@ -112,10 +130,36 @@ func (w *VecFlushable) Flush() error {
 	}
 
 	w.clearModified()
+	w.metrics.flushes.Inc(1)
+	w.metrics.cacheSize.Set(int64(w.underlying.memSize))
 
 	return nil
 }
 
+// Unload writes the flushed pairs, which are held in memory, to the underlying DB. Not flushed pairs are kept.
+func (w *VecFlushable) Unload() error {
+	if w.modified == nil {
+		return errClosed
+	}
+	for len(w.underlying.cache) != 0 {
+		if err := w.underlying.unload(w.underlying.batchSize); err != nil {
+			return err
+		}
+	}
+	w.metrics.cacheSize.Set(int64(w.underlying.memSize))
+	return nil
+}
+
+// Prune deletes the flushed pairs with keys starting with prefix, which are less than prefix+limit,
+// both from the memory and the underlying DB. Not flushed pairs are kept.
+// Returns the number of the deleted pairs and their size in bytes.
+func (w *VecFlushable) Prune(prefix, limit []byte) (int, uint64, error) {
+	if w.modified == nil {
+		return 0, 0, errClosed
+	}
+	return w.underlying.prune(prefix, limit)
+}
+
 func (w *VecFlushable) DropNotFlushed() {
 	w.clearModified()
 }
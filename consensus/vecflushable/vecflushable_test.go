@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 
+	"github.com/0xsoniclabs/consensus/consensus/metrics"
 	"github.com/0xsoniclabs/consensus/utils/byteutils"
 	"github.com/0xsoniclabs/kvdb"
 	"github.com/0xsoniclabs/kvdb/devnulldb"
@@ -98,7 +99,8 @@ func TestVecflushableBackup(t *testing.T) {
 	//   => | cache = 4 | memSize = 96 | levelDB = 6
 
 	backupDB, _ := tempLevelDB()
-	vecflushable := wrap(backupDB, 696-1, 48)
+	registry := metrics.NewMemoryRegistry()
+	vecflushable := wrap(backupDB, 696-1, 48, registry)
 
 	putOp := func(key []byte, value []byte) {
 		if err := vecflushable.Put(key, value); err != nil {
@@ -119,6 +121,9 @@ func TestVecflushableBackup(t *testing.T) {
 	assert.Equal(t, 0, vecflushable.NotFlushedSizeEst())
 	assert.Equal(t, expectedUnderlyingCacheCount, len(vecflushable.underlying.cache))
 	assert.Equal(t, expectedUnderlyingCacheSize, vecflushable.underlying.memSize)
+	assert.Equal(t, int64(totalItems), registry.CounterValue("vecflushable/flushes"))
+	assert.Equal(t, int64(1), registry.CounterValue("vecflushable/unloads"))
+	assert.Equal(t, int64(expectedUnderlyingCacheSize), registry.GaugeValue("vecflushable/cache_size"))
 
 	getOp := func(key []byte, val []byte) {
 		v, err := vecflushable.Get(key)
@@ -319,6 +324,93 @@ func TestHasInBacked(t *testing.T) {
 	assert.False(t, has)
 }
 
+func TestPrune(t *testing.T) {
+	backupDB, _ := tempLevelDB()
+	// a part of the flushed pairs is unloaded into the backup DB
+	vecflushable := wrap(backupDB, 696-1, 48, nil)
+
+	key := func(prefix byte, i uint64) []byte {
+		return append([]byte{prefix}, byteutils.Uint64ToBigEndian(i)...)
+	}
+	for i := uint64(0); i < 10; i++ {
+		for _, prefix := range []byte{'a', 'b'} {
+			if err := vecflushable.Put(key(prefix, i), byteutils.Uint64ToBigEndian(i)); err != nil {
+				t.Fatal(err)
+			}
+			if err := vecflushable.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// not flushed pairs are kept
+	notFlushed := key('a', 10)
+	if err := vecflushable.Put(notFlushed, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, size, err := vecflushable.Prune([]byte{'a'}, byteutils.Uint64ToBigEndian(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, pruned)
+	assert.Equal(t, uint64(5*(9+8)), size)
+
+	for i := uint64(0); i < 10; i++ {
+		has, err := vecflushable.Has(key('a', i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, i >= 5, has, i)
+		has, err = vecflushable.Has(key('b', i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, has, i)
+	}
+	has, err := vecflushable.Has(notFlushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, has)
+}
+
+func TestUnload(t *testing.T) {
+	backupDB, _ := tempLevelDB()
+	// the flushed pairs stay in memory below the limit
+	vecflushable := wrap(backupDB, 1000000, 48, nil)
+
+	for i := uint64(0); i < 10; i++ {
+		if err := vecflushable.Put(byteutils.Uint64ToBigEndian(i), byteutils.Uint64ToBigEndian(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := vecflushable.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// not flushed pairs are kept
+	notFlushed := byteutils.Uint64ToBigEndian(10)
+	if err := vecflushable.Put(notFlushed, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vecflushable.Unload(); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		val, err := backupDB.Get(byteutils.Uint64ToBigEndian(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, byteutils.Uint64ToBigEndian(i), val, i)
+	}
+	val, err := backupDB.Get(notFlushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, val)
+	assert.Equal(t, 1, vecflushable.NotFlushedPairs())
+}
+
 func TestHasClosed(t *testing.T) {
 	backupDB, _ := tempLevelDB()
 	vecflushable := Wrap(backupDB, 1000)